@@ -0,0 +1,160 @@
+// Package gadgetbridgedb reads the samples of Gadgetbridge's auto-export
+// databases. It's what the Telegraf plugin reads them with, so that other
+// tools, such as exporters or migration scripts, can read them the same way
+// without depending on Telegraf.
+//
+// A table is described by a TableDescription and its rows are read in the
+// order of their timestamps with Read:
+//
+//	db, err := gadgetbridgedb.Open("/path/to/gadgetbridge-export.db")
+//	if err != nil {
+//		return err
+//	}
+//	defer db.Close()
+//
+//	rows, err := gadgetbridgedb.Read(db, table, gadgetbridgedb.ReadOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//
+//	for rows.Next() {
+//		row, err := rows.Scan()
+//		if err != nil {
+//			continue // a single unreadable row, such as one with a NULL timestamp
+//		}
+//		...
+//	}
+//	return rows.Err()
+package gadgetbridgedb
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+
+	_ "github.com/doug-martin/goqu/v9/dialect/sqlite3"
+	_ "modernc.org/sqlite"
+)
+
+var builder = goqu.Dialect("sqlite")
+
+// Open opens the database at path read-only. The database is taken to be
+// immutable, as Gadgetbridge writes a new export rather than changing the
+// existing one, and is only used by one connection at a time, as SQLite
+// doesn't support concurrent access to it.
+func Open(path string) (*sql.DB, error) {
+	connURI := url.URL{
+		Scheme: "file",
+		Path:   path,
+	}
+
+	connQuery := connURI.Query()
+	connQuery.Set("mode", "ro")
+	connQuery.Set("immutable", "1")
+	connURI.RawQuery = connQuery.Encode()
+
+	db, err := sql.Open("sqlite", connURI.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	// Prevent concurrent access to the database as SQLite doesn't support it.
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// SchemaVersion returns the schema version of the database, which
+// Gadgetbridge keeps in PRAGMA user_version and increments whenever its
+// tables change.
+func SchemaVersion(db *sql.DB) (int64, error) {
+	var version int64
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// ColumnInfo describes a column of a table.
+type ColumnInfo struct {
+	// Name is the name of the column.
+	Name string
+	// Type is the declared type of the column, such as INTEGER or TEXT.
+	Type string
+}
+
+// Columns returns the columns of the given table in their declared order. No
+// columns are returned if the table doesn't exist.
+func Columns(db *sql.DB, table string) ([]ColumnInfo, error) {
+	r, err := db.Query("SELECT name, type FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var columns []ColumnInfo
+	for r.Next() {
+		var column ColumnInfo
+		if err := r.Scan(&column.Name, &column.Type); err != nil {
+			return nil, fmt.Errorf("error scanning column: %w", err)
+		}
+		columns = append(columns, column)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("error reading columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// HasColumn returns whether the columns include one of the given name.
+func HasColumn(columns []ColumnInfo, name string) bool {
+	return slices.ContainsFunc(columns, func(c ColumnInfo) bool { return c.Name == name })
+}
+
+// HasRowid returns whether the table has a rowid, which tables created
+// WITHOUT ROWID don't.
+func HasRowid(db *sql.DB, table string) (bool, error) {
+	var withoutRowid bool
+	err := db.QueryRow("SELECT wr FROM pragma_table_list WHERE name = ?", table).Scan(&withoutRowid)
+	return !withoutRowid, err
+}
+
+// Affinity is the type affinity of a column, which is the type that SQLite
+// prefers to store its values as.
+type Affinity string
+
+const (
+	AffinityInteger Affinity = "INTEGER"
+	AffinityReal    Affinity = "REAL"
+	AffinityNumeric Affinity = "NUMERIC"
+	AffinityText    Affinity = "TEXT"
+	AffinityBlob    Affinity = "BLOB"
+)
+
+// IsNumeric returns whether the affinity stores numbers as numbers.
+func (a Affinity) IsNumeric() bool {
+	return a == AffinityInteger || a == AffinityReal || a == AffinityNumeric
+}
+
+// Affinity returns the affinity of the column from its declared type,
+// following the rules of https://www.sqlite.org/datatype3.html#determination_of_column_affinity.
+func (c ColumnInfo) Affinity() Affinity {
+	t := strings.ToUpper(c.Type)
+	switch {
+	case strings.Contains(t, "INT"):
+		return AffinityInteger
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return AffinityText
+	case strings.Contains(t, "BLOB"), t == "":
+		return AffinityBlob
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return AffinityReal
+	default:
+		return AffinityNumeric
+	}
+}
@@ -0,0 +1,217 @@
+package gadgetbridgedb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableReader decodes what a table's columns can't describe on their own,
+// such as timestamps in milliseconds, codes that stand for names or JSON
+// objects of values. Tables whose values are read as they are use
+// ColumnReader, which readers of the other tables can embed to only change
+// what they decode.
+type TableReader interface {
+	// Time returns the time of the timestamp ts of a row.
+	Time(ts int64) time.Time
+	// Unix returns the timestamp of the time t, as the inverse of Time.
+	Unix(t time.Time) int64
+	// Columns returns the columns of the table that Decode decodes, which
+	// are read into Row.Decoded in the same order. They shouldn't also be
+	// tags or fields.
+	Columns() []string
+	// Decode adds the tags and fields decoded from the values of the
+	// columns that it decodes, as read from SQLite, to tags and fields. An
+	// error leaves the row out as unreadable.
+	Decode(values []any, tags map[string]string, fields map[string]any) error
+}
+
+// ColumnReader is the TableReader of the tables whose values are read as they
+// are. It decodes no columns.
+type ColumnReader struct {
+	// Milliseconds, if true, means that the timestamps are in Unix
+	// milliseconds rather than seconds.
+	Milliseconds bool
+}
+
+var _ TableReader = ColumnReader{}
+
+// Time implements TableReader.
+func (r ColumnReader) Time(ts int64) time.Time {
+	if r.Milliseconds {
+		return time.UnixMilli(ts)
+	}
+	return time.Unix(ts, 0)
+}
+
+// Unix implements TableReader.
+func (r ColumnReader) Unix(t time.Time) int64 {
+	if r.Milliseconds {
+		return t.UnixMilli()
+	}
+	return t.Unix()
+}
+
+// Columns implements TableReader.
+func (r ColumnReader) Columns() []string { return nil }
+
+// Decode implements TableReader.
+func (r ColumnReader) Decode(values []any, tags map[string]string, fields map[string]any) error {
+	return nil
+}
+
+// TagsReader is a TableReader that adds the same tags to every row, such as
+// to tell apart the rows of tables that share a measurement. It decodes no
+// columns.
+type TagsReader struct {
+	ColumnReader
+	// Tags are the tags added to every row.
+	Tags map[string]string
+}
+
+var _ TableReader = TagsReader{}
+
+// Decode implements TableReader.
+func (r TagsReader) Decode(values []any, tags map[string]string, fields map[string]any) error {
+	for k, v := range r.Tags {
+		tags[k] = v
+	}
+	return nil
+}
+
+// KindReader is a TableReader that decodes a column of codes, such as the
+// RAW_KIND of a device's activity samples, into a tag of the names they stand
+// for.
+type KindReader struct {
+	ColumnReader
+	// Column is the column of the codes.
+	Column string
+	// Tag is the name of the tag of the names.
+	Tag string
+	// Kinds maps the codes to their names. A code that isn't known is
+	// tagged as it is, and a NULL one leaves the tag out.
+	Kinds map[int64]string
+}
+
+var _ TableReader = KindReader{}
+
+// Columns implements TableReader.
+func (r KindReader) Columns() []string { return []string{r.Column} }
+
+// Decode implements TableReader.
+func (r KindReader) Decode(values []any, tags map[string]string, fields map[string]any) error {
+	var code int64
+	switch v := values[0].(type) {
+	case nil:
+		delete(tags, r.Tag)
+		return nil
+	case int64:
+		code = v
+	default:
+		return fmt.Errorf("column %q: kind %#v isn't an integer", r.Column, v)
+	}
+
+	if name, ok := r.Kinds[code]; ok {
+		tags[r.Tag] = name
+	} else {
+		tags[r.Tag] = strconv.FormatInt(code, 10)
+	}
+	return nil
+}
+
+// JSONReader is a TableReader that decodes a column of JSON objects, such as
+// the SUMMARY_DATA of BASE_ACTIVITY_SUMMARY, into a field for each of their
+// numbers. A key whose value is an object with a "value" number, as in
+// {"distanceMeters": {"value": 1200, "unit": "meters"}}, is decoded into a
+// field of that number. Fields are named after their keys in lowercase,
+// prefixed with Prefix.
+type JSONReader struct {
+	ColumnReader
+	// Column is the column of the JSON objects.
+	Column string
+	// Prefix is prefixed to the names of the fields.
+	Prefix string
+}
+
+var _ TableReader = JSONReader{}
+
+// Columns implements TableReader.
+func (r JSONReader) Columns() []string { return []string{r.Column} }
+
+// Decode implements TableReader.
+func (r JSONReader) Decode(values []any, tags map[string]string, fields map[string]any) error {
+	var data []byte
+	switch v := values[0].(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("column %q: %T isn't JSON", r.Column, v)
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("column %q: %w", r.Column, err)
+	}
+
+	for key, raw := range object {
+		var value struct {
+			Value json.Number `json:"value"`
+		}
+		n := json.Number(strings.TrimSpace(string(raw)))
+		if err := json.Unmarshal(raw, &value); err == nil && value.Value != "" {
+			n = value.Value
+		}
+
+		name := r.Prefix + strings.ToLower(key)
+		if i, err := n.Int64(); err == nil {
+			fields[name] = i
+		} else if f, err := n.Float64(); err == nil {
+			fields[name] = f
+		}
+	}
+	return nil
+}
+
+// MisfitReader is the TableReader of PEBBLE_MISFIT_SAMPLE, which decodes the
+// samples that the Misfit app for Pebble packed into RAW_PEBBLE_MISFIT_SAMPLE
+// the way that Gadgetbridge does. Sleep samples are tagged with a kind of
+// sleep and have the intensity of the sleep but no steps, while the others are
+// tagged with a kind of activity and have the steps, which double as their
+// intensity.
+type MisfitReader struct {
+	ColumnReader
+}
+
+var _ TableReader = MisfitReader{}
+
+// Columns implements TableReader.
+func (r MisfitReader) Columns() []string { return []string{"RAW_PEBBLE_MISFIT_SAMPLE"} }
+
+// Decode implements TableReader.
+func (r MisfitReader) Decode(values []any, tags map[string]string, fields map[string]any) error {
+	sample, ok := values[0].(int64)
+	if !ok {
+		return fmt.Errorf("column %q: sample %#v isn't an integer", "RAW_PEBBLE_MISFIT_SAMPLE", values[0])
+	}
+
+	// Sleep samples range from 0x2401 to 0x4801, with the intensity in
+	// between.
+	if sample&0x83ff == 0x0001 && sample&0xff00 <= 0x4800 {
+		tags["kind"] = "sleep"
+		fields["steps"] = int64(0)
+		fields["intensity"] = (sample & 0x7c00) >> 10
+		return nil
+	}
+
+	steps := (sample & 0x00fe) >> 1
+	tags["kind"] = "activity"
+	fields["steps"] = steps
+	fields["intensity"] = steps
+	return nil
+}
@@ -0,0 +1,94 @@
+package gadgetbridgedb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestColumnReader(t *testing.T) {
+	at := time.Date(2024, 9, 8, 8, 51, 0, 500e6, time.UTC)
+
+	seconds := ColumnReader{}
+	assert.Equal(t, at.Unix(), seconds.Unix(at))
+	assert.Equal(t, at.Truncate(time.Second), seconds.Time(seconds.Unix(at)).UTC())
+
+	milliseconds := ColumnReader{Milliseconds: true}
+	assert.Equal(t, at.UnixMilli(), milliseconds.Unix(at))
+	assert.Equal(t, at, milliseconds.Time(milliseconds.Unix(at)).UTC())
+}
+
+func TestKindReader(t *testing.T) {
+	r := KindReader{Column: "RAW_KIND", Tag: "kind", Kinds: map[int64]string{1: "sleep"}}
+
+	tags := map[string]string{}
+	assert.NoError(t, r.Decode([]any{int64(1)}, tags, nil))
+	assert.Equal(t, map[string]string{"kind": "sleep"}, tags)
+
+	assert.NoError(t, r.Decode([]any{int64(7)}, tags, nil))
+	assert.Equal(t, map[string]string{"kind": "7"}, tags)
+
+	assert.NoError(t, r.Decode([]any{nil}, tags, nil))
+	assert.Equal(t, map[string]string{}, tags)
+
+	assert.Error(t, r.Decode([]any{"sleep"}, tags, nil))
+}
+
+func TestJSONReader(t *testing.T) {
+	r := JSONReader{Column: "SUMMARY_DATA", Prefix: "summary_"}
+
+	fields := map[string]any{}
+	assert.NoError(t, r.Decode([]any{`{
+		"steps": 1200,
+		"distanceMeters": {"value": 950.5, "unit": "meters"},
+		"name": "Morning walk",
+		"laps": [1, 2]
+	}`}, nil, fields))
+	assert.Equal(t, map[string]any{
+		"summary_steps":          int64(1200),
+		"summary_distancemeters": 950.5,
+	}, fields)
+
+	assert.NoError(t, r.Decode([]any{nil}, nil, fields))
+	assert.Error(t, r.Decode([]any{"{"}, nil, fields))
+	assert.Error(t, r.Decode([]any{int64(1)}, nil, fields))
+}
+
+func TestMisfitReader(t *testing.T) {
+	r := MisfitReader{}
+
+	tags, fields := map[string]string{}, map[string]any{}
+	assert.NoError(t, r.Decode([]any{int64(0x2401)}, tags, fields))
+	assert.Equal(t, map[string]string{"kind": "sleep"}, tags)
+	assert.Equal(t, map[string]any{"steps": int64(0), "intensity": int64(9)}, fields)
+
+	assert.NoError(t, r.Decode([]any{int64(18)}, tags, fields))
+	assert.Equal(t, map[string]string{"kind": "activity"}, tags)
+	assert.Equal(t, map[string]any{"steps": int64(9), "intensity": int64(9)}, fields)
+
+	assert.Error(t, r.Decode([]any{nil}, tags, fields))
+}
+
+func TestRead_TableReader(t *testing.T) {
+	db := newTestDB(t)
+
+	table := testTable
+	table.Reader = KindReader{Column: "STEPS", Tag: "steps"}
+	table.Columns.Fields = nil
+
+	r, err := Read(db, table, ReadOptions{})
+	assert.NoError(t, err)
+	defer r.Close()
+
+	var decoded []any
+	for r.Next() {
+		row, err := r.Scan()
+		if err != nil {
+			continue
+		}
+		decoded = append(decoded, row.Decoded...)
+	}
+	assert.NoError(t, r.Err())
+	assert.Equal(t, []any{int64(1), "n/a", nil, int64(5)}, decoded)
+}
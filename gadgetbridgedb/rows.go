@@ -0,0 +1,195 @@
+package gadgetbridgedb
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// ReadOptions selects the rows read by Read. The timestamps are in the unit
+// of the table's timestamp column.
+type ReadOptions struct {
+	// After, if not nil, restricts the rows to those timestamped after it,
+	// such as to read only the rows written since the last read.
+	After *int64
+	// From and To, if not nil, restrict the rows to those timestamped within
+	// [From, To).
+	From, To *int64
+	// Newest, if positive, restricts the rows to the newest Newest of them,
+	// which are still read in order.
+	Newest int
+}
+
+// Row is a row of a table, with its values in the order of the columns of
+// the table's description.
+type Row struct {
+	// Timestamp is the value of the timestamp column.
+	Timestamp int64
+	// Tags are the values of the tag columns, which are NULL where the row
+	// has none.
+	Tags []sql.NullString
+	// Fields are the values of the field columns as read from SQLite: nil,
+	// int64, float64, string or []byte, as SQLite lets any column hold a
+	// value of any type.
+	Fields []any
+	// JoinedTags are the values of the tags of the joins, in the order of
+	// TableDescription.JoinedTagNames, which are NULL for rows without a
+	// match.
+	JoinedTags []sql.NullString
+	// Decoded are the values of the columns decoded by the table's
+	// TableReader, in the order of its Columns, as read from SQLite.
+	Decoded []any
+	// UTCOffset is the value of the UTC offset column, which is NULL if the
+	// table has none.
+	UTCOffset sql.NullInt64
+}
+
+// Key returns a key that's the same for rows with the same tags, fields and
+// decoded values, such as to tell apart the rows that share a timestamp.
+func (r Row) Key() string {
+	var b strings.Builder
+	for _, v := range r.Tags {
+		if v.Valid {
+			fmt.Fprintf(&b, "%q,", v.String)
+		} else {
+			b.WriteString("NULL,")
+		}
+	}
+	for _, v := range slices.Concat(r.Fields, r.Decoded) {
+		fmt.Fprintf(&b, "%#v,", v)
+	}
+	return b.String()
+}
+
+// Rows is a cursor over the rows of a table returned by Read. Like sql.Rows,
+// it must be closed once it's no longer needed, which it is on its own once
+// Next returns false.
+type Rows struct {
+	rows *sql.Rows
+	row  Row
+	dest []any
+}
+
+// Read reads the rows of the table t in the order of their timestamps. Rows
+// sharing a timestamp are read in a stable order: by their rowids if the
+// table has them, which keeps duplicate rows in the order they were written,
+// or else by their tags and fields.
+func Read(db *sql.DB, t TableDescription, opts ReadOptions) (*Rows, error) {
+	rowid, err := HasRowid(db, t.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error checking for rowid: %w", err)
+	}
+
+	decodedColumns := t.TableReader().Columns()
+
+	var offsetColumn []string
+	if t.Columns.UTCOffset != "" {
+		offsetColumn = []string{t.Columns.UTCOffset}
+	}
+
+	// The columns are qualified so that the joins can tell them apart from
+	// their own.
+	q := builder.
+		From(t.Name).
+		Select(slices.Concat(
+			qualifiedColumns(t.Name, []string{t.Columns.Timestamp}),
+			qualifiedColumns(t.Name, t.Columns.Tags),
+			qualifiedColumns(t.Name, t.Columns.Fields),
+			t.joinedTags(),
+			qualifiedColumns(t.Name, decodedColumns),
+			qualifiedColumns(t.Name, offsetColumn),
+		)...).
+		Order(t.rowOrder(rowid)...)
+
+	timestamp := goqu.C(t.Columns.Timestamp)
+	if opts.After != nil {
+		q = q.Where(timestamp.Gt(*opts.After))
+	}
+	if opts.From != nil {
+		q = q.Where(timestamp.Gte(*opts.From))
+	}
+	if opts.To != nil {
+		q = q.Where(timestamp.Lt(*opts.To))
+	}
+	if opts.Newest > 0 {
+		newest := q.Order(timestamp.Desc()).Limit(uint(opts.Newest))
+		q = builder.From(newest).Order(timestamp.Asc())
+	}
+
+	qSQL, qArgs, err := q.ToSQL()
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &Rows{
+		rows: r,
+		row: Row{
+			Tags:       make([]sql.NullString, len(t.Columns.Tags)),
+			Fields:     make([]any, len(t.Columns.Fields)),
+			JoinedTags: make([]sql.NullString, len(t.JoinedTagNames())),
+			Decoded:    make([]any, len(decodedColumns)),
+		},
+	}
+
+	rows.dest = append(rows.dest, &rows.row.Timestamp)
+	for i := range rows.row.Tags {
+		rows.dest = append(rows.dest, &rows.row.Tags[i])
+	}
+	for i := range rows.row.Fields {
+		rows.dest = append(rows.dest, &rows.row.Fields[i])
+	}
+	for i := range rows.row.JoinedTags {
+		rows.dest = append(rows.dest, &rows.row.JoinedTags[i])
+	}
+	for i := range rows.row.Decoded {
+		rows.dest = append(rows.dest, &rows.row.Decoded[i])
+	}
+	if offsetColumn != nil {
+		rows.dest = append(rows.dest, &rows.row.UTCOffset)
+	}
+
+	return rows, nil
+}
+
+// Next prepares the next row to be scanned, returning false once there are
+// no more rows or they can't be read, which Err tells apart.
+func (r *Rows) Next() bool {
+	return r.rows.Next()
+}
+
+// Scan returns the current row. A row that can't be read, such as one with a
+// NULL timestamp, returns an error without keeping the rows after it from
+// being read. The slices of the row are reused by the next call to Scan.
+func (r *Rows) Scan() (Row, error) {
+	if err := r.rows.Scan(r.dest...); err != nil {
+		return Row{}, err
+	}
+	return r.row, nil
+}
+
+// Err returns the error that stopped Next, if any.
+func (r *Rows) Err() error {
+	return r.rows.Err()
+}
+
+// Close closes the rows, freeing the database's connection.
+func (r *Rows) Close() error {
+	return r.rows.Close()
+}
+
+// qualifiedColumns returns the columns of the table qualified with its name.
+func qualifiedColumns(table string, columns []string) []any {
+	qualified := make([]any, len(columns))
+	for i, column := range columns {
+		qualified[i] = goqu.T(table).Col(column)
+	}
+	return qualified
+}
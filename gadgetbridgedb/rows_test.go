@@ -0,0 +1,256 @@
+package gadgetbridgedb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+const testDump = `
+CREATE TABLE DEVICE (_id INTEGER PRIMARY KEY, NAME TEXT);
+INSERT INTO DEVICE VALUES (1, 'Watch');
+
+CREATE TABLE TEST_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, STEPS INTEGER);
+INSERT INTO TEST_SAMPLE VALUES
+	(30, 1, 5),
+	(10, 1, 1),
+	(20, 2, 'n/a'),
+	(NULL, 1, 3),
+	(20, 1, NULL);
+`
+
+var testTable = TableDescription{
+	Name: "TEST_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID"},
+		Fields:    []string{"STEPS"},
+	},
+	Joins: []TableJoin{{
+		Name: "DEVICE",
+		On:   map[string]string{"DEVICE_ID": "_id"},
+		Tags: []string{"NAME"},
+	}},
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "gadgetbridge.db")
+
+	w, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err)
+	_, err = w.Exec(testDump)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	db, err := Open(dbPath)
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// readRows reads every row of testTable, returning a copy of each row that
+// could be read and how many couldn't.
+func readRows(t *testing.T, db *sql.DB, opts ReadOptions) (rows []Row, unreadable int) {
+	t.Helper()
+
+	r, err := Read(db, testTable, opts)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	for r.Next() {
+		row, err := r.Scan()
+		if err != nil {
+			unreadable++
+			continue
+		}
+		rows = append(rows, Row{
+			Timestamp:  row.Timestamp,
+			Tags:       append([]sql.NullString(nil), row.Tags...),
+			Fields:     append([]any(nil), row.Fields...),
+			JoinedTags: append([]sql.NullString(nil), row.JoinedTags...),
+		})
+	}
+	assert.NoError(t, r.Err())
+
+	return rows, unreadable
+}
+
+func TestRead(t *testing.T) {
+	db := newTestDB(t)
+
+	rows, unreadable := readRows(t, db, ReadOptions{})
+	assert.Equal(t, 1, unreadable, "the row with a NULL timestamp")
+
+	watch := sql.NullString{String: "Watch", Valid: true}
+	assert.Equal(t, []Row{
+		{Timestamp: 10, Tags: []sql.NullString{{String: "1", Valid: true}}, Fields: []any{int64(1)}, JoinedTags: []sql.NullString{watch}},
+		{Timestamp: 20, Tags: []sql.NullString{{String: "2", Valid: true}}, Fields: []any{"n/a"}, JoinedTags: []sql.NullString{{}}},
+		{Timestamp: 20, Tags: []sql.NullString{{String: "1", Valid: true}}, Fields: []any{nil}, JoinedTags: []sql.NullString{watch}},
+		{Timestamp: 30, Tags: []sql.NullString{{String: "1", Valid: true}}, Fields: []any{int64(5)}, JoinedTags: []sql.NullString{watch}},
+	}, rows)
+
+	timestamps := func(rows []Row) []int64 {
+		var ts []int64
+		for _, row := range rows {
+			ts = append(ts, row.Timestamp)
+		}
+		return ts
+	}
+
+	after := int64(10)
+	rows, _ = readRows(t, db, ReadOptions{After: &after})
+	assert.Equal(t, []int64{20, 20, 30}, timestamps(rows))
+
+	from, to := int64(20), int64(30)
+	rows, _ = readRows(t, db, ReadOptions{From: &from, To: &to})
+	assert.Equal(t, []int64{20, 20}, timestamps(rows))
+
+	rows, _ = readRows(t, db, ReadOptions{Newest: 2})
+	assert.Equal(t, []int64{20, 30}, timestamps(rows))
+}
+
+func TestRow_Key(t *testing.T) {
+	row := func(tag sql.NullString, field any) Row {
+		return Row{Tags: []sql.NullString{tag}, Fields: []any{field}}
+	}
+
+	one := sql.NullString{String: "1", Valid: true}
+	assert.Equal(t, row(one, int64(5)).Key(), row(one, int64(5)).Key())
+	assert.NotEqual(t, row(one, int64(5)).Key(), row(one, "5").Key())
+	assert.NotEqual(t, row(one, nil).Key(), row(sql.NullString{}, nil).Key())
+	assert.NotEqual(t, row(sql.NullString{String: "NULL", Valid: true}, nil).Key(), row(sql.NullString{}, nil).Key())
+}
+
+// FuzzRead reads rows with values of every type that SQLite lets a column
+// hold, whatever its declared type, such as text in an INTEGER column.
+func FuzzRead(f *testing.F) {
+	f.Add(byte(1), byte(1), byte(1), int64(20), "1")
+	f.Add(byte(1), byte(3), byte(3), int64(20), "n/a")
+	f.Add(byte(0), byte(0), byte(0), int64(0), "")
+	f.Add(byte(3), byte(4), byte(2), int64(-1), "1e400")
+	f.Add(byte(2), byte(3), byte(3), int64(1<<62), " 42 ")
+
+	dbPath := filepath.Join(f.TempDir(), "gadgetbridge.db")
+	db, err := sql.Open("sqlite", dbPath)
+	assert.NoError(f, err)
+	db.SetMaxOpenConns(1)
+	f.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(testDump)
+	assert.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, timestampKind, tagKind, fieldKind byte, n int64, s string) {
+		_, err := db.Exec("DELETE FROM TEST_SAMPLE")
+		assert.NoError(t, err)
+		_, err = db.Exec("INSERT INTO TEST_SAMPLE VALUES (?, ?, ?)",
+			fuzzValue(timestampKind, n, s), fuzzValue(tagKind, n, s), fuzzValue(fieldKind, n, s))
+		assert.NoError(t, err)
+
+		r, err := Read(db, testTable, ReadOptions{Newest: 1})
+		assert.NoError(t, err)
+		defer r.Close()
+
+		for r.Next() {
+			row, err := r.Scan()
+			if err != nil {
+				continue
+			}
+			switch row.Fields[0].(type) {
+			case nil, int64, float64, string, []byte:
+			default:
+				t.Errorf("field read as %T", row.Fields[0])
+			}
+			row.Key()
+		}
+		assert.NoError(t, r.Err())
+	})
+}
+
+// fuzzValue returns n or s as a value of the SQLite type chosen by kind.
+func fuzzValue(kind byte, n int64, s string) any {
+	switch kind % 5 {
+	case 1:
+		return n
+	case 2:
+		return float64(n) / 3
+	case 3:
+		return s
+	case 4:
+		return []byte(s)
+	default:
+		return nil
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        any
+		affinity Affinity
+		want     any
+		wantOK   bool
+	}{
+		{"integer", int64(7), AffinityInteger, int64(7), true},
+		{"integer text", "42", AffinityInteger, int64(42), true},
+		{"integer padded text", []byte(" 42 "), AffinityInteger, int64(42), true},
+		{"integer whole real", 3.0, AffinityInteger, int64(3), true},
+		{"integer whole real text", "3.0", AffinityInteger, int64(3), true},
+		{"integer fraction", 1.5, AffinityInteger, nil, false},
+		{"integer fraction text", " 1.5", AffinityInteger, nil, false},
+		{"integer out of range", 1e19, AffinityInteger, nil, false},
+		{"real integer", int64(7), AffinityReal, 7.0, true},
+		{"real integer text", "42", AffinityReal, 42.0, true},
+		{"real", 1.5, AffinityReal, 1.5, true},
+		{"numeric integer", int64(7), AffinityNumeric, 7.0, true},
+		{"untyped integer", int64(7), AffinityBlob, 7.0, true},
+		{"untyped text", " 1.5", AffinityBlob, 1.5, true},
+		{"not a number", "n/a", AffinityReal, nil, false},
+		{"blob", []byte{0x01}, AffinityInteger, nil, false},
+		{"null", nil, AffinityInteger, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := CoerceValue(test.v, test.affinity)
+			assert.Equal(t, test.wantOK, ok)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+// FuzzCoerceValue coerces field values written as text by some firmware,
+// which are numbers only if they can be parsed as one, of the type of the
+// column's affinity.
+func FuzzCoerceValue(f *testing.F) {
+	for _, s := range []string{"42", " 1.5 ", "-0", "1e400", "0x10", "NaN", "--", "", "9223372036854775808"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, affinity := range []Affinity{AffinityInteger, AffinityReal, AffinityNumeric, AffinityText, AffinityBlob} {
+			for _, v := range []any{s, []byte(s)} {
+				coerced, ok := CoerceValue(v, affinity)
+				if !ok {
+					continue
+				}
+				switch coerced.(type) {
+				case int64:
+					if affinity != AffinityInteger {
+						t.Errorf("%q coerced to %T for %s", s, coerced, affinity)
+					}
+				case float64:
+					if affinity == AffinityInteger {
+						t.Errorf("%q coerced to %T for %s", s, coerced, affinity)
+					}
+				default:
+					t.Errorf("%q coerced to %T", s, coerced)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,380 @@
+package gadgetbridgedb
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// SummaryOptions selects the rows that a summary is computed from and where
+// its days and nights are.
+type SummaryOptions struct {
+	// From and To, if not zero, restrict the rows to those within [From, To).
+	From, To time.Time
+	// Location is where the days and nights begin at midnight and noon,
+	// which is time.Local if nil. The timestamps of tables in local time are
+	// taken to be in it too.
+	Location *time.Location
+}
+
+func (o SummaryOptions) location() *time.Location {
+	if o.Location == nil {
+		return time.Local
+	}
+	return o.Location
+}
+
+// DailySteps is the number of steps counted during a day.
+type DailySteps struct {
+	// Tags are the values of the table's tag columns, such as DEVICE_ID, by
+	// their names. NULL tags are left out.
+	Tags map[string]string
+	// Day is the midnight that the day begins at.
+	Day time.Time
+	// Steps is the number of steps.
+	Steps int64
+}
+
+// StepsPerDay sums the steps in the column of the activity samples of the
+// table t by the day they were counted on, separately for every combination
+// of the table's tags, such as per device and user. Like the plugin, it
+// leaves out the steps that are sentinels or implausible. The days are
+// ordered by the day, then by their tags.
+func StepsPerDay(db *sql.DB, t TableDescription, column string, opts SummaryOptions) ([]DailySteps, error) {
+	loc := opts.location()
+	days := make(map[summaryKey]*DailySteps)
+
+	err := summarize(db, t, []string{column}, opts, func(at time.Time, tags summaryTags, values []float64) {
+		y, m, d := at.In(loc).Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+
+		k := summaryKey{tags.key, day.Unix()}
+		if days[k] == nil {
+			days[k] = &DailySteps{Tags: tags.get(), Day: day}
+		}
+		days[k].Steps += int64(values[0])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedSummaries(days, func(d *DailySteps) time.Time { return d.Day }), nil
+}
+
+// SleepOptions configures SleepPerNight.
+type SleepOptions struct {
+	SummaryOptions
+	// KindColumn is the field column of the kinds of activity that the
+	// device recognized, such as RAW_KIND.
+	KindColumn string
+	// Kinds are the kinds of sleep, such as those of light and deep sleep.
+	// They differ between devices.
+	Kinds []int64
+	// SampleLength is the time that each sample stands for, which is a
+	// minute if zero.
+	SampleLength time.Duration
+}
+
+// NightlySleep is the sleep of a night.
+type NightlySleep struct {
+	// Tags are the values of the table's tag columns, such as DEVICE_ID, by
+	// their names. NULL tags are left out.
+	Tags map[string]string
+	// Night is the midnight that begins the day whose evening the night
+	// begins on. A night lasts from noon to noon, so that sleep past
+	// midnight counts towards the night before.
+	Night time.Time
+	// Start and End are the times of the first and last samples asleep.
+	Start, End time.Time
+	// Asleep is how long was slept, which is the length of every sample
+	// asleep rather than the time between Start and End.
+	Asleep time.Duration
+}
+
+// SleepPerNight sums the sleep recorded by the activity samples of the table
+// t by the night it was recorded on, separately for every combination of the
+// table's tags. The nights are ordered by the night, then by their tags.
+func SleepPerNight(db *sql.DB, t TableDescription, opts SleepOptions) ([]NightlySleep, error) {
+	loc := opts.location()
+	sampleLength := opts.SampleLength
+	if sampleLength <= 0 {
+		sampleLength = time.Minute
+	}
+
+	nights := make(map[summaryKey]*NightlySleep)
+
+	err := summarize(db, t, []string{opts.KindColumn}, opts.SummaryOptions, func(at time.Time, tags summaryTags, values []float64) {
+		if !slices.Contains(opts.Kinds, int64(values[0])) {
+			return
+		}
+
+		y, m, d := at.In(loc).Add(-12 * time.Hour).Date()
+		night := time.Date(y, m, d, 0, 0, 0, 0, loc)
+
+		k := summaryKey{tags.key, night.Unix()}
+		sleep := nights[k]
+		if sleep == nil {
+			sleep = &NightlySleep{Tags: tags.get(), Night: night, Start: at}
+			nights[k] = sleep
+		}
+		sleep.End = at
+		sleep.Asleep += sampleLength
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedSummaries(nights, func(n *NightlySleep) time.Time { return n.Night }), nil
+}
+
+// SessionOptions configures SleepSessions.
+type SessionOptions struct {
+	SleepOptions
+	// MaxGap is the longest time between two samples asleep of the same
+	// session, past which the later one begins another. It's an hour if
+	// zero.
+	MaxGap time.Duration
+}
+
+// SleepSession is a stretch of sleep, such as a night's or a nap.
+type SleepSession struct {
+	// Tags are the values of the table's tag columns, such as DEVICE_ID, by
+	// their names. NULL tags are left out.
+	Tags map[string]string
+	// Start is the time of the first sample asleep, and End is when the last
+	// one ends, a sample's length after it.
+	Start, End time.Time
+	// Asleep is how long was slept, which is the length of every sample
+	// asleep rather than the time between Start and End.
+	Asleep time.Duration
+	// Ended is whether the table has a sample of the same tags more than
+	// MaxGap after the last sample asleep, so that samples yet to be
+	// recorded can't add to the session.
+	Ended bool
+}
+
+// SleepSessions returns the sleep sessions recorded by the activity samples
+// of the table t, separately for every combination of the table's tags. A
+// session ends once no sample asleep follows its last one within MaxGap. The
+// sessions are ordered by their start.
+func SleepSessions(db *sql.DB, t TableDescription, opts SessionOptions) ([]SleepSession, error) {
+	sampleLength := opts.SampleLength
+	if sampleLength <= 0 {
+		sampleLength = time.Minute
+	}
+	maxGap := opts.MaxGap
+	if maxGap <= 0 {
+		maxGap = time.Hour
+	}
+
+	type openSession struct {
+		index int
+		// last is the time of the last sample asleep.
+		last time.Time
+	}
+
+	var sessions []SleepSession
+	open := make(map[string]openSession)
+
+	err := summarize(db, t, []string{opts.KindColumn}, opts.SummaryOptions, func(at time.Time, tags summaryTags, values []float64) {
+		session, ok := open[tags.key]
+		if ok && at.Sub(session.last) > maxGap {
+			sessions[session.index].Ended = true
+			delete(open, tags.key)
+			ok = false
+		}
+
+		if !slices.Contains(opts.Kinds, int64(values[0])) {
+			return
+		}
+
+		if !ok {
+			session.index = len(sessions)
+			sessions = append(sessions, SleepSession{Tags: tags.get(), Start: at})
+		}
+		session.last = at
+		open[tags.key] = session
+
+		sessions[session.index].End = at.Add(sampleLength)
+		sessions[session.index].Asleep += sampleLength
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// BatteryLevel is a battery level that a device reported.
+type BatteryLevel struct {
+	// Tags are the values of the table's tag columns, such as DEVICE_ID and
+	// BATTERY_INDEX, by their names. NULL tags are left out.
+	Tags map[string]string
+	// Time is when the level was reported.
+	Time time.Time
+	// Level is the level in percent.
+	Level int64
+	// Charging is whether the level rose since the previous one of the
+	// same battery, which is only seen after the device was charged.
+	Charging bool
+}
+
+// BatteryHistory returns the battery levels in the column of the table t,
+// such as the LEVEL of BatteryLevels, leaving out those that repeat the
+// previous level of the same battery. The levels are ordered by their time.
+func BatteryHistory(db *sql.DB, t TableDescription, column string, opts SummaryOptions) ([]BatteryLevel, error) {
+	var levels []BatteryLevel
+	last := make(map[string]int64)
+
+	err := summarize(db, t, []string{column}, opts, func(at time.Time, tags summaryTags, values []float64) {
+		level := int64(values[0])
+		previous, ok := last[tags.key]
+		if ok && level == previous {
+			return
+		}
+		last[tags.key] = level
+
+		levels = append(levels, BatteryLevel{
+			Tags:     tags.get(),
+			Time:     at,
+			Level:    level,
+			Charging: ok && level > previous,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+// summaryKey identifies a summary by its tags and the Unix time of its day or
+// night.
+type summaryKey struct {
+	tags string
+	time int64
+}
+
+// summaryTags are the tags of a row, which are only made into a map for the
+// rows that begin a summary.
+type summaryTags struct {
+	key string
+	get func() map[string]string
+}
+
+// summarize calls fn with the time, tags and the values of the given field
+// columns of every row of t, in the order of their timestamps. Rows that
+// can't be read or whose values aren't all numbers are skipped, as are those
+// with values that are sentinels or implausible, like the plugin does.
+func summarize(db *sql.DB, t TableDescription, columns []string, opts SummaryOptions, fn func(at time.Time, tags summaryTags, values []float64)) error {
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		indexes[i] = slices.Index(t.Columns.Fields, column)
+		if indexes[i] == -1 {
+			return fmt.Errorf("column %q isn't listed in the fields of table %q", column, t.Name)
+		}
+	}
+
+	reader := t.TableReader()
+	loc := opts.location()
+
+	unix := func(at time.Time) int64 {
+		if t.Columns.LocalTime {
+			// The timestamps are the wall clock of the location as if it
+			// were UTC.
+			l := at.In(loc)
+			at = time.Date(l.Year(), l.Month(), l.Day(), l.Hour(), l.Minute(), l.Second(), l.Nanosecond(), time.UTC)
+		}
+		return reader.Unix(at)
+	}
+
+	var readOpts ReadOptions
+	if !opts.From.IsZero() {
+		from := unix(opts.From)
+		readOpts.From = &from
+	}
+	if !opts.To.IsZero() {
+		to := unix(opts.To)
+		readOpts.To = &to
+	}
+
+	rows, err := Read(db, t, readOpts)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	values := make([]float64, len(columns))
+next:
+	for rows.Next() {
+		row, err := rows.Scan()
+		if err != nil {
+			continue
+		}
+
+		for i, index := range indexes {
+			column := columns[i]
+			// The values are summed up as floats whatever their column.
+			v, ok := CoerceValue(row.Fields[index], AffinityReal)
+			if !ok || v == nil || IsSentinel(t.Columns.Sentinels[column], v) {
+				continue next
+			}
+			if r, ok := t.Columns.Plausible[column]; ok && !r.Contains(v) {
+				continue next
+			}
+			values[i], _ = toFloat(v)
+		}
+
+		at := reader.Time(row.Timestamp)
+		if t.Columns.LocalTime {
+			// The wall clock of the timestamp is that of the location.
+			u := at.UTC()
+			at = time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), u.Nanosecond(), loc)
+		}
+
+		fn(at, rowTags(t, row), values)
+	}
+
+	return rows.Err()
+}
+
+// rowTags returns the tags of the row of t, which must be gotten before the
+// next row is scanned into it.
+func rowTags(t TableDescription, row Row) summaryTags {
+	tags := Row{Tags: row.Tags}
+	return summaryTags{
+		key: tags.Key(),
+		get: func() map[string]string {
+			m := make(map[string]string, len(row.Tags))
+			for i, tag := range t.Columns.Tags {
+				if row.Tags[i].Valid {
+					m[tag] = row.Tags[i].String
+				}
+			}
+			return m
+		},
+	}
+}
+
+// sortedSummaries returns the summaries ordered by the time that at returns,
+// then by their tags.
+func sortedSummaries[T any](summaries map[summaryKey]*T, at func(*T) time.Time) []T {
+	keys := make([]summaryKey, 0, len(summaries))
+	for k := range summaries {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b summaryKey) int {
+		if c := at(summaries[a]).Compare(at(summaries[b])); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.tags, b.tags)
+	})
+
+	sorted := make([]T, len(keys))
+	for i, k := range keys {
+		sorted[i] = *summaries[k]
+	}
+	return sorted
+}
@@ -0,0 +1,165 @@
+package gadgetbridgedb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+const summaryDump = `
+CREATE TABLE ACTIVITY_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, STEPS INTEGER, RAW_KIND INTEGER);
+INSERT INTO ACTIVITY_SAMPLE VALUES
+	-- 2024-09-07 in UTC, walking in the afternoon and asleep at night.
+	(1725714000, 1, 100, 1),
+	(1725714060, 1, 50, 1),
+	(1725714120, 1, 65535, 1),
+	(1725714180, 1, 'n/a', 1),
+	(1725714000, 2, 7, 1),
+	(1725746400, 1, 0, 4),
+	(1725746460, 1, 0, 5),
+	-- 2024-09-08, still asleep past midnight, then walking.
+	(1725757200, 1, 0, 5),
+	(1725757260, 1, 2000, 1),
+	(1725764400, 1, 30, 1);
+
+CREATE TABLE BATTERY_LEVEL (TIMESTAMP INTEGER, DEVICE_ID INTEGER, BATTERY_INDEX INTEGER, LEVEL INTEGER);
+INSERT INTO BATTERY_LEVEL VALUES
+	(1725714000, 1, 0, 80),
+	(1725717600, 1, 0, 80),
+	(1725721200, 1, 0, 75),
+	(1725724800, 1, 0, -1),
+	(1725728400, 1, 0, 100),
+	(1725721200, 2, 0, 40);
+`
+
+var summaryTable = TableDescription{
+	Name: "ACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID"},
+		Fields:    []string{"STEPS", "RAW_KIND"},
+		Sentinels: map[string][]int64{"STEPS": {65535}},
+		Plausible: map[string]ValueRange{"STEPS": {Min: 0, Max: 1000}},
+	},
+}
+
+func newSummaryDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "gadgetbridge.db")
+
+	w, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err)
+	_, err = w.Exec(summaryDump)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	db, err := Open(dbPath)
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestStepsPerDay(t *testing.T) {
+	db := newSummaryDB(t)
+	day := func(d int) time.Time { return time.Date(2024, 9, d, 0, 0, 0, 0, time.UTC) }
+
+	days, err := StepsPerDay(db, summaryTable, "STEPS", SummaryOptions{Location: time.UTC})
+	assert.NoError(t, err)
+	assert.Equal(t, []DailySteps{
+		{Tags: map[string]string{"DEVICE_ID": "1"}, Day: day(7), Steps: 150},
+		{Tags: map[string]string{"DEVICE_ID": "2"}, Day: day(7), Steps: 7},
+		{Tags: map[string]string{"DEVICE_ID": "1"}, Day: day(8), Steps: 30},
+	}, days)
+
+	days, err = StepsPerDay(db, summaryTable, "STEPS", SummaryOptions{From: day(8), Location: time.UTC})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(days))
+
+	_, err = StepsPerDay(db, summaryTable, "CALORIES", SummaryOptions{})
+	assert.Error(t, err)
+}
+
+func TestSleepPerNight(t *testing.T) {
+	db := newSummaryDB(t)
+
+	nights, err := SleepPerNight(db, summaryTable, SleepOptions{
+		SummaryOptions: SummaryOptions{Location: time.UTC},
+		KindColumn:     "RAW_KIND",
+		Kinds:          []int64{4, 5},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []NightlySleep{{
+		Tags:   map[string]string{"DEVICE_ID": "1"},
+		Night:  time.Date(2024, 9, 7, 0, 0, 0, 0, time.UTC),
+		Start:  time.Unix(1725746400, 0),
+		End:    time.Unix(1725757200, 0),
+		Asleep: 3 * time.Minute,
+	}}, nights)
+}
+
+func TestSleepSessions(t *testing.T) {
+	db := newSummaryDB(t)
+
+	opts := SessionOptions{
+		SleepOptions: SleepOptions{KindColumn: "RAW_KIND", Kinds: []int64{4, 5}},
+		MaxGap:       30 * time.Minute,
+	}
+
+	sessions, err := SleepSessions(db, summaryTable, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []SleepSession{
+		{
+			Tags:   map[string]string{"DEVICE_ID": "1"},
+			Start:  time.Unix(1725746400, 0),
+			End:    time.Unix(1725746520, 0),
+			Asleep: 2 * time.Minute,
+			Ended:  true,
+		},
+		{
+			Tags:   map[string]string{"DEVICE_ID": "1"},
+			Start:  time.Unix(1725757200, 0),
+			End:    time.Unix(1725757260, 0),
+			Asleep: time.Minute,
+			Ended:  true,
+		},
+	}, sessions)
+
+	// Without the samples of the morning, nothing was recorded long enough
+	// after the last sample asleep for the session to have ended.
+	opts.To = time.Unix(1725760000, 0)
+	sessions, err = SleepSessions(db, summaryTable, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(sessions))
+	assert.False(t, sessions[1].Ended)
+	opts.To = time.Time{}
+
+	// A longer gap joins both into one session.
+	opts.MaxGap = 4 * time.Hour
+	sessions, err = SleepSessions(db, summaryTable, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sessions))
+	assert.Equal(t, time.Unix(1725757260, 0), sessions[0].End)
+	assert.Equal(t, 3*time.Minute, sessions[0].Asleep)
+}
+
+func TestBatteryHistory(t *testing.T) {
+	db := newSummaryDB(t)
+
+	levels, err := BatteryHistory(db, BatteryLevels, "LEVEL", SummaryOptions{})
+	assert.NoError(t, err)
+
+	battery := func(device string) map[string]string {
+		return map[string]string{"DEVICE_ID": device, "BATTERY_INDEX": "0"}
+	}
+	assert.Equal(t, []BatteryLevel{
+		{Tags: battery("1"), Time: time.Unix(1725714000, 0), Level: 80},
+		{Tags: battery("1"), Time: time.Unix(1725721200, 0), Level: 75},
+		{Tags: battery("2"), Time: time.Unix(1725721200, 0), Level: 40},
+		{Tags: battery("1"), Time: time.Unix(1725728400, 0), Level: 100, Charging: true},
+	}, levels)
+}
@@ -0,0 +1,982 @@
+package gadgetbridgedb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// TableDescription describes a table in the database.
+// It is used to determine which tables to read and how to parse the data.
+type TableDescription struct {
+	// Name is the name of the table in the database.
+	Name string `toml:"table"`
+	// Columns describes the columns in the table.
+	Columns TableColumns `toml:"columns"`
+	// Measurement is the measurement that the rows are added to, which
+	// defaults to the table's name in lower case. Tables that share one can
+	// tell their rows apart with a TagsReader.
+	Measurement string `toml:"measurement,omitempty"`
+	// Joins describes the tables that more tags are looked up in.
+	Joins []TableJoin `toml:"joins,omitempty"`
+	// Milliseconds, if true, means that the timestamps are in Unix
+	// milliseconds rather than seconds, as in the tables of Gadgetbridge's
+	// AbstractTimeSample. Tables with a Reader leave it to the Reader.
+	Milliseconds bool `toml:"milliseconds,omitempty"`
+	// Reader decodes what the columns can't describe on their own, such as
+	// timestamps in milliseconds. It defaults to a ColumnReader, which reads
+	// the values as they are, and can only be set from Go.
+	Reader TableReader `toml:"-"`
+}
+
+// MeasurementName returns the measurement that t's rows are added to.
+func (t TableDescription) MeasurementName() string {
+	if t.Measurement == "" {
+		return strings.ToLower(t.Name)
+	}
+	return t.Measurement
+}
+
+// TableReader returns the reader of t's rows.
+func (t TableDescription) TableReader() TableReader {
+	if t.Reader == nil {
+		return ColumnReader{Milliseconds: t.Milliseconds}
+	}
+	return t.Reader
+}
+
+// Validate checks t for mistakes that would otherwise only fail it once it's
+// queried, if at all.
+func (t TableDescription) Validate() error {
+	if err := t.Columns.Validate(); err != nil {
+		return fmt.Errorf("columns: %w", err)
+	}
+	if t.Milliseconds && t.Reader != nil {
+		return errors.New("milliseconds is set along with a reader")
+	}
+	for _, j := range t.Joins {
+		if err := j.Validate(); err != nil {
+			return fmt.Errorf("join %q: %w", j.Name, err)
+		}
+		for _, joined := range j.Tags {
+			name := j.TagName(joined)
+			if slices.ContainsFunc(t.Columns.Tags, func(tag string) bool { return t.Columns.KeyName(tag) == name }) {
+				return fmt.Errorf("join %q: tag %q is also a tag of the table", j.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// TableJoin describes a table that tags are looked up in for every row of the
+// table that joins it, such as the name of a row's device.
+type TableJoin struct {
+	// Name is the name of the joined table in the database.
+	Name string `toml:"table"`
+	// On maps columns of the joining table to the columns of the joined
+	// table that they must equal. Only the first matching row is used.
+	On map[string]string `toml:"on"`
+	// Tags is a list of columns of the joined table that contain the tags.
+	// Each is named after the joined table and the column, such as
+	// device_name for the NAME column of DEVICE, and is left out of rows
+	// without a match.
+	Tags []string `toml:"tags"`
+	// As maps some of the columns of Tags to the names of their tags
+	// instead, such as DEVICE_ID to device_id for a table whose rows only
+	// refer to a table with the device, so that they're told apart by
+	// device like those of the tables with a DEVICE_ID of their own.
+	As map[string]string `toml:"as,omitempty"`
+}
+
+// TagName returns the name of the tag of the column of the joined table.
+func (j TableJoin) TagName(column string) string {
+	if name, ok := j.As[column]; ok {
+		return name
+	}
+	return strings.ToLower(j.Name + "_" + column)
+}
+
+// Validate checks j for mistakes.
+func (j TableJoin) Validate() error {
+	if j.Name == "" {
+		return errors.New("missing table")
+	}
+	if len(j.On) == 0 {
+		return errors.New("missing on columns")
+	}
+	if len(j.Tags) == 0 {
+		return errors.New("missing tags")
+	}
+	for column, name := range j.As {
+		if !slices.Contains(j.Tags, column) {
+			return fmt.Errorf("as: column %q isn't listed in tags", column)
+		}
+		if name == "" {
+			return fmt.Errorf("as: empty tag name for column %q", column)
+		}
+	}
+	return nil
+}
+
+// rowOrder returns the order that the rows of t are read in, which is stable
+// for rows sharing a timestamp: by their timestamps, and then by their rowids
+// if the table has them, which keeps duplicate rows in the order they were
+// written. Most sample tables are WITHOUT ROWID, with a primary key that
+// rules out duplicates, and are ordered by the rest of their columns instead.
+func (t TableDescription) rowOrder(rowid bool) []exp.OrderedExpression {
+	order := []exp.OrderedExpression{goqu.T(t.Name).Col(t.Columns.Timestamp).Asc()}
+	if rowid {
+		return append(order, goqu.T(t.Name).Col("rowid").Asc())
+	}
+	for _, column := range slices.Concat(t.Columns.Tags, t.Columns.Fields, t.TableReader().Columns()) {
+		order = append(order, goqu.T(t.Name).Col(column).Asc())
+	}
+	return order
+}
+
+// joinedTags returns the expressions selecting the tags of the joins of t, in
+// the order of their columns.
+func (t TableDescription) joinedTags() []any {
+	var exprs []any
+	for i, j := range t.Joins {
+		for k, tag := range j.Tags {
+			exprs = append(exprs, t.joinedTag(j, tag).As(fmt.Sprintf("join%d_%d", i, k)))
+		}
+	}
+	return exprs
+}
+
+// joinedTag returns the subquery selecting the column tag of the join j of t.
+func (t TableDescription) joinedTag(j TableJoin, tag string) *goqu.SelectDataset {
+	// The joined table is aliased so that it can be the joining table
+	// itself.
+	joined := goqu.T(j.Name).As("joined")
+	var on []exp.Expression
+	for column, joinedColumn := range j.On {
+		on = append(on, goqu.I("joined."+joinedColumn).Eq(goqu.T(t.Name).Col(column)))
+	}
+	return builder.
+		From(joined).
+		Select(goqu.I("joined." + tag)).
+		Where(on...).
+		Limit(1)
+}
+
+// DeviceColumn returns the expression selecting the DEVICE_ID of the rows of
+// t, either its own or that of a join tagging it as device_id, and whether it
+// has either.
+func (t TableDescription) DeviceColumn() (exp.Expression, bool) {
+	if slices.Contains(t.Columns.Tags, "DEVICE_ID") {
+		return goqu.T(t.Name).Col("DEVICE_ID"), true
+	}
+	for _, j := range t.Joins {
+		for _, tag := range j.Tags {
+			if j.TagName(tag) == "device_id" {
+				return t.joinedTag(j, tag), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// TableColumns describes the columns in a table.
+type TableColumns struct {
+	// Timestamp is the name of the column that contains the timestamp.
+	// This must not be empty.
+	Timestamp string `toml:"timestamp"`
+	// Tags is a list of columns that contain the tags to be parsed as strings.
+	Tags []string `toml:"tags"`
+	// NullTags is how the rows with a NULL tag are handled. It defaults to
+	// NullTagsDropRow.
+	NullTags NullTagsBehavior `toml:"null_tags,omitempty"`
+	// Fields is a list of columns that contain the fields to be parsed
+	// numerically (as either int64 or float64).
+	Fields []string `toml:"fields"`
+	// LocalTime, if true, means that the timestamps are in the device's local
+	// time rather than UTC, which is converted using the UTCOffset column or
+	// else Timezone.
+	LocalTime bool `toml:"local_time,omitempty"`
+	// Timezone is the IANA timezone that the local timestamps are in, for
+	// tables whose device doesn't follow the database's timezone in
+	// DatabaseTimezones, which it otherwise defaults to. It requires
+	// LocalTime.
+	Timezone string `toml:"timezone,omitempty"`
+	// UTCOffset is the name of the column, if any, that contains the device's
+	// offset from UTC in seconds when each row was recorded. It requires
+	// LocalTime.
+	UTCOffset string `toml:"utc_offset,omitempty"`
+	// Ignored is a list of columns that are deliberately not read, which
+	// UnknownColumns leaves out. It can only be set from Go.
+	Ignored []string `toml:"-"`
+	// Sentinels maps field columns to the values that devices write when
+	// nothing was measured, such as a heart rate of 255, which are left out
+	// of the rows that have them.
+	Sentinels map[string][]int64 `toml:"sentinels,omitempty"`
+	// Plausible maps field columns to the ranges of their plausible values,
+	// such as steps that aren't negative or wrapped around by a firmware
+	// that rebooted. Values out of range are left out of the rows that have
+	// them and counted as implausible_value errors.
+	Plausible map[string]ValueRange `toml:"plausible,omitempty"`
+}
+
+// Validate checks c for mistakes, such as columns listed more than once.
+func (c TableColumns) Validate() error {
+	if c.Timestamp == "" {
+		return errors.New("missing timestamp")
+	}
+
+	// A column listed twice would be gathered twice under the same name, and
+	// one that's both a tag and a field would conflict in most outputs.
+	listed := make(map[string]string, len(c.Tags)+len(c.Fields))
+	for _, list := range []struct {
+		key     string
+		columns []string
+	}{
+		{"tags", c.Tags},
+		{"fields", c.Fields},
+	} {
+		for _, column := range list.columns {
+			if column == "" {
+				return fmt.Errorf("%s: empty column name", list.key)
+			}
+			if key, ok := listed[column]; ok {
+				if key == list.key {
+					return fmt.Errorf("%s: column %q is listed more than once", key, column)
+				}
+				return fmt.Errorf("column %q is listed in both %s and %s", column, key, list.key)
+			}
+			listed[column] = list.key
+		}
+	}
+
+	if c.UTCOffset != "" && !c.LocalTime {
+		return errors.New("utc_offset requires local_time")
+	}
+	if c.Timezone != "" && !c.LocalTime {
+		return errors.New("timezone requires local_time")
+	}
+	if err := c.NullTags.Validate(); err != nil {
+		return fmt.Errorf("null_tags: %w", err)
+	}
+	for field := range c.Sentinels {
+		if listed[field] != "fields" {
+			return fmt.Errorf("sentinels: column %q isn't listed in fields", field)
+		}
+	}
+	for field, r := range c.Plausible {
+		if listed[field] != "fields" {
+			return fmt.Errorf("plausible: column %q isn't listed in fields", field)
+		}
+		if r.Min > r.Max {
+			return fmt.Errorf("plausible: range of %q has a min above its max", field)
+		}
+	}
+
+	return nil
+}
+
+// KeyName returns the name of the tag or field of the column, which is its
+// name in lowercase.
+func (c TableColumns) KeyName(column string) string {
+	return strings.ToLower(column)
+}
+
+// ValueRange is an inclusive range of values.
+type ValueRange struct {
+	Min int64 `toml:"min"`
+	Max int64 `toml:"max"`
+}
+
+// Contains returns whether the field value v is within the range. Values that
+// aren't numbers are always within it.
+func (r ValueRange) Contains(v any) bool {
+	f, ok := toFloat(v)
+	return !ok || f >= float64(r.Min) && f <= float64(r.Max)
+}
+
+// JoinedTagNames returns the names of the tags of the joins of t, in the order
+// of their columns.
+func (t TableDescription) JoinedTagNames() []string {
+	var names []string
+	for _, j := range t.Joins {
+		for _, tag := range j.Tags {
+			names = append(names, j.TagName(tag))
+		}
+	}
+	return names
+}
+
+// UnknownColumns returns the columns of the table t, given all of its
+// columns, that it doesn't read.
+func (t TableDescription) UnknownColumns(columns []ColumnInfo) []ColumnInfo {
+	known := slices.Concat([]string{t.Columns.Timestamp, t.Columns.UTCOffset}, t.Columns.Tags, t.Columns.Fields, t.Columns.Ignored, t.TableReader().Columns())
+
+	var unknown []ColumnInfo
+	for _, column := range columns {
+		if !slices.Contains(known, column.Name) {
+			unknown = append(unknown, column)
+		}
+	}
+	return unknown
+}
+
+// MissingColumns returns the tag and field columns of t that aren't among
+// the columns of its table, such as those that an older Gadgetbridge didn't
+// have yet.
+func (t TableDescription) MissingColumns(columns []ColumnInfo) []string {
+	var missing []string
+	for _, column := range slices.Concat(t.Columns.Tags, t.Columns.Fields) {
+		if !HasColumn(columns, column) {
+			missing = append(missing, column)
+		}
+	}
+	return missing
+}
+
+// WithoutColumns returns t without the given tag and field columns.
+func (t TableDescription) WithoutColumns(names []string) TableDescription {
+	if len(names) == 0 {
+		return t
+	}
+	omitted := func(column string) bool { return slices.Contains(names, column) }
+	t.Columns.Tags = slices.DeleteFunc(slices.Clone(t.Columns.Tags), omitted)
+	t.Columns.Fields = slices.DeleteFunc(slices.Clone(t.Columns.Fields), omitted)
+	return t
+}
+
+// NullTagsBehavior is how the NULL tag columns of a table's rows are handled.
+type NullTagsBehavior string
+
+const (
+	// NullTagsDropRow drops a row with a NULL tag as unreadable.
+	NullTagsDropRow NullTagsBehavior = "drop_row"
+	// NullTagsDropTag leaves a NULL tag out of the row's tags.
+	NullTagsDropTag NullTagsBehavior = "drop_tag"
+	// NullTagsUnknown sets a NULL tag to NullTagValue.
+	NullTagsUnknown NullTagsBehavior = "unknown"
+)
+
+// NullTagValue is the value that NullTagsUnknown gives NULL tags.
+const NullTagValue = "unknown"
+
+// Validate checks that b is a known behavior.
+func (b NullTagsBehavior) Validate() error {
+	switch b {
+	case "", NullTagsDropRow, NullTagsDropTag, NullTagsUnknown:
+		return nil
+	default:
+		return fmt.Errorf("unknown behavior %q", b)
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// HybridHRActivitySamples describes the activity samples of Fossil Hybrid HR
+// watches, one a minute, which the plugin gathers by default.
+var HybridHRActivitySamples = TableDescription{
+	Name: "HYBRID_HRACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"USER_ID", "DEVICE_ID"},
+		Fields:    []string{"WEAR_TYPE", "STEPS", "CALORIES", "VARIABILITY", "MAX_VARIABILITY", "HEARTRATE_QUALITY", "ACTIVE", "HEART_RATE"},
+		Sentinels: map[string][]int64{
+			"STEPS":      {-1, 65535},
+			"HEART_RATE": {-1, 0, 255},
+		},
+		// The samples are per minute, which no one walks a thousand steps
+		// in.
+		Plausible: map[string]ValueRange{
+			"STEPS": {Min: 0, Max: 1000},
+		},
+	},
+}
+
+// BatteryLevels describes the battery levels that devices of every kind
+// report, which the plugin gathers by default.
+var BatteryLevels = TableDescription{
+	Name: "BATTERY_LEVEL",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "BATTERY_INDEX"},
+		Fields:    []string{"LEVEL"},
+		Sentinels: map[string][]int64{
+			"LEVEL": {-1},
+		},
+	},
+}
+
+// huamiHeartRateTable describes one of the tables of the heart rates that
+// Huami devices measure apart from their activity samples, which share the
+// huami_heart_rate measurement, tagged with the kind of heart rate.
+func huamiHeartRateTable(name, kind string) TableDescription {
+	return TableDescription{
+		Name:        name,
+		Measurement: "huami_heart_rate",
+		Columns: TableColumns{
+			Timestamp: "TIMESTAMP",
+			Tags:      []string{"DEVICE_ID", "USER_ID"},
+			Fields:    []string{"HEART_RATE"},
+			// The timestamps are in UTC, so the device's offset isn't
+			// needed to read them.
+			Ignored: []string{"UTC_OFFSET"},
+			Sentinels: map[string][]int64{
+				"HEART_RATE": {0, 255},
+			},
+		},
+		Reader: TagsReader{
+			ColumnReader: ColumnReader{Milliseconds: true},
+			Tags:         map[string]string{"kind": kind},
+		},
+	}
+}
+
+// HuamiHeartRateManualSamples describes the HUAMI_HEART_RATE_MANUAL_SAMPLE
+// table of the heart rates measured on demand.
+var HuamiHeartRateManualSamples = huamiHeartRateTable("HUAMI_HEART_RATE_MANUAL_SAMPLE", "manual")
+
+// HuamiHeartRateRestingSamples describes the HUAMI_HEART_RATE_RESTING_SAMPLE
+// table of the daily resting heart rates.
+var HuamiHeartRateRestingSamples = huamiHeartRateTable("HUAMI_HEART_RATE_RESTING_SAMPLE", "resting")
+
+// HuamiHeartRateMaxSamples describes the HUAMI_HEART_RATE_MAX_SAMPLE table of
+// the daily maximum heart rates.
+var HuamiHeartRateMaxSamples = huamiHeartRateTable("HUAMI_HEART_RATE_MAX_SAMPLE", "max")
+
+// XiaomiSleepStageSamples describes the XIAOMI_SLEEP_STAGE_SAMPLE table of the
+// sleep stages that Xiaomi devices, such as the Mi Band 8 and later and the
+// Redmi watches, recognized, each from its timestamp until the next.
+var XiaomiSleepStageSamples = TableDescription{
+	Name: "XIAOMI_SLEEP_STAGE_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"STAGE"},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// XiaomiSleepTimeSamples describes the XIAOMI_SLEEP_TIME_SAMPLE table of the
+// sleep sessions of Xiaomi devices, each timestamped with when it began. The
+// WAKEUP_TIME it ended at is in Unix milliseconds, and the durations of the
+// session and of each of its stages are in minutes.
+var XiaomiSleepTimeSamples = TableDescription{
+	Name: "XIAOMI_SLEEP_TIME_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields: []string{
+			"WAKEUP_TIME", "IS_AWAKE", "TOTAL_DURATION", "DEEP_SLEEP_DURATION",
+			"LIGHT_SLEEP_DURATION", "REM_SLEEP_DURATION", "AWAKE_DURATION",
+		},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// GarminStressSamples describes the GARMIN_STRESS_SAMPLE table of the stress
+// levels, from 0 to 100, that Garmin devices estimate from the heart rate.
+var GarminStressSamples = TableDescription{
+	Name: "GARMIN_STRESS_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"STRESS"},
+		// Garmin writes negative levels while it can't estimate one, such
+		// as while the device isn't worn or its wearer is moving.
+		Sentinels: map[string][]int64{
+			"STRESS": {-1, -2},
+		},
+		Plausible: map[string]ValueRange{
+			"STRESS": {Min: 0, Max: 100},
+		},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// GarminBodyEnergySamples describes the GARMIN_BODY_ENERGY_SAMPLE table of the
+// Body Battery levels, from 0 to 100, that Garmin devices estimate the energy
+// reserves of their wearer as.
+var GarminBodyEnergySamples = TableDescription{
+	Name: "GARMIN_BODY_ENERGY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"ENERGY"},
+		Plausible: map[string]ValueRange{
+			"ENERGY": {Min: 0, Max: 100},
+		},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// GarminHRVValueSamples describes the GARMIN_HRV_VALUE_SAMPLE table of the
+// heart rate variability that Garmin devices measure during the night, in
+// milliseconds.
+var GarminHRVValueSamples = TableDescription{
+	Name: "GARMIN_HRV_VALUE_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"VALUE"},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// GarminHRVSummarySamples describes the GARMIN_HRV_SUMMARY_SAMPLE table of the
+// nightly summaries of the heart rate variability of Garmin devices: the
+// averages of the last night and week, the baseline that they're judged
+// against and the status that the device judged them as, by its code.
+var GarminHRVSummarySamples = TableDescription{
+	Name: "GARMIN_HRV_SUMMARY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields: []string{
+			"WEEKLY_AVERAGE", "LAST_NIGHT_AVERAGE", "LAST_NIGHT_5_MIN_HIGH",
+			"BASELINE_LOW_UPPER", "BASELINE_BALANCED_LOWER", "BASELINE_BALANCED_UPPER",
+			"STATUS_NUM",
+		},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// GarminActivitySamples describes the GARMIN_ACTIVITY_SAMPLE table of the
+// activity that Garmin devices record throughout the day. Databases of older
+// Gadgetbridges lack its DISTANCE_CM and ACTIVE_CALORIES.
+var GarminActivitySamples = TableDescription{
+	Name: "GARMIN_ACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"RAW_INTENSITY", "STEPS", "RAW_KIND", "HEART_RATE", "DISTANCE_CM", "ACTIVE_CALORIES"},
+		Sentinels: map[string][]int64{
+			"HEART_RATE": {-1, 0, 255},
+		},
+	},
+}
+
+// PebbleHealthActivitySamples describes the PEBBLE_HEALTH_ACTIVITY_SAMPLE
+// table of the activity that Pebble Health recorded every minute.
+var PebbleHealthActivitySamples = TableDescription{
+	Name: "PEBBLE_HEALTH_ACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"RAW_INTENSITY", "STEPS", "HEART_RATE"},
+		Sentinels: map[string][]int64{
+			"HEART_RATE": {0, 255},
+		},
+		// The samples are decoded from the raw data already.
+		Ignored: []string{"RAW_PEBBLE_HEALTH_DATA"},
+	},
+}
+
+// PebbleHealthActivityOverlays describes the PEBBLE_HEALTH_ACTIVITY_OVERLAY
+// table of the stretches of sleep and exercise that Pebble Health recognized,
+// each timestamped with its TIMESTAMP_FROM and lasting until its
+// TIMESTAMP_TO, in Unix seconds. Their RAW_KIND is tagged as the kind of
+// stretch.
+var PebbleHealthActivityOverlays = TableDescription{
+	Name: "PEBBLE_HEALTH_ACTIVITY_OVERLAY",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP_FROM",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"TIMESTAMP_TO"},
+		Ignored:   []string{"RAW_PEBBLE_HEALTH_DATA"},
+	},
+	Reader: KindReader{
+		Column: "RAW_KIND",
+		Tag:    "kind",
+		Kinds: map[int64]string{
+			1: "sleep",
+			2: "deep_sleep",
+			3: "nap",
+			4: "deep_nap",
+			5: "walk",
+			6: "run",
+		},
+	},
+}
+
+// PebbleMisfitSamples describes the PEBBLE_MISFIT_SAMPLE table of the samples
+// that the Misfit app for Pebble recorded every minute. Each sample is packed
+// into RAW_PEBBLE_MISFIT_SAMPLE, which MisfitReader decodes.
+var PebbleMisfitSamples = TableDescription{
+	Name: "PEBBLE_MISFIT_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+	},
+	Reader: MisfitReader{},
+}
+
+// PebbleMorpheuzSamples describes the PEBBLE_MORPHEUZ_SAMPLE table of the
+// movement that the Morpheuz sleep app for Pebble recorded through the night.
+var PebbleMorpheuzSamples = TableDescription{
+	Name: "PEBBLE_MORPHEUZ_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"RAW_INTENSITY"},
+	},
+}
+
+// FitProActivitySamples describes the FIT_PRO_ACTIVITY_SAMPLE table of the
+// activity that bands speaking the FitPro protocol record, along with their
+// occasional SpO2 and blood pressure measurements.
+var FitProActivitySamples = TableDescription{
+	Name: "FIT_PRO_ACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields: []string{
+			"STEPS",
+			"RAW_KIND",
+			"HEART_RATE",
+			"CALORIES_BURNT",
+			"DISTANCE_METERS",
+			"SPO2_PERCENT",
+			"PRESSURE_LOW_MM_HG",
+			"PRESSURE_HIGH_MM_HG",
+			"ACTIVE_TIME_MINUTES",
+		},
+		// The bands leave the measurements they didn't take at zero.
+		Sentinels: map[string][]int64{
+			"HEART_RATE":          {0, 255},
+			"SPO2_PERCENT":        {0},
+			"PRESSURE_LOW_MM_HG":  {0},
+			"PRESSURE_HIGH_MM_HG": {0},
+		},
+	},
+}
+
+// WithingsSteelHRActivitySamples describes the
+// WITHINGS_STEEL_HRACTIVITY_SAMPLE table of the activity that the Withings
+// Steel HR records, each sample lasting its DURATION in seconds. Gadgetbridge
+// misses the underscore between HR and ACTIVITY in its name.
+var WithingsSteelHRActivitySamples = TableDescription{
+	Name: "WITHINGS_STEEL_HRACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields: []string{
+			"DURATION",
+			"RAW_KIND",
+			"STEPS",
+			"DISTANCE",
+			"CALORIES",
+			"HEART_RATE",
+			"RAW_INTENSITY",
+		},
+		Sentinels: map[string][]int64{
+			"HEART_RATE": {0, 255},
+		},
+	},
+}
+
+// SonySWR12Samples describes the SONY_SWR12_SAMPLE table of the activity that
+// the Sony SmartBand 2 (SWR12) records.
+var SonySWR12Samples = TableDescription{
+	Name: "SONY_SWR12_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"HEART_RATE", "STEPS", "RAW_KIND", "RAW_INTENSITY"},
+		Sentinels: map[string][]int64{
+			"HEART_RATE": {0, 255},
+		},
+	},
+}
+
+// LefunActivitySamples describes the LEFUN_ACTIVITY_SAMPLE table of the
+// activity that devices speaking the Lefun protocol record.
+var LefunActivitySamples = TableDescription{
+	Name: "LEFUN_ACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"RAW_KIND", "STEPS", "DISTANCE", "CALORIES", "HEART_RATE"},
+		Sentinels: map[string][]int64{
+			"HEART_RATE": {0, 255},
+		},
+	},
+}
+
+// LefunBiometricSamples describes the LEFUN_BIOMETRIC_SAMPLE table of the heart
+// rate, blood pressure and blood oxygen that devices speaking the Lefun
+// protocol measure, timestamped in Unix milliseconds. Its TYPE is tagged as
+// the kind of measurement, whose VALUE1 is the heart rate or oxygen
+// saturation, or the systolic pressure along with the diastolic VALUE2.
+var LefunBiometricSamples = TableDescription{
+	Name: "LEFUN_BIOMETRIC_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"VALUE1", "VALUE2"},
+	},
+	Reader: KindReader{
+		ColumnReader: ColumnReader{Milliseconds: true},
+		Column:       "TYPE",
+		Tag:          "type",
+		Kinds: map[int64]string{
+			0: "heart_rate",
+			1: "blood_pressure",
+			2: "blood_oxygen",
+		},
+	},
+}
+
+// LefunSleepSamples describes the LEFUN_SLEEP_SAMPLE table of the sleep that
+// devices speaking the Lefun protocol record, timestamped in Unix
+// milliseconds. Each sample starts a stretch of the TYPE of sleep.
+var LefunSleepSamples = TableDescription{
+	Name: "LEFUN_SLEEP_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"TYPE"},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// HuaweiActivitySamples describes the HUAWEI_ACTIVITY_SAMPLE table of the
+// activity that Huawei bands and watches record. Unlike most samples, each
+// spans from its TIMESTAMP to its OTHER_TIMESTAMP, both in Unix seconds, and
+// more than one SOURCE can record the same span, so the SOURCE is tagged.
+// Values that weren't recorded are -1.
+var HuaweiActivitySamples = TableDescription{
+	Name: "HUAWEI_ACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID", "SOURCE"},
+		Fields: []string{
+			"OTHER_TIMESTAMP",
+			"RAW_KIND",
+			"RAW_INTENSITY",
+			"STEPS",
+			"CALORIES",
+			"DISTANCE",
+			"SPO",
+			"HEART_RATE",
+		},
+		Sentinels: map[string][]int64{
+			"RAW_KIND":      {-1},
+			"RAW_INTENSITY": {-1},
+			"STEPS":         {-1},
+			"CALORIES":      {-1},
+			"DISTANCE":      {-1},
+			"SPO":           {-1},
+			"HEART_RATE":    {-1, 0, 255},
+		},
+	},
+}
+
+// HuaweiWorkoutSummarySamples describes the HUAWEI_WORKOUT_SUMMARY_SAMPLE table
+// of the workouts that Huawei bands and watches record, each timestamped with
+// its START_TIMESTAMP and lasting until its END_TIMESTAMP, in Unix seconds.
+// Their WORKOUT_ID is tagged to tell apart the samples of each in
+// HuaweiWorkoutDataSamples.
+var HuaweiWorkoutSummarySamples = TableDescription{
+	Name: "HUAWEI_WORKOUT_SUMMARY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "START_TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID", "WORKOUT_ID"},
+		Fields: []string{
+			"END_TIMESTAMP",
+			"WORKOUT_NUMBER",
+			"STATUS",
+			"TYPE",
+			"CALORIES",
+			"DISTANCE",
+			"STEP_COUNT",
+			"TOTAL_TIME",
+			"DURATION",
+			"STROKES",
+			"AVG_STROKE_RATE",
+			"POOL_LENGTH",
+			"LAPS",
+			"AVG_SWOLF",
+		},
+		Ignored: []string{"RAW_DATA"},
+	},
+}
+
+// HuaweiWorkoutDataSamples describes the HUAWEI_WORKOUT_DATA_SAMPLE table of
+// the samples that Huawei bands and watches record every few seconds of a
+// workout. The samples only have the WORKOUT_ID of their workout, so its
+// device and user are joined from HuaweiWorkoutSummarySamples, tagged as
+// device_id and user_id like those of the other tables. Values that weren't
+// recorded are -1.
+var HuaweiWorkoutDataSamples = TableDescription{
+	Name: "HUAWEI_WORKOUT_DATA_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"WORKOUT_ID"},
+		Fields: []string{
+			"HEART_RATE",
+			"SPEED",
+			"STEP_RATE",
+			"CADENCE",
+			"STEP_LENGTH",
+			"GROUND_CONTACT_TIME",
+			"IMPACT",
+			"SWING_ANGLE",
+			"FORE_FOOT_LANDING",
+			"MID_FOOT_LANDING",
+			"BACK_FOOT_LANDING",
+			"EVERSION_ANGLE",
+			"SWOLF",
+			"STROKE_RATE",
+			"CALORIES",
+			"CYCLING_POWER",
+			"FREQUENCY",
+			"ALTITUDE",
+		},
+		Sentinels: map[string][]int64{
+			"HEART_RATE":          {-1, 0, 255},
+			"SPEED":               {-1},
+			"STEP_RATE":           {-1},
+			"CADENCE":             {-1},
+			"STEP_LENGTH":         {-1},
+			"GROUND_CONTACT_TIME": {-1},
+			"IMPACT":              {-1},
+			"SWING_ANGLE":         {-1},
+			"FORE_FOOT_LANDING":   {-1},
+			"MID_FOOT_LANDING":    {-1},
+			"BACK_FOOT_LANDING":   {-1},
+			"EVERSION_ANGLE":      {-1},
+			"SWOLF":               {-1},
+			"STROKE_RATE":         {-1},
+			"CALORIES":            {-1},
+			"CYCLING_POWER":       {-1},
+			"FREQUENCY":           {-1},
+			"ALTITUDE":            {-1},
+		},
+		Ignored: []string{"DATA_ERROR_HEX"},
+	},
+	Joins: []TableJoin{{
+		Name: "HUAWEI_WORKOUT_SUMMARY_SAMPLE",
+		On:   map[string]string{"WORKOUT_ID": "WORKOUT_ID"},
+		Tags: []string{"DEVICE_ID", "USER_ID"},
+		As:   map[string]string{"DEVICE_ID": "device_id", "USER_ID": "user_id"},
+	}},
+}
+
+// MiScaleWeightSamples describes the MI_SCALE_WEIGHT_SAMPLE table of the body
+// weights, in kilograms, that Mi scales measure, timestamped in Unix
+// milliseconds. Gadgetbridge doesn't keep the impedance that body
+// composition scales measure.
+var MiScaleWeightSamples = TableDescription{
+	Name: "MI_SCALE_WEIGHT_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"WEIGHT_KG"},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// ColmiActivitySamples describes the COLMI_ACTIVITY_SAMPLE table of the
+// activity that Colmi smart rings record.
+var ColmiActivitySamples = TableDescription{
+	Name: "COLMI_ACTIVITY_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"RAW_INTENSITY", "STEPS", "RAW_KIND", "HEART_RATE", "DISTANCE", "CALORIES"},
+		Sentinels: map[string][]int64{
+			"HEART_RATE": {0, 255},
+		},
+	},
+}
+
+// ColmiHeartRateSamples describes the COLMI_HEART_RATE_SAMPLE table of the
+// heart rates that Colmi smart rings measure periodically, timestamped in Unix
+// milliseconds.
+var ColmiHeartRateSamples = TableDescription{
+	Name: "COLMI_HEART_RATE_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"HEART_RATE"},
+		Sentinels: map[string][]int64{
+			"HEART_RATE": {0, 255},
+		},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// ColmiSpo2Samples describes the COLMI_SPO2_SAMPLE table of the blood oxygen
+// saturations, in percent, that Colmi smart rings measure, timestamped in Unix
+// milliseconds.
+var ColmiSpo2Samples = TableDescription{
+	Name: "COLMI_SPO2_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"SPO2"},
+		Sentinels: map[string][]int64{
+			"SPO2": {0},
+		},
+		Plausible: map[string]ValueRange{
+			"SPO2": {Min: 0, Max: 100},
+		},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// ColmiStressSamples describes the COLMI_STRESS_SAMPLE table of the stress
+// levels, from 0 to 100, that Colmi smart rings measure, timestamped in Unix
+// milliseconds.
+var ColmiStressSamples = TableDescription{
+	Name: "COLMI_STRESS_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"STRESS"},
+		Plausible: map[string]ValueRange{
+			"STRESS": {Min: 0, Max: 100},
+		},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// ColmiSleepStageSamples describes the COLMI_SLEEP_STAGE_SAMPLE table of the
+// stages of sleep that Colmi smart rings record, timestamped in Unix
+// milliseconds. Each stage lasts its DURATION in minutes.
+var ColmiSleepStageSamples = TableDescription{
+	Name: "COLMI_SLEEP_STAGE_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"DURATION", "STAGE"},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
+
+// ColmiSleepSessionSamples describes the COLMI_SLEEP_SESSION_SAMPLE table of
+// the nights that Colmi smart rings record, each timestamped with when it
+// began and lasting until its WAKEUP_TIME, both in Unix milliseconds. Their
+// stages are gathered from ColmiSleepStageSamples instead.
+var ColmiSleepSessionSamples = TableDescription{
+	Name: "COLMI_SLEEP_SESSION_SAMPLE",
+	Columns: TableColumns{
+		Timestamp: "TIMESTAMP",
+		Tags:      []string{"DEVICE_ID", "USER_ID"},
+		Fields:    []string{"WAKEUP_TIME"},
+		Ignored:   []string{"SLEEP_STAGES"},
+	},
+	Reader: ColumnReader{Milliseconds: true},
+}
@@ -0,0 +1,312 @@
+// Package testdb synthesizes Gadgetbridge databases for testing and
+// benchmarking, with as many devices, samples and anomalies as needed. The
+// databases have the schema of Gadgetbridge's own tables for the devices,
+// users, activity samples of Fossil Hybrid HR watches and battery levels.
+package testdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Options configures a generated database.
+type Options struct {
+	// Devices is the number of devices that samples are recorded by, which
+	// is 1 if zero.
+	Devices int
+	// From and To are the range [From, To) that samples are recorded in. A
+	// zero To is the current minute, and a zero From is a day before To.
+	From, To time.Time
+	// Interval is the time between the activity samples of a device, which
+	// is a minute if zero.
+	Interval time.Duration
+	// BatteryInterval is the time between the battery levels of a device,
+	// which is an hour if zero.
+	BatteryInterval time.Duration
+	// Seed seeds the random values, so that the same options generate the
+	// same database.
+	Seed int64
+	// Anomalies are the anomalies injected into the activity samples.
+	Anomalies Anomalies
+}
+
+// Anomalies are the fractions, between 0 and 1, of the activity samples with
+// each kind of anomaly that devices are known to record.
+type Anomalies struct {
+	// Sentinels are samples with the values that devices write when
+	// nothing was measured, such as a heart rate of 255.
+	Sentinels float64
+	// Implausible are samples with more steps than anyone walks in a
+	// minute, such as those of a firmware whose counter wrapped around.
+	Implausible float64
+	// Mistyped are samples with a heart rate written as text that isn't a
+	// number.
+	Mistyped float64
+	// BadTimestamps are samples timestamped in 1970 or 2106 by a device
+	// with a dead clock.
+	BadTimestamps float64
+	// Gaps are samples left out, in stretches of an hour, as if the device
+	// weren't worn or out of reach.
+	Gaps float64
+}
+
+// gapLength is the length of a gap in the samples.
+const gapLength = time.Hour
+
+// Stats counts the rows of a generated database.
+type Stats struct {
+	// Samples is the number of activity samples.
+	Samples int
+	// BatteryLevels is the number of battery levels.
+	BatteryLevels int
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+CREATE TABLE IF NOT EXISTS "DEVICE_ATTRIBUTES" ("_id" INTEGER PRIMARY KEY ,"FIRMWARE_VERSION1" TEXT NOT NULL ,"FIRMWARE_VERSION2" TEXT,"VALID_FROM_UTC" INTEGER,"VALID_TO_UTC" INTEGER,"DEVICE_ID" INTEGER NOT NULL ,"VOLATILE_IDENTIFIER" TEXT);
+CREATE TABLE IF NOT EXISTS "HYBRID_HRACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"CALORIES" INTEGER NOT NULL ,"VARIABILITY" INTEGER NOT NULL ,"MAX_VARIABILITY" INTEGER NOT NULL ,"HEARTRATE_QUALITY" INTEGER NOT NULL ,"ACTIVE" INTEGER NOT NULL ,"WEAR_TYPE" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS "BATTERY_LEVEL" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"LEVEL" INTEGER NOT NULL ,"BATTERY_INDEX" INTEGER  NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ,"BATTERY_INDEX" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+`
+
+// Generate writes a database configured by opts to path, which mustn't exist
+// yet.
+func Generate(path string, opts Options) (Stats, error) {
+	opts = opts.withDefaults()
+	if !opts.From.Before(opts.To) {
+		return Stats{}, errors.New("from must be before to")
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return Stats{}, fmt.Errorf("%s already exists", path)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	defer db.Close()
+
+	// Nothing is lost if the database is half written, so it's written as
+	// fast as SQLite can.
+	if _, err := db.Exec("PRAGMA journal_mode = OFF; PRAGMA synchronous = OFF"); err != nil {
+		return Stats{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return Stats{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(schema); err != nil {
+		return Stats{}, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	g := generator{
+		tx:   tx,
+		opts: opts,
+		rand: rand.New(rand.NewSource(opts.Seed)),
+	}
+	if err := g.generate(); err != nil {
+		return Stats{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Stats{}, err
+	}
+
+	return g.stats, db.Close()
+}
+
+func (o Options) withDefaults() Options {
+	if o.Devices <= 0 {
+		o.Devices = 1
+	}
+	if o.To.IsZero() {
+		o.To = time.Now().Truncate(time.Minute)
+	}
+	if o.From.IsZero() {
+		o.From = o.To.Add(-24 * time.Hour)
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Minute
+	}
+	if o.BatteryInterval <= 0 {
+		o.BatteryInterval = time.Hour
+	}
+	return o
+}
+
+type generator struct {
+	tx    *sql.Tx
+	opts  Options
+	rand  *rand.Rand
+	stats Stats
+}
+
+func (g *generator) generate() error {
+	if _, err := g.tx.Exec(`INSERT INTO USER VALUES (1, 'gadgetbridge-user', 631152000000, 2)`); err != nil {
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+
+	for id := 1; id <= g.opts.Devices; id++ {
+		if err := g.insertDevice(id); err != nil {
+			return fmt.Errorf("failed to insert device %d: %w", id, err)
+		}
+	}
+
+	samples, err := g.tx.Prepare(`INSERT INTO HYBRID_HRACTIVITY_SAMPLE VALUES (?, ?, 1, ?, ?, ?, 76, ?, ?, 0, ?)`)
+	if err != nil {
+		return err
+	}
+	defer samples.Close()
+
+	for id := 1; id <= g.opts.Devices; id++ {
+		if err := g.insertSamples(samples, id); err != nil {
+			return fmt.Errorf("failed to insert samples of device %d: %w", id, err)
+		}
+	}
+
+	levels, err := g.tx.Prepare(`INSERT INTO BATTERY_LEVEL VALUES (?, ?, ?, 0)`)
+	if err != nil {
+		return err
+	}
+	defer levels.Close()
+
+	for id := 1; id <= g.opts.Devices; id++ {
+		if err := g.insertBatteryLevels(levels, id); err != nil {
+			return fmt.Errorf("failed to insert battery levels of device %d: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) insertDevice(id int) error {
+	identifier := fmt.Sprintf("00:00:00:00:%02X:%02X", id>>8&0xff, id&0xff)
+	_, err := g.tx.Exec(
+		`INSERT INTO DEVICE VALUES (?, ?, 'Fossil', ?, 0, 'FOSSILQHYBRID', 'IV.0.0', NULL, NULL)`,
+		id, fmt.Sprintf("Hybrid HR %d", id), identifier)
+	if err != nil {
+		return err
+	}
+	_, err = g.tx.Exec(
+		`INSERT INTO DEVICE_ATTRIBUTES VALUES (?, 'IV0.0.3.0r.v13', '3.0', ?, NULL, ?, NULL)`,
+		id, g.opts.From.UnixMilli(), id)
+	return err
+}
+
+// insertSamples inserts the activity samples of the device, following a day
+// of sleeping at night and walking now and then during the day.
+func (g *generator) insertSamples(stmt *sql.Stmt, device int) error {
+	perMinute := g.opts.Interval.Minutes()
+	heartRate := 60.0
+
+	// A gap starts at a sample with the chance that leaves out the fraction
+	// of samples asked for, given that it's its length long.
+	gap := max(int(gapLength/g.opts.Interval), 1)
+	gaps := g.opts.Anomalies.Gaps
+	gapChance := gaps / (float64(gap)*(1-gaps) + gaps)
+	inGap := 0
+
+	for at := g.opts.From; at.Before(g.opts.To); at = at.Add(g.opts.Interval) {
+		if inGap > 0 {
+			inGap--
+			continue
+		}
+		if g.chance(gapChance) {
+			inGap = gap - 1
+			continue
+		}
+
+		hour := at.Hour()
+		asleep := hour < 7 || hour >= 23
+
+		var steps float64
+		target := 55.0
+		if !asleep {
+			target = 75
+			if g.chance(0.2) {
+				steps = 60 + g.rand.Float64()*60
+				target = 110
+			} else {
+				steps = g.rand.Float64() * 10
+			}
+		}
+		// The heart rate moves towards that of what's being done rather
+		// than jumping to it.
+		heartRate += (target-heartRate)*0.3 + g.rand.NormFloat64()*3
+		steps *= perMinute
+
+		ts := at.Unix()
+		var stepsValue, heartRateValue any = int64(steps), int64(math.Round(heartRate))
+		switch {
+		case g.chance(g.opts.Anomalies.Sentinels):
+			if g.chance(0.5) {
+				heartRateValue = int64(255)
+			} else {
+				stepsValue = int64(65535)
+			}
+		case g.chance(g.opts.Anomalies.Implausible):
+			stepsValue = int64(30000 + g.rand.Intn(30000))
+		case g.chance(g.opts.Anomalies.Mistyped):
+			heartRateValue = "--"
+		case g.chance(g.opts.Anomalies.BadTimestamps):
+			// The timestamps are kept apart so that the samples don't
+			// replace each other.
+			if g.chance(0.5) {
+				ts = int64(g.stats.Samples)
+			} else {
+				ts = math.MaxUint32 - int64(g.stats.Samples)
+			}
+		}
+
+		active := 0
+		if steps > 50 {
+			active = 1
+		}
+
+		_, err := stmt.Exec(
+			ts, device, stepsValue, int64(steps/20),
+			30+g.rand.Intn(40), 1+g.rand.Intn(4), active, heartRateValue)
+		if err != nil {
+			return err
+		}
+		g.stats.Samples++
+	}
+
+	return nil
+}
+
+// insertBatteryLevels inserts the battery levels of the device, which drain
+// over two days and are charged back to full.
+func (g *generator) insertBatteryLevels(stmt *sql.Stmt, device int) error {
+	drain := 100 / (48 * time.Hour).Hours() * g.opts.BatteryInterval.Hours()
+	level := 50 + g.rand.Float64()*50
+
+	for at := g.opts.From; at.Before(g.opts.To); at = at.Add(g.opts.BatteryInterval) {
+		level -= drain
+		if level < 10 {
+			level = 100
+		}
+		if _, err := stmt.Exec(at.Unix(), device, int64(level)); err != nil {
+			return err
+		}
+		g.stats.BatteryLevels++
+	}
+
+	return nil
+}
+
+// chance returns true with the probability p.
+func (g *generator) chance(p float64) bool {
+	return p > 0 && g.rand.Float64() < p
+}
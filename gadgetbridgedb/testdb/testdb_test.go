@@ -0,0 +1,81 @@
+package testdb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestGenerate(t *testing.T) {
+	to := time.Date(2024, 9, 8, 0, 0, 0, 0, time.UTC)
+	opts := Options{
+		Devices: 2,
+		From:    to.Add(-2 * time.Hour),
+		To:      to,
+		Seed:    1,
+	}
+
+	path := filepath.Join(t.TempDir(), "gadgetbridge.db")
+	stats, err := Generate(path, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{Samples: 2 * 120, BatteryLevels: 2 * 2}, stats)
+
+	db, err := sql.Open("sqlite", path)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, 2, count(t, db, "SELECT COUNT(*) FROM DEVICE"))
+	assert.Equal(t, 120, count(t, db, "SELECT COUNT(*) FROM HYBRID_HRACTIVITY_SAMPLE WHERE DEVICE_ID = 2"))
+	assert.Equal(t, 0, count(t, db, "SELECT COUNT(*) FROM HYBRID_HRACTIVITY_SAMPLE WHERE HEART_RATE NOT BETWEEN 30 AND 200 OR STEPS > 1000"))
+
+	_, err = Generate(path, opts)
+	assert.Error(t, err, "the database already exists")
+
+	// The same seed generates the same samples.
+	again := filepath.Join(t.TempDir(), "gadgetbridge.db")
+	_, err = Generate(again, opts)
+	assert.NoError(t, err)
+	_, err = db.Exec("ATTACH DATABASE ? AS again", again)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count(t, db, "SELECT COUNT(*) FROM (SELECT * FROM HYBRID_HRACTIVITY_SAMPLE EXCEPT SELECT * FROM again.HYBRID_HRACTIVITY_SAMPLE)"))
+}
+
+func TestGenerate_Anomalies(t *testing.T) {
+	to := time.Date(2024, 9, 8, 0, 0, 0, 0, time.UTC)
+	generate := func(anomalies Anomalies) *sql.DB {
+		path := filepath.Join(t.TempDir(), "gadgetbridge.db")
+		_, err := Generate(path, Options{From: to.Add(-2 * time.Hour), To: to, Anomalies: anomalies})
+		assert.NoError(t, err)
+
+		db, err := sql.Open("sqlite", path)
+		assert.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	db := generate(Anomalies{Sentinels: 1})
+	assert.Equal(t, 120, count(t, db, "SELECT COUNT(*) FROM HYBRID_HRACTIVITY_SAMPLE WHERE HEART_RATE = 255 OR STEPS = 65535"))
+
+	db = generate(Anomalies{Implausible: 1})
+	assert.Equal(t, 120, count(t, db, "SELECT COUNT(*) FROM HYBRID_HRACTIVITY_SAMPLE WHERE STEPS > 1000"))
+
+	db = generate(Anomalies{Mistyped: 1})
+	assert.Equal(t, 120, count(t, db, "SELECT COUNT(*) FROM HYBRID_HRACTIVITY_SAMPLE WHERE typeof(HEART_RATE) = 'text'"))
+
+	db = generate(Anomalies{BadTimestamps: 1})
+	assert.Equal(t, 120, count(t, db, "SELECT COUNT(*) FROM HYBRID_HRACTIVITY_SAMPLE WHERE TIMESTAMP < 1000 OR TIMESTAMP > 4000000000"))
+
+	db = generate(Anomalies{Gaps: 1})
+	assert.Equal(t, 0, count(t, db, "SELECT COUNT(*) FROM HYBRID_HRACTIVITY_SAMPLE"))
+}
+
+func count(t *testing.T, db *sql.DB, query string) int {
+	t.Helper()
+
+	var n int
+	assert.NoError(t, db.QueryRow(query).Scan(&n))
+	return n
+}
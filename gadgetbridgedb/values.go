@@ -0,0 +1,75 @@
+package gadgetbridgedb
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CoerceValue returns the field value v as read from SQLite, whose columns
+// can hold a value of any type regardless of their declared type, as a number
+// of the type of the column's affinity, so that every value of a field has the
+// same type: an int64 for INTEGER and a float64 for any other. Text that looks
+// like a number, such as a step count written as "42" by some firmware, is
+// parsed as one. ok is false for any other text or blob, and for a number that
+// isn't a whole one in an INTEGER column. NULLs are left as they are.
+func CoerceValue(v any, affinity Affinity) (coerced any, ok bool) {
+	var f float64
+	switch v := v.(type) {
+	case nil:
+		return nil, true
+	case int64:
+		if affinity == AffinityInteger {
+			return v, true
+		}
+		return float64(v), true
+	case float64:
+		f = v
+	case string:
+		return coerceText(v, affinity)
+	case []byte:
+		return coerceText(string(v), affinity)
+	default:
+		return nil, false
+	}
+
+	if affinity != AffinityInteger {
+		return f, true
+	}
+	// Like SQLite, a whole number is taken as an integer. float64(math.MaxInt64)
+	// rounds up to 2^63, which is just out of range.
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return nil, false
+	}
+	return int64(f), true
+}
+
+// coerceText parses the text s as a number for CoerceValue.
+func coerceText(s string, affinity Affinity) (any, bool) {
+	s = strings.TrimSpace(s)
+	if affinity == AffinityInteger {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, false
+	}
+	return CoerceValue(f, affinity)
+}
+
+// IsSentinel returns whether the field value v, as returned by CoerceValue,
+// is one of the sentinels, which devices write when nothing was measured.
+func IsSentinel(sentinels []int64, v any) bool {
+	f, ok := toFloat(v)
+	if !ok {
+		return false
+	}
+	for _, sentinel := range sentinels {
+		if f == float64(sentinel) {
+			return true
+		}
+	}
+	return false
+}
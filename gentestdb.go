@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb/testdb"
+)
+
+// genTestDBCommand writes a synthesized database to the path given as its
+// argument, for testing and benchmarking.
+func genTestDBCommand(args []string) error {
+	fs, global := newFlagSet("gen-testdb", "<database>")
+
+	var opts testdb.Options
+	from := fs.String("from", "",
+		"start of the samples as a date (2006-01-02) or RFC 3339 time, defaults to a day before -to")
+	to := fs.String("to", "",
+		"end of the samples as a date (2006-01-02), which is inclusive, or RFC 3339 time, defaults to now")
+	fs.IntVar(&opts.Devices, "devices", 1, "number of devices that record samples")
+	fs.DurationVar(&opts.Interval, "interval", 0, "time between the activity samples of a device, defaults to 1m")
+	fs.DurationVar(&opts.BatteryInterval, "battery_interval", 0, "time between the battery levels of a device, defaults to 1h")
+	fs.Int64Var(&opts.Seed, "seed", 0, "seed of the random values, so that the same flags write the same database")
+	fs.Float64Var(&opts.Anomalies.Sentinels, "sentinels", 0,
+		"fraction of the samples with values that mean nothing was measured, such as a heart rate of 255")
+	fs.Float64Var(&opts.Anomalies.Implausible, "implausible", 0,
+		"fraction of the samples with more steps than anyone walks in a minute")
+	fs.Float64Var(&opts.Anomalies.Mistyped, "mistyped", 0,
+		"fraction of the samples with a heart rate written as text that isn't a number")
+	fs.Float64Var(&opts.Anomalies.BadTimestamps, "bad_timestamps", 0,
+		"fraction of the samples timestamped in 1970 or 2106")
+	fs.Float64Var(&opts.Anomalies.Gaps, "gaps", 0,
+		"fraction of the samples left out, in stretches of an hour")
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected the path to the database to write")
+	}
+
+	var err error
+	if opts.From, err = parseBackfillTime(*from, false); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	if opts.To, err = parseBackfillTime(*to, true); err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	stats, err := testdb.Generate(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote %d activity samples and %d battery levels to %s\n",
+		stats.Samples, stats.BatteryLevels, fs.Arg(0))
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/plugins/inputs/gadgetbridge"
+
+	"github.com/influxdata/telegraf/plugins/common/shim"
+)
+
+// serveHealth serves the health of the shim's input at addr in the
+// background. /readyz succeeds once a gather has succeeded, and /healthz
+// succeeds as long as one has within maxAge. A zero maxAge disables that
+// check, leaving /healthz to succeed as long as the process is up.
+func serveHealth(s *shim.Shim, addr string, maxAge time.Duration) error {
+	plugin, ok := s.Input.(*gadgetbridge.Plugin)
+	if !ok {
+		return fmt.Errorf("unexpected input type %T", s.Input)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		status := plugin.Status()
+		// Startup is left to /readyz, so no gather having finished yet isn't
+		// unhealthy.
+		healthy := maxAge == 0 || status.LastGather.IsZero() || time.Since(status.LastSuccess) <= maxAge
+		writeStatus(w, status, healthy)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := plugin.Status()
+		writeStatus(w, status, !status.LastSuccess.IsZero())
+	})
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("failed to serve health", "addr", addr, "err", err)
+		}
+	}()
+
+	return nil
+}
+
+// writeStatus writes the status as JSON, failing the request if ok is false.
+func writeStatus(w http.ResponseWriter, status gadgetbridge.Status, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/plugins/inputs/gadgetbridge"
+
+	"github.com/influxdata/telegraf/plugins/common/shim"
+)
+
+// validateCommand validates the config against the schema of each database
+// without emitting any metrics.
+func validateCommand(args []string) error {
+	fs, global := newFlagSet("validate", "")
+	configFile := configFlag(fs)
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return err
+	}
+
+	shimLayer, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	if err := validate(shimLayer); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config:\n%s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(os.Stderr, "Config is valid")
+	return nil
+}
+
+// listTablesCommand prints the tables of the database given as its argument.
+func listTablesCommand(args []string) error {
+	path, err := parseDatabaseArg("list-tables", args)
+	if err != nil {
+		return err
+	}
+	return printTables(os.Stdout, path)
+}
+
+// generateConfigCommand prints a config for the database given as its
+// argument.
+func generateConfigCommand(args []string) error {
+	path, err := parseDatabaseArg("generate-config", args)
+	if err != nil {
+		return err
+	}
+	return printConfig(os.Stdout, path)
+}
+
+// sampleConfigCommand prints the plugin's sample config, or that of the
+// device family given as its argument.
+func sampleConfigCommand(args []string) error {
+	fs, global := newFlagSet("sample-config", "[family]")
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return err
+	}
+
+	switch fs.NArg() {
+	case 0:
+		fmt.Print(new(gadgetbridge.Plugin).SampleConfig())
+		return nil
+	case 1:
+		config, err := gadgetbridge.FamilySampleConfig(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Print(config)
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected at most one device family, one of: %s",
+			strings.Join(gadgetbridge.DeviceFamilies(), ", "))
+	}
+}
+
+// parseDatabaseArg parses the flags of the named command, which takes the
+// path to a database as its only argument, and returns that path.
+func parseDatabaseArg(name string, args []string) (string, error) {
+	fs, global := newFlagSet(name, "<database>")
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return "", err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", errors.New("expected the path to a database")
+	}
+
+	return fs.Arg(0), nil
+}
+
+// validate validates the shim's input against the databases it would gather.
+func validate(s *shim.Shim) error {
+	plugin, ok := s.Input.(*gadgetbridge.Plugin)
+	if !ok {
+		return fmt.Errorf("unexpected input type %T", s.Input)
+	}
+	return plugin.Validate()
+}
+
+// printTables prints every table of the database at path with its columns.
+func printTables(w io.Writer, path string) error {
+	tables, err := gadgetbridge.InspectDatabase(path)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, table := range tables {
+		fmt.Fprintf(tw, "%s\t%d rows", table.Name, table.Rows)
+		if !table.MinTime.IsZero() {
+			fmt.Fprintf(tw, "\t%s\t%s to %s",
+				table.TimestampColumn,
+				table.MinTime.Format(time.RFC3339),
+				table.MaxTime.Format(time.RFC3339))
+		}
+		fmt.Fprintln(tw)
+
+		for _, column := range table.Columns {
+			fmt.Fprintf(tw, "  %s\t%s\n", column.Name, column.Type)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// printConfig prints a plugin config for the database at path that gathers
+// every sample table in it.
+func printConfig(w io.Writer, path string) error {
+	tables, err := gadgetbridge.GenerateExtraTables(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "[[inputs.gadgetbridge]]\n")
+	fmt.Fprintf(w, "  database_paths = [%q]\n", path)
+	fmt.Fprintln(w)
+
+	return gadgetbridge.WriteExtraTables(w, tables)
+}
@@ -0,0 +1,155 @@
+//go:build integration
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+
+	_ "modernc.org/sqlite"
+)
+
+// TestExecd runs the binary under the execd input of the telegraf found in
+// $PATH, or in $TELEGRAF, against the plugin's fixture database. Each run is
+// a separate Telegraf process, so that the state file has to carry what was
+// gathered over to the next.
+//
+//	go test -tags integration -run TestExecd .
+func TestExecd(t *testing.T) {
+	telegraf := os.Getenv("TELEGRAF")
+	if telegraf == "" {
+		var err error
+		telegraf, err = exec.LookPath("telegraf")
+		if err != nil {
+			t.Skip("telegraf isn't installed; set $TELEGRAF to its path")
+		}
+	}
+
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "telegraf-plugin-gadgetbridge")
+	dbPath := filepath.Join(dir, "gadgetbridge.db")
+	statePath := filepath.Join(dir, "state.json")
+	pluginConfig := filepath.Join(dir, "gadgetbridge.conf")
+	telegrafConfig := filepath.Join(dir, "telegraf.conf")
+
+	build := exec.Command("go", "build", "-o", binPath, ".")
+	build.Stderr = os.Stderr
+	assert.NoError(t, build.Run(), "failed to build the binary")
+
+	dump, err := os.ReadFile("plugins/inputs/gadgetbridge/testdata/gadgetbridge.sql")
+	assert.NoError(t, err)
+	execSQL(t, dbPath, string(dump))
+
+	writeFile(t, pluginConfig, fmt.Sprintf(`
+[[inputs.gadgetbridge]]
+  database_paths = [%q]
+`, dbPath))
+
+	writeFile(t, telegrafConfig, fmt.Sprintf(`
+[agent]
+  omit_hostname = true
+
+[[inputs.execd]]
+  command = [%q, "run", "-config", %q, "-state_file", %q, "-poll_interval_disabled"]
+  signal = "STDIN"
+  restart_delay = "1s"
+  data_format = "influx"
+`, binPath, pluginConfig, statePath))
+
+	// runTelegraf runs Telegraf until it has gathered once and the binary
+	// has exited, returning the number of metrics of each measurement.
+	runTelegraf := func(t *testing.T) map[string]int {
+		t.Helper()
+
+		var stdout bytes.Buffer
+		cmd := exec.Command(telegraf, "--config", telegrafConfig, "--test", "--test-wait", "5")
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+		assert.NoError(t, cmd.Run(), "telegraf failed")
+
+		return countMetrics(t, stdout.String())
+	}
+
+	t.Run("first run", func(t *testing.T) {
+		counts := runTelegraf(t)
+		assert.Equal(t, 10, counts["hybrid_hractivity_sample"])
+		assert.Equal(t, 10, counts["battery_level"])
+
+		var state struct {
+			LastTableTimes map[string]int64 `json:"last_table_times"`
+		}
+		b, err := os.ReadFile(statePath)
+		assert.NoError(t, err, "state wasn't saved on exit")
+		assert.NoError(t, json.Unmarshal(b, &state))
+		assert.Equal(t, map[string]int64{
+			"HYBRID_HRACTIVITY_SAMPLE": 1725786000,
+			"BATTERY_LEVEL":            1725842806,
+		}, state.LastTableTimes)
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		counts := runTelegraf(t)
+		assert.Equal(t, 0, counts["hybrid_hractivity_sample"], "rows gathered again after a restart")
+		assert.Equal(t, 0, counts["battery_level"], "rows gathered again after a restart")
+	})
+
+	t.Run("new rows", func(t *testing.T) {
+		execSQL(t, dbPath, `INSERT INTO HYBRID_HRACTIVITY_SAMPLE VALUES(1725786060,1,1,0,12,41,76,3,1,0,88);`)
+
+		counts := runTelegraf(t)
+		assert.Equal(t, 1, counts["hybrid_hractivity_sample"])
+		assert.Equal(t, 0, counts["battery_level"])
+	})
+}
+
+// countMetrics parses the line protocol printed by telegraf --test and counts
+// the metrics of each measurement.
+func countMetrics(t *testing.T, output string) map[string]int {
+	t.Helper()
+
+	parser := &influx.Parser{}
+	assert.NoError(t, parser.Init())
+
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimPrefix(scanner.Text(), "> ")
+		if line == "" {
+			continue
+		}
+
+		metric, err := parser.ParseLine(line)
+		assert.NoError(t, err, "invalid line protocol: %s", line)
+		counts[metric.Name()]++
+	}
+	assert.NoError(t, scanner.Err())
+
+	return counts
+}
+
+func execSQL(t *testing.T, dbPath, query string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(query)
+	assert.NoError(t, err)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
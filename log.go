@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// setupLogging makes slog log to w at the given level and format, either
+// "text" or "json". Telegraf's loggers, which write to the log package with
+// a level prefix, are logged through slog at their own level as well.
+func setupLogging(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+
+	log.SetFlags(0)
+	log.SetOutput(telegrafLogWriter{slog.Default()})
+
+	return nil
+}
+
+// telegrafLevels maps the prefixes of Telegraf's log lines to their levels.
+var telegrafLevels = map[string]slog.Level{
+	"E!": slog.LevelError,
+	"W!": slog.LevelWarn,
+	"I!": slog.LevelInfo,
+	"D!": slog.LevelDebug,
+}
+
+// telegrafLogWriter logs the lines written by Telegraf's loggers to a slog
+// logger.
+type telegrafLogWriter struct {
+	logger *slog.Logger
+}
+
+func (w telegrafLogWriter) Write(b []byte) (int, error) {
+	msg := strings.TrimSpace(string(b))
+
+	level := slog.LevelInfo
+	if prefix, rest, ok := strings.Cut(msg, " "); ok {
+		if l, ok := telegrafLevels[prefix]; ok {
+			level = l
+			msg = strings.TrimSpace(rest)
+		}
+	}
+
+	w.logger.Log(context.Background(), level, msg)
+	return len(b), nil
+}
+
+// fatal logs msg with err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
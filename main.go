@@ -3,40 +3,270 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
-	"time"
-
-	_ "libdb.so/telegraf-plugin-gadgetbridge/gadgetbridge"
+	"strings"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/common/shim"
 )
 
-var pollInterval = flag.Duration("poll_interval", 5*time.Minute, "how often to send metrics")
+// command is a subcommand of the binary.
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
 
-var pollIntervalDisabled = flag.Bool(
-	"poll_interval_disabled",
-	false,
-	"set to true to disable polling. You want to use this when you are sending metrics on your own schedule",
-)
-var configFile = flag.String("config", "", "path to the config file for this plugin")
-var err error
+var commands = []command{
+	{"run", "gather metrics periodically and write them to stdout or the given outputs (default)", runCommand},
+	{"once", "gather metrics once, write them to stdout or the given outputs and exit", onceCommand},
+	{"backfill", "gather the metrics within a time range regardless of what was gathered before", backfillCommand},
+	{"validate", "validate the config against the schema of each database", validateCommand},
+	{"list-tables", "print the tables and columns of a database with their row counts and time ranges", listTablesCommand},
+	{"generate-config", "print a config gathering every sample table found in a database", generateConfigCommand},
+	{"sample-config", "print the sample config, or one tailored to a device family (huami, garmin, banglejs)", sampleConfigCommand},
+	{"gen-testdb", "write a synthesized database with the given devices, samples and anomalies for testing", genTestDBCommand},
+	{"state", "print or reset what has been gathered according to a state file", stateCommand},
+	{"version", "print the version, commit, Go version and features of this binary", versionCommand},
+}
 
 func main() {
-	flag.Parse()
-	if *pollIntervalDisabled {
-		*pollInterval = shim.PollIntervalDisabled
+	// Without a command, the plugin is run just like Telegraf's execd input
+	// expects it to be.
+	name := "run"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(args); err != nil {
+				fatal(name+" failed", err)
+			}
+			return
+		}
+	}
+
+	if name != "help" {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	}
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s%s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -help' for the flags of a command.\n", os.Args[0])
+}
+
+// newFlagSet creates the flag set of the named command with the flags shared
+// by every command. args describes its positional arguments, if any.
+func newFlagSet(name, args string) (*flag.FlagSet, *globalFlags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n\nFlags:\n", os.Args[0], name, args)
+		fs.PrintDefaults()
+	}
+
+	var global globalFlags
+	global.register(fs)
+
+	return fs, &global
+}
+
+// globalFlags are the flags shared by every command.
+type globalFlags struct {
+	logLevel  string
+	logFormat string
+	pprofAddr string
+}
+
+func (f *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.logLevel, "log_level", "info", "minimum level of the logs: debug, info, warn or error")
+	fs.StringVar(&f.logFormat, "log_format", "text", "format of the logs written to stderr: text or json")
+	fs.StringVar(&f.pprofAddr, "pprof_addr", "",
+		"serve net/http/pprof's profiles under /debug/pprof/ at this address, such as localhost:6060")
+}
+
+// setup sets up logging and profiling as configured by the flags. It must be
+// called once the flags are parsed.
+func (f *globalFlags) setup() error {
+	if err := setupLogging(os.Stderr, f.logLevel, f.logFormat); err != nil {
+		return err
+	}
+
+	if f.pprofAddr != "" {
+		if err := servePprof(f.pprofAddr); err != nil {
+			return fmt.Errorf("failed to serve pprof: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// configFlag adds the -config flag to fs.
+func configFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", "", "path to the config file for this plugin")
+}
+
+// stateFileFlag adds the -state_file flag to fs.
+func stateFileFlag(fs *flag.FlagSet) *string {
+	return fs.String(
+		"state_file",
+		"",
+		"file to keep what has been gathered in across runs, so that rows aren't emitted twice. It's saved on exit",
+	)
+}
+
+// loadConfig creates the shim running the plugin configured in configFile.
+// Environment variables in the file are expanded by the shim.
+func loadConfig(configFile string) (*shim.Shim, error) {
+	if configFile != "" {
+		warnUnsetEnvVars(configFile)
+	}
+
+	s := shim.New()
+	if err := s.LoadConfig(&configFile); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
+	return s, nil
+}
+
+// warnUnsetEnvVars warns about every environment variable referred to in
+// configFile that isn't set. The shim expands those to empty strings, which
+// would otherwise silently turn a path like "${DIR}/export.db" into
+// "/export.db".
+func warnUnsetEnvVars(configFile string) {
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		// Loading the config reports this.
+		return
+	}
+
+	warned := make(map[string]bool)
+	os.Expand(string(b), func(name string) string {
+		if _, ok := os.LookupEnv(name); !ok && !warned[name] {
+			warned[name] = true
+			slog.Warn("config refers to unset environment variable", "config", configFile, "name", name)
+		}
+		return ""
+	})
+}
+
+// outputFlags are the flags of the outputs that metrics can be written to
+// instead of stdout.
+type outputFlags struct {
+	influxDBURL    string
+	influxDBOrg    string
+	influxDBBucket string
+	influxDBToken  secretFlag
+
+	mqttBroker   string
+	mqttTopic    string
+	mqttQoS      int
+	mqttUsername string
+	mqttPassword secretFlag
+}
+
+func (f *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.influxDBURL, "influxdb_url", "",
+		"write metrics straight to the InfluxDB v2 server at this URL instead of stdout")
+	fs.StringVar(&f.influxDBOrg, "influxdb_org", "", "organization to write to with -influxdb_url")
+	fs.StringVar(&f.influxDBBucket, "influxdb_bucket", "", "bucket to write to with -influxdb_url")
+	f.influxDBToken.setEnv("INFLUX_TOKEN")
+	fs.Var(&f.influxDBToken, "influxdb_token", "API `token` for -influxdb_url, defaults to $INFLUX_TOKEN")
+
+	fs.StringVar(&f.mqttBroker, "mqtt_broker", "",
+		"publish every gathered field to the MQTT broker at this URL, such as tcp://localhost:1883")
+	fs.StringVar(&f.mqttTopic, "mqtt_topic", `gadgetbridge/{{ .Tag "device_id" }}/{{ .PluginName }}`,
+		"topic template for -mqtt_broker, where .PluginName is the measurement. The field name is appended to it")
+	fs.IntVar(&f.mqttQoS, "mqtt_qos", 0, "QoS level of the messages published with -mqtt_broker")
+	fs.StringVar(&f.mqttUsername, "mqtt_username", "", "username for -mqtt_broker")
+	f.mqttPassword.setEnv("MQTT_PASSWORD")
+	fs.Var(&f.mqttPassword, "mqtt_password", "`password` for -mqtt_broker, defaults to $MQTT_PASSWORD")
+}
+
+// outputs creates the outputs enabled by the flags.
+func (f *outputFlags) outputs() ([]telegraf.Output, error) {
+	var outs []telegraf.Output
+
+	if f.influxDBURL != "" {
+		output, err := newInfluxDBOutput(f.influxDBURL, f.influxDBOrg, f.influxDBBucket, f.influxDBToken.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid InfluxDB output: %w", err)
+		}
+		outs = append(outs, output)
+	}
+
+	if f.mqttBroker != "" {
+		outs = append(outs, newMQTTOutput(f.mqttBroker, f.mqttTopic, f.mqttQoS, f.mqttUsername, f.mqttPassword.Secret))
+	}
+
+	return outs, nil
+}
 
-	// create the shim. This is what will run your plugins.
-	shimLayer := shim.New()
+// secretFlag is a flag holding a credential. It's kept as a config.Secret,
+// just like the credentials of Telegraf's plugins, and never printed, not even
+// as the default in -help.
+type secretFlag struct {
+	config.Secret
+}
+
+// setEnv sets the flag to the value of the environment variable, if it's set.
+func (f *secretFlag) setEnv(name string) {
+	if v, ok := os.LookupEnv(name); ok {
+		f.Set(v)
+	}
+}
+
+func (f *secretFlag) String() string { return "" }
+
+func (f *secretFlag) Set(v string) error {
+	f.Secret = config.NewSecret([]byte(v))
+	return nil
+}
+
+// restoreState loads the state of the shim's input from stateFile, if set.
+func restoreState(s *shim.Shim, stateFile string) error {
+	if stateFile == "" {
+		return nil
+	}
+
+	plugin, ok := s.Input.(telegraf.StatefulPlugin)
+	if !ok {
+		return fmt.Errorf("input %T has no state", s.Input)
+	}
+
+	if err := loadState(plugin, stateFile); err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+
+	return nil
+}
+
+// persistState saves the state of the shim's input to stateFile, if set. The
+// error is logged as well as returned.
+func persistState(s *shim.Shim, stateFile string) error {
+	if stateFile == "" {
+		return nil
+	}
 
-	if err = shimLayer.LoadConfig(configFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Err loading input: %s\n", err)
-		os.Exit(1)
+	plugin, ok := s.Input.(telegraf.StatefulPlugin)
+	if !ok {
+		err := fmt.Errorf("input %T has no state", s.Input)
+		slog.Error("failed to save state", "err", err)
+		return err
 	}
 
-	if err = shimLayer.Run(*pollInterval); err != nil {
-		fmt.Fprintf(os.Stderr, "Err: %s\n", err)
-		os.Exit(1)
+	if err := saveState(plugin, stateFile); err != nil {
+		slog.Error("failed to save state", "err", err)
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestWarnUnsetEnvVars(t *testing.T) {
+	t.Setenv("GADGETBRIDGE_SET", "/data/Gadgetbridge.db")
+
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name:   "set",
+			config: `database_paths = ["$GADGETBRIDGE_SET"]`,
+		},
+		{
+			name:   "unset",
+			config: `database_paths = ["${GADGETBRIDGE_UNSET}"]`,
+			want:   []string{"GADGETBRIDGE_UNSET"},
+		},
+		{
+			name:   "unset twice",
+			config: `database_paths = ["$GADGETBRIDGE_UNSET", "$GADGETBRIDGE_SET", "${GADGETBRIDGE_UNSET}"]`,
+			want:   []string{"GADGETBRIDGE_UNSET"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "gadgetbridge.conf")
+			assert.NoError(t, os.WriteFile(configFile, []byte(test.config), 0o644))
+
+			var buf bytes.Buffer
+			logger := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+			defer slog.SetDefault(logger)
+
+			warnUnsetEnvVars(configFile)
+
+			var names []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if _, name, ok := strings.Cut(line, " name="); ok {
+					names = append(names, name)
+				}
+			}
+			assert.Equal(t, test.want, names)
+		})
+	}
+}
+
+func TestSecretFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		set  string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "flag",
+			set:  "hunter2",
+			want: "hunter2",
+		},
+		{
+			name: "env",
+			env:  map[string]string{"GADGETBRIDGE_TOKEN": "hunter2"},
+			want: "hunter2",
+		},
+		{
+			name: "flag over env",
+			set:  "hunter2",
+			env:  map[string]string{"GADGETBRIDGE_TOKEN": "correct horse"},
+			want: "hunter2",
+		},
+		{
+			name: "neither",
+			want: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			// The environment variable is read when the flag is registered,
+			// before the flags are parsed.
+			var f secretFlag
+			f.setEnv("GADGETBRIDGE_TOKEN")
+			if test.set != "" {
+				assert.NoError(t, f.Set(test.set))
+			}
+
+			// The secret must never show up in the usage as a default.
+			assert.Equal(t, "", f.String())
+
+			secret, err := f.Get()
+			assert.NoError(t, err)
+			defer secret.Destroy()
+			assert.Equal(t, test.want, secret.String())
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/plugins/inputs/gadgetbridge"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/plugins/common/shim"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/plugins/serializers/json"
+)
+
+// testRows is the number of rows gathered from each table with -test.
+const testRows = 10
+
+// gatherFlags are the flags shared by the commands that gather once.
+type gatherFlags struct {
+	configFile    *string
+	outputFormat  string
+	databaseStdin bool
+	from          string
+	to            string
+	tables        string
+	test          bool
+	outputs       outputFlags
+}
+
+func (f *gatherFlags) register(fs *flag.FlagSet) {
+	f.configFile = configFlag(fs)
+	fs.StringVar(&f.outputFormat, "output_format", "influx",
+		"format of the metrics printed to stdout: influx for InfluxDB line protocol, or json for one JSON object per line")
+	fs.BoolVar(&f.databaseStdin, "database_stdin", false,
+		"read a database from stdin and gather it instead of the configured ones, tagged with a database_path of \"stdin\"")
+	fs.StringVar(&f.from, "from", "",
+		"start of the range to gather as a date (2006-01-02) or RFC 3339 time, or empty for no start")
+	fs.StringVar(&f.to, "to", "",
+		"end of the range to gather as a date (2006-01-02), which is inclusive, or RFC 3339 time, or empty for no end")
+	fs.StringVar(&f.tables, "tables", "",
+		"comma-separated glob patterns of the tables to gather, or empty for all configured tables")
+	fs.BoolVar(&f.test, "test", false,
+		fmt.Sprintf("gather only the newest %d rows of every table to quickly try out the config", testRows))
+	f.outputs.register(fs)
+}
+
+// hasRange returns whether any of the flags narrowing down what is gathered
+// are set.
+func (f *gatherFlags) hasRange() bool {
+	return f.from != "" || f.to != "" || f.tables != "" || f.test
+}
+
+// onceCommand gathers metrics once, continuing from -state_file if it's set.
+func onceCommand(args []string) error {
+	fs, global := newFlagSet("once", "")
+	stateFile := stateFileFlag(fs)
+
+	var flags gatherFlags
+	flags.register(fs)
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return err
+	}
+
+	// Gathering a specific range or set of tables once overrides the state for
+	// that run, just like a backfill does.
+	return gatherOnceCommand(&flags, flags.hasRange(), *stateFile)
+}
+
+// backfillCommand gathers the metrics within a range once, regardless of what
+// was gathered before.
+func backfillCommand(args []string) error {
+	fs, global := newFlagSet("backfill", "")
+
+	var flags gatherFlags
+	flags.register(fs)
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return err
+	}
+
+	return gatherOnceCommand(&flags, true, "")
+}
+
+// gatherOnceCommand gathers metrics once as configured by flags and writes
+// them to stdout or the outputs. The state is loaded from and saved to
+// stateFile, if set, unless some metrics couldn't be written.
+func gatherOnceCommand(flags *gatherFlags, backfill bool, stateFile string) error {
+	serializer, err := newSerializer(flags.outputFormat)
+	if err != nil {
+		return fmt.Errorf("invalid output format: %w", err)
+	}
+
+	outs, err := flags.outputs.outputs()
+	if err != nil {
+		return err
+	}
+
+	// The state only tells tables apart, not databases, so the state of the
+	// configured databases would skip the rows of the piped one.
+	if flags.databaseStdin && stateFile != "" {
+		return errors.New("-database_stdin can't be used with -state_file")
+	}
+
+	shimLayer, err := loadConfig(*flags.configFile)
+	if err != nil {
+		return err
+	}
+
+	if err := restoreState(shimLayer, stateFile); err != nil {
+		return err
+	}
+
+	var stdinPath string
+	if flags.databaseStdin {
+		var cleanup func()
+		stdinPath, cleanup, err = useStdinDatabase(shimLayer)
+		if err != nil {
+			return fmt.Errorf("failed to read database from stdin: %w", err)
+		}
+		defer cleanup()
+	}
+
+	gather := shimLayer.Input.Gather
+	if backfill {
+		gather, err = backfillGather(shimLayer, flags)
+		if err != nil {
+			return err
+		}
+	}
+	if stdinPath != "" {
+		gatherPath := gather
+		gather = func(acc telegraf.Accumulator) error {
+			return gatherPath(stdinTaggingAccumulator{acc, stdinPath})
+		}
+	}
+
+	release := holdSignals()
+	if len(outs) > 0 {
+		err = writeOutputsOnce(shimLayer, outs, gather)
+	} else {
+		err = gatherOnce(shimLayer, os.Stdout, serializer, gather)
+	}
+	release()
+
+	// The state would otherwise move past the metrics that were never
+	// written, which the next gather would then skip.
+	if errors.Is(err, errWriteFailed) {
+		if stateFile != "" {
+			slog.Warn("not saving the state since some metrics weren't written")
+		}
+		return err
+	}
+
+	if persistState(shimLayer, stateFile) != nil {
+		return err
+	}
+
+	// Databases are only moved or deleted once their metrics are written and
+	// the state is saved, since they couldn't be gathered again otherwise.
+	if plugin, ok := shimLayer.Input.(*gadgetbridge.Plugin); ok {
+		if perr := plugin.ProcessGathered(); perr != nil {
+			err = errors.Join(err, perr)
+		}
+	}
+	return err
+}
+
+// useStdinDatabase copies the database piped into stdin to a temporary file
+// and makes it the only database of the shim's input, returning its path. The
+// returned function removes the file.
+func useStdinDatabase(s *shim.Shim) (path string, cleanup func(), err error) {
+	plugin, ok := s.Input.(*gadgetbridge.Plugin)
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected input type %T", s.Input)
+	}
+
+	f, err := os.CreateTemp("", "gadgetbridge-stdin-*.db")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary database: %w", err)
+	}
+	cleanup = func() { os.Remove(f.Name()) }
+
+	if _, err := io.Copy(f, os.Stdin); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("failed to read database from stdin: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write temporary database: %w", err)
+	}
+
+	plugin.DatabasePaths = []string{f.Name()}
+	// Nothing else that's configured is about the piped database: its copy
+	// has no checksum to verify and is removed rather than processed.
+	plugin.SettingsPaths = nil
+	plugin.VerifyChecksums = false
+	plugin.ChecksumManifest = ""
+	plugin.ProcessedAction = gadgetbridge.ProcessedKeep
+
+	return f.Name(), cleanup, nil
+}
+
+// stdinDatabasePath is the database_path tag of the database piped into stdin,
+// in place of the path of its temporary copy, which changes every time.
+const stdinDatabasePath = "stdin"
+
+// stdinTaggingAccumulator tags the metrics of the database at path with
+// stdinDatabasePath.
+type stdinTaggingAccumulator struct {
+	telegraf.Accumulator
+	path string
+}
+
+func (a stdinTaggingAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if tags["database_path"] == a.path {
+		tags = maps.Clone(tags)
+		tags["database_path"] = stdinDatabasePath
+	}
+	a.Accumulator.AddFields(measurement, fields, tags, t...)
+}
+
+// backfillGather returns a function gathering the metrics of the shim's input
+// selected by the range of flags.
+func backfillGather(s *shim.Shim, flags *gatherFlags) (func(telegraf.Accumulator) error, error) {
+	plugin, ok := s.Input.(*gadgetbridge.Plugin)
+	if !ok {
+		return nil, fmt.Errorf("unexpected input type %T", s.Input)
+	}
+
+	fromTime, err := parseBackfillTime(flags.from, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -from: %w", err)
+	}
+
+	toTime, err := parseBackfillTime(flags.to, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -to: %w", err)
+	}
+
+	if !fromTime.IsZero() && !toTime.IsZero() && !fromTime.Before(toTime) {
+		return nil, errors.New("-from must be before -to")
+	}
+
+	opts := gadgetbridge.BackfillOptions{
+		From: fromTime,
+		To:   toTime,
+	}
+	if flags.tables != "" {
+		opts.Tables = strings.Split(flags.tables, ",")
+	}
+	if flags.test {
+		opts.Limit = testRows
+	}
+
+	return func(acc telegraf.Accumulator) error {
+		return plugin.Backfill(acc, opts)
+	}, nil
+}
+
+// parseBackfillTime parses a date in local time or an RFC 3339 time. If end is
+// true, a date is taken to include the whole day.
+func parseBackfillTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// newSerializer creates a serializer writing metrics in the given format,
+// either "influx" or "json". Both write one metric per line.
+func newSerializer(format string) (telegraf.Serializer, error) {
+	var serializer interface {
+		telegraf.Serializer
+		Init() error
+	}
+
+	switch format {
+	case "influx":
+		serializer = &influx.Serializer{}
+	case "json":
+		serializer = &json.Serializer{}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if err := serializer.Init(); err != nil {
+		return nil, fmt.Errorf("creating serializer failed: %w", err)
+	}
+
+	return serializer, nil
+}
+
+// gatherOnce runs gather once with an accumulator of the shim and writes the
+// metrics to w using serializer.
+func gatherOnce(s *shim.Shim, w io.Writer, serializer telegraf.Serializer, gather func(telegraf.Accumulator) error) error {
+	metricCh := make(chan telegraf.Metric, 1)
+	writeErr := make(chan error, 1)
+
+	go func() {
+		bw := bufio.NewWriter(w)
+
+		var err error
+		for m := range metricCh {
+			if err == nil {
+				var b []byte
+				if b, err = serializer.Serialize(m); err == nil {
+					_, err = bw.Write(b)
+				}
+			}
+		}
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", errWriteFailed, err)
+		}
+
+		writeErr <- err
+	}()
+
+	acc := agent.NewAccumulator(s, metricCh)
+	gatherErr := gather(acc)
+	close(metricCh)
+
+	return errors.Join(gatherErr, <-writeErr)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/shim"
+	telegraftest "github.com/influxdata/telegraf/testutil"
+)
+
+func TestParseBackfillTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		end     bool
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "date",
+			s:    "2024-03-01",
+			want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name: "end date",
+			s:    "2024-03-01",
+			end:  true,
+			want: time.Date(2024, 3, 2, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name: "RFC 3339",
+			s:    "2024-03-01T12:30:00Z",
+			want: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "end RFC 3339",
+			s:    "2024-03-01T12:30:00Z",
+			end:  true,
+			want: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid",
+			s:       "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseBackfillTime(test.s, test.end)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, test.want.Equal(got), "got %v, want %v", got, test.want)
+		})
+	}
+}
+
+func TestNewSerializer(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			format: "influx",
+			want:   "gadgetbridge_battery level=1i 0\n",
+		},
+		{
+			format: "json",
+			want:   `{"fields":{"level":1},"name":"gadgetbridge_battery","tags":{},"timestamp":0}` + "\n",
+		},
+		{
+			format:  "csv",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			serializer, err := newSerializer(test.format)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			b, err := serializer.Serialize(testMetric(1))
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, string(b))
+		})
+	}
+}
+
+// errWriter fails every write.
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestGatherOnce_WriteErrors(t *testing.T) {
+	gatherErr := errors.New("database is locked")
+
+	tests := []struct {
+		name          string
+		gatherErr     error
+		failWrite     bool
+		wantErr       bool
+		wantWriteFail bool
+	}{
+		{
+			name: "ok",
+		},
+		{
+			name:      "gather",
+			gatherErr: gatherErr,
+			wantErr:   true,
+		},
+		{
+			name:          "write",
+			failWrite:     true,
+			wantErr:       true,
+			wantWriteFail: true,
+		},
+	}
+
+	gather := func(err error) func(telegraf.Accumulator) error {
+		return func(acc telegraf.Accumulator) error {
+			acc.AddMetric(testMetric(1))
+			return err
+		}
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := func(t *testing.T, err error) {
+				if !test.wantErr {
+					assert.NoError(t, err)
+					return
+				}
+				assert.Error(t, err)
+				// Only write errors keep the state from being saved.
+				assert.Equal(t, test.wantWriteFail, errors.Is(err, errWriteFailed))
+			}
+
+			t.Run("stdout", func(t *testing.T) {
+				serializer, err := newSerializer("influx")
+				assert.NoError(t, err)
+
+				var w strings.Builder
+				var out io.Writer = &w
+				if test.failWrite {
+					out = errWriter{}
+				}
+
+				check(t, gatherOnce(shim.New(), out, serializer, gather(test.gatherErr)))
+			})
+
+			t.Run("outputs", func(t *testing.T) {
+				output := &fakeOutput{fail: test.failWrite}
+				err := writeOutputs(shim.New(), []telegraf.Output{output}, gather(test.gatherErr))
+				check(t, err)
+			})
+		})
+	}
+}
+
+func TestStdinTaggingAccumulator(t *testing.T) {
+	acc := new(telegraftest.Accumulator)
+	stdinAcc := stdinTaggingAccumulator{acc, "/tmp/gadgetbridge-stdin-1.db"}
+
+	tags := map[string]string{"database_path": "/tmp/gadgetbridge-stdin-1.db", "device_id": "1"}
+	stdinAcc.AddFields("gadgetbridge_battery", map[string]any{"level": 50}, tags)
+	stdinAcc.AddFields("gadgetbridge_battery", map[string]any{"level": 60}, map[string]string{"database_path": "/data/gadgetbridge.db"})
+
+	assert.Equal(t, "stdin", acc.Metrics[0].Tags["database_path"])
+	assert.Equal(t, "1", acc.Metrics[0].Tags["device_id"])
+	assert.Equal(t, "/data/gadgetbridge.db", acc.Metrics[1].Tags["database_path"])
+	// The plugin reuses the tags of a table's rows, so they're left as they
+	// are.
+	assert.Equal(t, "/tmp/gadgetbridge-stdin-1.db", tags["database_path"])
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/common/shim"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/outputs/influxdb_v2"
+	"github.com/influxdata/telegraf/plugins/outputs/mqtt"
+	"github.com/influxdata/telegraf/plugins/outputs/prometheus_client"
+)
+
+// newInfluxDBOutput creates an InfluxDB v2 output writing to the given
+// bucket.
+func newInfluxDBOutput(url, org, bucket string, token config.Secret) (telegraf.Output, error) {
+	if org == "" || bucket == "" {
+		return nil, errors.New("both -influxdb_org and -influxdb_bucket are required")
+	}
+
+	output := outputs.Outputs["influxdb_v2"]().(*influxdb_v2.InfluxDB)
+	output.URLs = []string{url}
+	output.Organization = org
+	output.Bucket = bucket
+	output.Token = token
+
+	return output, nil
+}
+
+// newPrometheusOutput creates an output serving the latest value of every
+// field as a Prometheus gauge on /metrics at the given address.
+func newPrometheusOutput(listen string) telegraf.Output {
+	output := outputs.Outputs["prometheus_client"]().(*prometheus_client.PrometheusClient)
+	output.Listen = listen
+	output.MetricVersion = 2
+	output.TypeMappings.Gauge = []string{"*"}
+	output.CollectorsExclude = []string{"gocollector", "process"}
+	// Samples may be gathered far less often than they are scraped, so they
+	// are kept until a newer one replaces them.
+	output.ExpirationInterval = 0
+
+	return output
+}
+
+// newMQTTOutput creates an output publishing every field to its own topic on
+// the given broker. topic is a template as understood by Telegraf's MQTT
+// output, with the field name appended to it.
+func newMQTTOutput(broker, topic string, qos int, username string, password config.Secret) telegraf.Output {
+	output := outputs.Outputs["mqtt"]().(*mqtt.MQTT)
+	output.Servers = []string{broker}
+	output.Topic = topic
+	output.QoS = qos
+	output.Username = config.NewSecret([]byte(username))
+	output.Password = password
+	// Plain values per field are what Home Assistant's MQTT sensors expect,
+	// and retaining them lets sensors show the last value right away.
+	output.Layout = "field"
+	output.Retain = true
+
+	return output
+}
+
+// flushInterval is how often metrics added outside of the polled gathers,
+// such as by a service input, are written to the outputs.
+const flushInterval = time.Second
+
+// metricBufferLimit is the number of metrics kept for each output while it
+// fails to write them, after which the oldest are dropped, like Telegraf's
+// metric_buffer_limit.
+const metricBufferLimit = 10000
+
+// runOutputs gathers the shim's input every interval, unless polling is
+// disabled, and writes the metrics to every output until the process is
+// interrupted. A service input is started as well, and the metrics it adds on
+// its own are written within a second.
+//
+// Metrics that an output fails to write are written again with the next
+// ones. If some are still unwritten once the process is interrupted, the
+// state of the input is set back to that of the last gather whose metrics
+// were all written, so that they're gathered again once the state is loaded.
+func runOutputs(s *shim.Shim, outs []telegraf.Output, interval time.Duration) error {
+	if err := connectOutputs(s, outs); err != nil {
+		return err
+	}
+	defer closeOutputs(outs)
+
+	stateful, _ := s.Input.(telegraf.StatefulPlugin)
+	getState := func() any {
+		if stateful == nil {
+			return nil
+		}
+		return stateful.GetState()
+	}
+
+	// The channel is unbuffered, so every metric of a gather has been
+	// received by the time the gather returns.
+	metricCh := make(chan telegraf.Metric)
+	flushCh := make(chan any)
+	written := make(chan any, 1)
+	w := newOutputWriter(outs, getState())
+	go func() {
+		written <- w.run(metricCh, flushCh)
+	}()
+	defer func() {
+		close(metricCh)
+		state := <-written
+		if !w.done() && stateful != nil {
+			slog.Warn("some metrics were never written, so the state is kept from before they were gathered")
+			stateful.SetState(state)
+		}
+	}()
+
+	acc := agent.NewAccumulator(s, metricCh)
+
+	if service, ok := s.Input.(telegraf.ServiceInput); ok {
+		if err := service.Start(acc); err != nil {
+			return fmt.Errorf("failed to start input: %w", err)
+		}
+		// The input may add metrics until it's stopped, so it's stopped
+		// before the channel is closed.
+		defer service.Stop()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var tick <-chan time.Time
+	if interval != shim.PollIntervalDisabled {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		if err := s.Input.Gather(acc); err != nil {
+			slog.Error("failed to gather", "err", err)
+		}
+		// Write the gather's metrics right away rather than at the next
+		// flush. No gather is in progress while the state is taken, so the
+		// metrics of every gather it covers have been received.
+		flushCh <- getState()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+		}
+	}
+}
+
+// outputWriter writes metrics to outputs, keeping those that an output failed
+// to write to write them again with the next ones.
+type outputWriter struct {
+	buffers []outputBuffer
+	// written is the state of the input as of the last gather whose metrics
+	// were all written.
+	written any
+	// dropped is the number of metrics dropped because a buffer was full,
+	// after which written stays as it is.
+	dropped int
+}
+
+// outputBuffer is an output along with the metrics it has yet to write.
+type outputBuffer struct {
+	output  telegraf.Output
+	metrics []telegraf.Metric
+}
+
+func newOutputWriter(outs []telegraf.Output, state any) *outputWriter {
+	w := &outputWriter{written: state}
+	for _, output := range outs {
+		w.buffers = append(w.buffers, outputBuffer{output: output})
+	}
+	return w
+}
+
+// add adds m to the metrics to write, dropping the oldest metric of the
+// buffers that are full.
+func (w *outputWriter) add(m telegraf.Metric) {
+	for i := range w.buffers {
+		b := &w.buffers[i]
+		if len(b.metrics) >= metricBufferLimit {
+			b.metrics = slices.Delete(b.metrics, 0, 1)
+			if w.dropped == 0 {
+				slog.Error("metric buffer is full, dropping the oldest metrics", "limit", metricBufferLimit)
+			}
+			w.dropped++
+		}
+		b.metrics = append(b.metrics, m)
+	}
+}
+
+// flush writes the buffered metrics to every output. state, if not nil, is the
+// state of the input as of the metrics added so far, which becomes the written
+// state once they're all written.
+func (w *outputWriter) flush(state any) error {
+	var errs []error
+	for i := range w.buffers {
+		b := &w.buffers[i]
+		if len(b.metrics) == 0 {
+			continue
+		}
+		if err := b.output.Write(b.metrics); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", errWriteFailed, err))
+			continue
+		}
+		b.metrics = nil
+	}
+
+	if state != nil && w.done() {
+		w.written = state
+	}
+	return errors.Join(errs...)
+}
+
+// done returns whether every metric added so far was written.
+func (w *outputWriter) done() bool {
+	for _, b := range w.buffers {
+		if len(b.metrics) > 0 {
+			return false
+		}
+	}
+	return w.dropped == 0
+}
+
+// run writes the metrics received from metricCh to every output whenever
+// flushCh receives a state, every flushInterval and once metricCh is closed.
+// It returns the written state.
+func (w *outputWriter) run(metricCh <-chan telegraf.Metric, flushCh <-chan any) any {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	// The state of the last gather is kept until its metrics are written,
+	// even if that takes more than one flush.
+	var state any
+	flush := func() {
+		if err := w.flush(state); err != nil {
+			slog.Error("failed to write metrics, retrying with the next ones", "err", err)
+		}
+	}
+
+	for {
+		select {
+		case m, ok := <-metricCh:
+			if !ok {
+				flush()
+				return w.written
+			}
+			w.add(m)
+		case state = <-flushCh:
+			flush()
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// errWriteFailed is wrapped by the errors of metrics that couldn't be
+// written, after which the state must not be saved.
+var errWriteFailed = errors.New("failed to write metrics")
+
+// writeOutputsOnce runs gather once with an accumulator of the shim and
+// writes the metrics to every output.
+func writeOutputsOnce(s *shim.Shim, outs []telegraf.Output, gather func(telegraf.Accumulator) error) error {
+	if err := connectOutputs(s, outs); err != nil {
+		return err
+	}
+	defer closeOutputs(outs)
+
+	return writeOutputs(s, outs, gather)
+}
+
+// connectOutputs initializes every output and connects it. Outputs that were
+// already connected are closed again if one of them fails.
+func connectOutputs(s *shim.Shim, outs []telegraf.Output) error {
+	for i, output := range outs {
+		models.SetLoggerOnPlugin(output, s.Log())
+		if o, ok := output.(telegraf.Initializer); ok {
+			if err := o.Init(); err != nil {
+				closeOutputs(outs[:i])
+				return fmt.Errorf("failed to init output: %w", err)
+			}
+		}
+
+		if err := output.Connect(); err != nil {
+			closeOutputs(outs[:i])
+			return fmt.Errorf("failed to connect output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func closeOutputs(outs []telegraf.Output) {
+	for _, output := range outs {
+		output.Close()
+	}
+}
+
+// writeOutputs runs gather once with an accumulator of the shim and writes the
+// metrics to every output.
+func writeOutputs(s *shim.Shim, outs []telegraf.Output, gather func(telegraf.Accumulator) error) error {
+	metrics, err := gatherMetrics(s, gather)
+	errs := []error{err}
+
+	if len(metrics) > 0 {
+		for _, output := range outs {
+			if err := output.Write(metrics); err != nil {
+				errs = append(errs, fmt.Errorf("%w: %w", errWriteFailed, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// gatherMetrics runs gather once with an accumulator of the shim and returns
+// the gathered metrics.
+func gatherMetrics(s *shim.Shim, gather func(telegraf.Accumulator) error) ([]telegraf.Metric, error) {
+	metricCh := make(chan telegraf.Metric, 1)
+	done := make(chan []telegraf.Metric, 1)
+
+	go func() {
+		var metrics []telegraf.Metric
+		for m := range metricCh {
+			metrics = append(metrics, m)
+		}
+		done <- metrics
+	}()
+
+	acc := agent.NewAccumulator(s, metricCh)
+	err := gather(acc)
+	close(metricCh)
+
+	return <-done, err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/influxdata/telegraf"
+	telegraftest "github.com/influxdata/telegraf/testutil"
+)
+
+// fakeOutput records the batches written to it, failing the writes while fail
+// is set.
+type fakeOutput struct {
+	fail    bool
+	batches [][]telegraf.Metric
+}
+
+func (o *fakeOutput) SampleConfig() string { return "" }
+func (o *fakeOutput) Connect() error       { return nil }
+func (o *fakeOutput) Close() error         { return nil }
+
+func (o *fakeOutput) Write(metrics []telegraf.Metric) error {
+	if o.fail {
+		return errors.New("broker unreachable")
+	}
+	o.batches = append(o.batches, metrics)
+	return nil
+}
+
+func testMetric(value int) telegraf.Metric {
+	return telegraftest.MustMetric("gadgetbridge_battery", nil, map[string]any{"level": value}, time.Unix(0, 0))
+}
+
+func TestOutputWriter_Retry(t *testing.T) {
+	influx := &fakeOutput{}
+	mqtt := &fakeOutput{fail: true}
+	w := newOutputWriter([]telegraf.Output{influx, mqtt}, "before")
+
+	w.add(testMetric(1))
+	err := w.flush("first")
+	assert.IsError(t, err, errWriteFailed)
+	assert.False(t, w.done())
+	assert.Equal(t, "before", w.written)
+
+	// The output that failed gets the metrics of the first flush along with
+	// the new ones, while the other only gets the new ones.
+	mqtt.fail = false
+	w.add(testMetric(2))
+	assert.NoError(t, w.flush("second"))
+	assert.True(t, w.done())
+	assert.Equal(t, "second", w.written)
+
+	assert.Equal(t, [][]telegraf.Metric{{testMetric(1)}, {testMetric(2)}}, influx.batches)
+	assert.Equal(t, [][]telegraf.Metric{{testMetric(1), testMetric(2)}}, mqtt.batches)
+}
+
+func TestOutputWriter_BufferLimit(t *testing.T) {
+	output := &fakeOutput{fail: true}
+	w := newOutputWriter([]telegraf.Output{output}, "before")
+
+	for i := range metricBufferLimit + 1 {
+		w.add(testMetric(i))
+	}
+	assert.Equal(t, metricBufferLimit, len(w.buffers[0].metrics))
+	assert.Equal(t, testMetric(1), w.buffers[0].metrics[0])
+
+	// The dropped metric is never written, so the state stays before it.
+	output.fail = false
+	assert.NoError(t, w.flush("after"))
+	assert.False(t, w.done())
+	assert.Equal(t, "before", w.written)
+}
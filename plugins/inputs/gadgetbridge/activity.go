@@ -0,0 +1,210 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/influxdata/telegraf"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// activityFileGatherer gathers the file at path that's referenced by a
+// recorded activity. The given tags identify the activity.
+type activityFileGatherer func(acc telegraf.Accumulator, path string, tags map[string]string) error
+
+// gatherActivityFiles gathers the files referenced in the given column of
+// BASE_ACTIVITY_SUMMARY for activities that haven't been gathered yet. Only
+// references matching the LIKE pattern, which SQLite matches
+// case-insensitively, are gathered.
+//
+// The state under stateKey holds the start time that the next gather reads
+// the activities from and, in pluginState.LastTableRows, the IDs of those
+// after it that were already gathered. A file that's missing, such as one
+// that has yet to be copied over from the phone, or that fails to be gathered
+// keeps the start time at its activity, so that it's tried again on every
+// gather until it succeeds.
+func (p *Plugin) gatherActivityFiles(
+	acc telegraf.Accumulator, db *sql.DB, dbPath string,
+	column, pattern, stateKey string, gather activityFileGatherer, opts gatherOptions,
+) error {
+	columns, err := gadgetbridgedb.Columns(db, "BASE_ACTIVITY_SUMMARY")
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		p.log.Warnf("Skipping activities missing from %q", dbPath)
+		return nil
+	}
+
+	q := sqliteBuilder.
+		From("BASE_ACTIVITY_SUMMARY").
+		Select("_id", "START_TIME", "DEVICE_ID", "USER_ID", column).
+		Where(goqu.C(column).Like(pattern)).
+		Order(goqu.C("START_TIME").Asc())
+	if opts.backfill {
+		q = opts.where(q, "START_TIME", time.Time.UnixMilli)
+		q = opts.newest(q, "START_TIME")
+	} else if lastTime, ok := p.state.LastTableTimes[stateKey]; ok {
+		// A state from before the IDs were kept only has the start time of
+		// the last activity gathered.
+		if _, ok := p.state.LastTableRows[stateKey]; ok {
+			q = q.Where(goqu.C("START_TIME").Gte(lastTime))
+		} else {
+			q = q.Where(goqu.C("START_TIME").Gt(lastTime))
+		}
+	}
+
+	qSQL, qArgs, err := q.ToSQL()
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	stats := p.newTableStats(stateKey)
+	countingAcc := countingAccumulator{acc, stats.metricsEmitted}
+
+	var gathered []string
+	if !opts.backfill {
+		gathered = p.state.LastTableRows[stateKey]
+	}
+
+	// The activities read, in the order of their start times, along with
+	// whether they're gathered by now.
+	type activity struct {
+		id        string
+		startTime int64
+		gathered  bool
+	}
+	var activities []activity
+
+	var n, dropped, failed int
+	var dropErr error
+	for r.Next() {
+		var activityID, startTime int64
+		var deviceID, userID, ref string
+		if err := r.Scan(&activityID, &startTime, &deviceID, &userID, &ref); err != nil {
+			if dropped == 0 {
+				dropErr = err
+			}
+			dropped++
+			continue
+		}
+		n++
+
+		id := fmt.Sprint(activityID)
+		if slices.Contains(gathered, id) {
+			activities = append(activities, activity{id, startTime, true})
+			continue
+		}
+
+		tags := map[string]string{
+			"database_path": dbPath,
+			"device_id":     deviceID,
+			"user_id":       userID,
+			"activity_id":   id,
+		}
+
+		// A missing or broken file shouldn't stop the activities after it from
+		// being gathered, so it's only reported.
+		path, err := p.resolveActivityFile(dbPath, ref)
+		if err == nil {
+			err = gather(countingAcc, path, tags)
+		}
+		if err != nil {
+			failed++
+			stats.errors.Incr(1)
+			acc.AddError(fmt.Errorf("activity %d: %w", activityID, err))
+		}
+		activities = append(activities, activity{id, startTime, err == nil})
+	}
+
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("error reading rows: %w", err)
+	}
+
+	if !opts.backfill && len(activities) > 0 {
+		// The next gather reads from the first activity that failed, or
+		// otherwise the last one.
+		from := activities[len(activities)-1].startTime
+		if i := slices.IndexFunc(activities, func(a activity) bool { return !a.gathered }); i >= 0 {
+			from = activities[i].startTime
+		}
+
+		after := []string{}
+		for _, a := range activities {
+			if a.gathered && a.startTime >= from {
+				after = append(after, a.id)
+			}
+		}
+
+		p.state.LastTableTimes[stateKey] = from
+		p.state.LastTableRows[stateKey] = after
+	}
+
+	stats.rowsRead.Incr(int64(n + dropped))
+	stats.rowsDropped.Incr(int64(dropped))
+
+	if dropped > 0 {
+		p.log.Warnf("Dropped %d unreadable activities with a %s of %q, the first because of: %v", dropped, column, dbPath, dropErr)
+		addTableError(acc, dbPath, stateKey, errorDroppedRows, dropped)
+	}
+	if failed > 0 {
+		addTableError(acc, dbPath, stateKey, errorActivityFile, failed)
+	}
+
+	p.log.Debugf("Gathered %d activities with a %s of %q", n, column, dbPath)
+	return nil
+}
+
+// resolveActivityFile finds the file referenced by an activity summary row.
+// The database stores paths as they were on the phone, so the reference is
+// looked up under each of the search roots, which are TrackSearchPaths
+// followed by the database's directory. For absolute references, every
+// suffix of the path is tried under each root, from the longest to the base
+// name.
+func (p *Plugin) resolveActivityFile(dbPath, ref string) (string, error) {
+	if filepath.IsAbs(ref) {
+		if _, err := os.Stat(ref); err == nil {
+			return ref, nil
+		}
+	}
+
+	roots := append(p.TrackSearchPaths[:len(p.TrackSearchPaths):len(p.TrackSearchPaths)], filepath.Dir(dbPath))
+	suffixes := pathSuffixes(ref)
+
+	for _, root := range roots {
+		for _, suffix := range suffixes {
+			candidate := filepath.Join(root, suffix)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("activity file %q not found", ref)
+}
+
+// pathSuffixes returns every suffix of the path's components, from the whole
+// path down to the base name.
+func pathSuffixes(path string) []string {
+	parts := strings.FieldsFunc(filepath.ToSlash(path), func(r rune) bool { return r == '/' })
+
+	suffixes := make([]string, len(parts))
+	for i := range parts {
+		suffixes[i] = filepath.Join(parts[i:]...)
+	}
+
+	return suffixes
+}
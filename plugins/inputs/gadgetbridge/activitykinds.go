@@ -0,0 +1,40 @@
+package gadgetbridge
+
+import "strconv"
+
+// activityKinds maps the codes of Gadgetbridge's ActivityKind, as stored in
+// BASE_ACTIVITY_SUMMARY.ACTIVITY_KIND, to the names of the sports they stand
+// for. The names follow those of the enum.
+var activityKinds = map[int64]string{
+	0x00000000: "unknown",
+	0x00000001: "activity",
+	0x00000010: "running",
+	0x00000020: "walking",
+	0x00000040: "swimming",
+	0x00000080: "cycling",
+	0x00000100: "treadmill",
+	0x00000200: "exercise",
+	0x00000400: "swimming_openwater",
+	0x00000800: "indoor_cycling",
+	0x00001000: "elliptical_trainer",
+	0x00002000: "jump_roping",
+	0x00004000: "yoga",
+	0x00008000: "soccer",
+	0x00010000: "rowing_machine",
+	0x00020000: "cricket",
+	0x00040000: "basketball",
+	0x00080000: "pingpong",
+	0x00100000: "badminton",
+	0x00200000: "strength_training",
+	0x00400000: "hiking",
+	0x00800000: "climbing",
+}
+
+// lookupActivityKind returns the name of the sport of the activity kind, or
+// its code if it isn't known.
+func lookupActivityKind(kind int64) string {
+	if name, ok := activityKinds[kind]; ok {
+		return name
+	}
+	return strconv.FormatInt(kind, 10)
+}
@@ -0,0 +1,80 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// activitySummaryMeasurement is the measurement that the activity summaries
+// gathered with GatherActivitySummaries are added to.
+const activitySummaryMeasurement = "gadgetbridge_activity_summary"
+
+// activitySummaryTable is the table that activity summaries are gathered
+// from. It doubles as their key in pluginState.LastTableTimes, which tracks
+// the START_TIME of the newest activity gathered.
+const activitySummaryTable = "BASE_ACTIVITY_SUMMARY"
+
+// gatherActivitySummaries gathers the activities that were recorded since the
+// last gather, each timestamped with its start and tagged with its sport and
+// the device that recorded it.
+func (p *Plugin) gatherActivitySummaries(acc telegraf.Accumulator, db *sql.DB, dbPath string, opts gatherOptions) error {
+	var devices map[string]deviceInfo
+	return p.gatherHistory(acc, db, dbPath, opts, historyTable{
+		name:        activitySummaryTable,
+		what:        "activity summaries",
+		measurement: activitySummaryMeasurement,
+		timeColumn:  "START_TIME",
+		columns:     []any{"_id", "START_TIME", "END_TIME", "ACTIVITY_KIND", "NAME", "DEVICE_ID", "USER_ID"},
+		prepare: func(db *sql.DB) (err error) {
+			devices, err = readDevices(db)
+			if err != nil {
+				return fmt.Errorf("error reading devices: %w", err)
+			}
+			return nil
+		},
+		scan: func(r *sql.Rows) (historyRow, error) {
+			return scanActivitySummary(r, devices)
+		},
+	})
+}
+
+func scanActivitySummary(r *sql.Rows, devices map[string]deviceInfo) (historyRow, error) {
+	var activityID, startTime, endTime, kind int64
+	var name sql.NullString
+	var deviceID, userID string
+	if err := r.Scan(&activityID, &startTime, &endTime, &kind, &name, &deviceID, &userID); err != nil {
+		return historyRow{}, err
+	}
+
+	tags := map[string]string{
+		"device_id":     deviceID,
+		"user_id":       userID,
+		"activity_id":   strconv.FormatInt(activityID, 10),
+		"activity_kind": strconv.FormatInt(kind, 10),
+		"sport":         lookupActivityKind(kind),
+	}
+	if name.Valid && name.String != "" {
+		tags["activity_name"] = name.String
+	}
+	if device, ok := devices[deviceID]; ok {
+		tags["device_name"] = device.name
+		tags["device_type"] = device.typeName
+		tags["device_identifier"] = device.identifier
+		tags["device_manufacturer"] = device.model.manufacturer
+		tags["device_model"] = device.model.model
+	}
+
+	start := time.UnixMilli(startTime)
+	return historyRow{
+		time: startTime,
+		tags: tags,
+		fields: map[string]interface{}{
+			"duration_seconds": time.UnixMilli(endTime).Sub(start).Seconds(),
+			"end_time":         time.UnixMilli(endTime).Unix(),
+		},
+	}, nil
+}
@@ -0,0 +1,117 @@
+package gadgetbridge
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	telegraftest "github.com/influxdata/telegraf/testutil"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb/testdb"
+)
+
+// BenchmarkPlugin_Gather gathers every row of generated databases of a
+// million and ten million activity samples, reporting the rows gathered per
+// second and the peak heap in use besides the allocations. The databases are
+// generated with the same seed on every run, so that runs can be compared
+// with benchstat. Generating them takes a while, which isn't timed.
+func BenchmarkPlugin_Gather(b *testing.B) {
+	// The sub-benchmarks are run more than once to find b.N, so the
+	// databases are generated once in a directory that outlives them.
+	dir := b.TempDir()
+	generated := make(map[int]testdb.Stats)
+
+	for _, rows := range []int{1_000_000, 10_000_000} {
+		b.Run(fmt.Sprintf("%dM", rows/1_000_000), func(b *testing.B) {
+			dbPath := filepath.Join(dir, fmt.Sprintf("%d.db", rows))
+			stats, ok := generated[rows]
+			if !ok {
+				stats = generateBenchDB(b, dbPath, rows)
+				generated[rows] = stats
+			}
+			total := stats.Samples + stats.BatteryLevels
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			peak := watchHeap()
+			for i := 0; i < b.N; i++ {
+				// A new plugin has no state, so it gathers every row.
+				p := &Plugin{DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{Quiet: true}}
+				assert.NoError(b, p.Init())
+				assert.NoError(b, p.Gather(new(telegraftest.NopAccumulator)))
+			}
+
+			b.StopTimer()
+			b.ReportMetric(float64(total*b.N)/b.Elapsed().Seconds(), "rows/s")
+			b.ReportMetric(float64(peak()), "peak-heap-B")
+		})
+	}
+}
+
+// generateBenchDB generates a database at dbPath of 10 devices with rows
+// activity samples between them, one a minute.
+func generateBenchDB(b *testing.B, dbPath string, rows int) testdb.Stats {
+	b.Helper()
+
+	const devices = 10
+	to := time.Date(2024, 9, 8, 0, 0, 0, 0, time.UTC)
+
+	stats, err := testdb.Generate(dbPath, testdb.Options{
+		Devices: devices,
+		From:    to.Add(-time.Duration(rows/devices) * time.Minute),
+		To:      to,
+		Seed:    1,
+		Anomalies: testdb.Anomalies{
+			Sentinels:   0.01,
+			Implausible: 0.001,
+			Mistyped:    0.001,
+			Gaps:        0.05,
+		},
+	})
+	assert.NoError(b, err)
+
+	return stats
+}
+
+// watchHeap samples the heap in use until the returned function is called,
+// which returns the most that was in use.
+func watchHeap() (peak func() uint64) {
+	var peakHeap uint64
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	sample := func() {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		peakHeap = max(peakHeap, stats.HeapInuse)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				sample()
+				return
+			case <-ticker.C:
+				sample()
+			}
+		}
+	}()
+
+	return func() uint64 {
+		close(done)
+		wg.Wait()
+		return peakHeap
+	}
+}
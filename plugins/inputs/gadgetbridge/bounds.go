@@ -0,0 +1,29 @@
+package gadgetbridge
+
+import (
+	"time"
+)
+
+// parseMinTimestamp parses MinTimestamp, which is either a date in UTC or an
+// RFC 3339 time. An empty one is the zero time, which bounds nothing.
+func parseMinTimestamp(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// timestampInBounds returns whether the timestamp at of a row is within the
+// bounds of MinTimestamp and MaxFutureSkew past now.
+func (p *Plugin) timestampInBounds(at, now time.Time) bool {
+	if !p.minTimestamp.IsZero() && at.Before(p.minTimestamp) {
+		return false
+	}
+	if p.MaxFutureSkew > 0 && at.After(now.Add(time.Duration(p.MaxFutureSkew))) {
+		return false
+	}
+	return true
+}
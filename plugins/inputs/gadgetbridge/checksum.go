@@ -0,0 +1,110 @@
+package gadgetbridge
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumSidecarExt is the extension of the file next to a database that
+// holds its SHA-256 checksum.
+const checksumSidecarExt = ".sha256"
+
+// verifyChecksum checks the database at path against its expected SHA-256
+// checksum, which is taken from ChecksumManifest if set or from the
+// database's sidecar file otherwise. An error is returned if the checksum
+// can't be found or doesn't match.
+func (p *Plugin) verifyChecksum(path string) error {
+	var want string
+	var err error
+	if p.ChecksumManifest != "" {
+		want, err = manifestChecksum(p.ChecksumManifest, path)
+	} else {
+		want, err = sidecarChecksum(path + checksumSidecarExt)
+	}
+	if err != nil {
+		return err
+	}
+
+	got, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", want, got)
+	}
+
+	return nil
+}
+
+// sidecarChecksum reads the checksum from a sidecar file. The file may either
+// contain only the checksum or a line of sha256sum output.
+func sidecarChecksum(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum: %w", err)
+	}
+
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file %q is empty", path)
+	}
+
+	return fields[0], nil
+}
+
+// manifestChecksum looks up the checksum of dbPath in a manifest in sha256sum
+// format. Entries are matched by path relative to the manifest, or by base
+// name.
+func manifestChecksum(manifestPath, dbPath string) (string, error) {
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open checksum manifest: %w", err)
+	}
+	defer f.Close()
+
+	manifestDir := filepath.Dir(manifestPath)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+
+		// sha256sum marks binary mode entries with an asterisk.
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+
+		if filepath.Join(manifestDir, name) == filepath.Clean(dbPath) || name == filepath.Base(dbPath) {
+			return sum, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read checksum manifest: %w", err)
+	}
+
+	return "", errors.New("no checksum found in manifest")
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to checksum database: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,150 @@
+package gadgetbridge
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// DuplicateRowsBehavior is how the rows of a table that share a timestamp and
+// tags, such as those some devices write more than once, are handled.
+type DuplicateRowsBehavior string
+
+const (
+	// DuplicateRowsKeep gathers every row as it is, leaving the duplicates
+	// to downstream.
+	DuplicateRowsKeep DuplicateRowsBehavior = "keep"
+	// DuplicateRowsLast gathers only the last of the duplicate rows.
+	DuplicateRowsLast DuplicateRowsBehavior = "last"
+	// DuplicateRowsMax gathers the largest value of every field across the
+	// duplicate rows.
+	DuplicateRowsMax DuplicateRowsBehavior = "max"
+)
+
+func (b DuplicateRowsBehavior) validate() error {
+	switch b {
+	case "", DuplicateRowsKeep, DuplicateRowsLast, DuplicateRowsMax:
+		return nil
+	default:
+		return fmt.Errorf("unknown behavior %q", b)
+	}
+}
+
+// dedupRow is a row held back by a deduplicatingAccumulator.
+type dedupRow struct {
+	measurement string
+	fields      map[string]interface{}
+	tags        map[string]string
+}
+
+// deduplicatingAccumulator merges the metrics of a table added through
+// AddFields, which must be in the order of their timestamps, that share a
+// timestamp and tags. The metrics of a timestamp are held back until one of
+// a later timestamp is added or Flush is called.
+type deduplicatingAccumulator struct {
+	telegraf.Accumulator
+	behavior DuplicateRowsBehavior
+
+	at   time.Time
+	rows []dedupRow
+	// index maps the tags of the held rows to their indices in rows.
+	index map[string]int
+	// merged counts the rows merged into others.
+	merged int
+}
+
+func newDeduplicatingAccumulator(acc telegraf.Accumulator, behavior DuplicateRowsBehavior) *deduplicatingAccumulator {
+	return &deduplicatingAccumulator{
+		Accumulator: acc,
+		behavior:    behavior,
+		index:       make(map[string]int),
+	}
+}
+
+func (a *deduplicatingAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if len(t) == 0 {
+		a.Accumulator.AddFields(measurement, fields, tags)
+		return
+	}
+
+	if !t[0].Equal(a.at) {
+		a.Flush()
+		a.at = t[0]
+	}
+
+	key := measurement + "," + tagsKey(tags)
+	i, ok := a.index[key]
+	if !ok {
+		// The fields and tags are reused for every row of a table, so they're
+		// copied while they're held back.
+		a.index[key] = len(a.rows)
+		a.rows = append(a.rows, dedupRow{measurement, maps.Clone(fields), maps.Clone(tags)})
+		return
+	}
+
+	a.merged++
+	row := &a.rows[i]
+	switch a.behavior {
+	case DuplicateRowsLast:
+		row.fields = maps.Clone(fields)
+	case DuplicateRowsMax:
+		for name, v := range fields {
+			if old, ok := row.fields[name]; !ok || isGreater(v, old) {
+				row.fields[name] = v
+			}
+		}
+	}
+}
+
+// Flush adds the metrics that are held back.
+func (a *deduplicatingAccumulator) Flush() {
+	for _, row := range a.rows {
+		a.Accumulator.AddFields(row.measurement, row.fields, row.tags, a.at)
+	}
+	a.rows = a.rows[:0]
+	clear(a.index)
+}
+
+// tagsKey returns a key that's the same for equal tags.
+func tagsKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%q=%q,", k, tags[k])
+	}
+	return b.String()
+}
+
+// isGreater returns whether the field value v is greater than old. Values
+// that aren't numbers are never greater, except than a NULL.
+func isGreater(v, old interface{}) bool {
+	if old == nil {
+		return v != nil
+	}
+	vf, ok := toFloat(v)
+	if !ok {
+		return false
+	}
+	oldf, ok := toFloat(old)
+	return ok && vf > oldf
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,69 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/influxdata/telegraf"
+)
+
+// deviceAttributesMeasurement is the measurement that the device attributes
+// gathered with GatherDeviceAttributes are added to.
+const deviceAttributesMeasurement = "gadgetbridge_device_attributes"
+
+// deviceAttributesTable is the table that device attributes are gathered
+// from. It doubles as their key in pluginState.LastTableTimes, which tracks
+// the VALID_FROM_UTC of the newest attributes gathered.
+const deviceAttributesTable = "DEVICE_ATTRIBUTES"
+
+// gatherDeviceAttributes gathers the firmware versions and volatile
+// identifiers of every device that were recorded since the last gather, each
+// timestamped with the time it became valid, such as when the firmware was
+// upgraded. Attributes without such a time can't be placed in history, so
+// they're left out.
+//
+// The firmware versions are the version1 and version2 fields, which don't
+// clash with the firmware_version and firmware_version2 tags of FirmwareTags.
+func (p *Plugin) gatherDeviceAttributes(acc telegraf.Accumulator, db *sql.DB, dbPath string, opts gatherOptions) error {
+	return p.gatherHistory(acc, db, dbPath, opts, historyTable{
+		name:        deviceAttributesTable,
+		what:        "device attributes",
+		measurement: deviceAttributesMeasurement,
+		timeColumn:  "VALID_FROM_UTC",
+		columns:     []any{"DEVICE_ID", "VALID_FROM_UTC", "VALID_TO_UTC", "FIRMWARE_VERSION1", "FIRMWARE_VERSION2", "VOLATILE_IDENTIFIER"},
+		where:       goqu.C("VALID_FROM_UTC").IsNotNull(),
+		scan:        scanDeviceAttributes,
+	})
+}
+
+func scanDeviceAttributes(r *sql.Rows) (historyRow, error) {
+	var deviceID, version1 string
+	var validFrom int64
+	var validTo sql.NullInt64
+	var version2, volatileIdentifier sql.NullString
+	if err := r.Scan(&deviceID, &validFrom, &validTo, &version1, &version2, &volatileIdentifier); err != nil {
+		return historyRow{}, err
+	}
+
+	fields := map[string]interface{}{
+		"version1": version1,
+	}
+	if version2.Valid {
+		fields["version2"] = version2.String
+	}
+	if volatileIdentifier.Valid {
+		fields["volatile_identifier"] = volatileIdentifier.String
+	}
+	// The attributes are only gathered once, so an end that's recorded
+	// after they were gathered isn't seen.
+	if validTo.Valid {
+		fields["valid_to"] = time.UnixMilli(validTo.Int64).Unix()
+	}
+
+	return historyRow{
+		time:   validFrom,
+		tags:   map[string]string{"device_id": deviceID},
+		fields: fields,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package gadgetbridge
+
+// deviceModel is the hardware of a device type.
+type deviceModel struct {
+	manufacturer string
+	model        string
+}
+
+// deviceModels maps the names of Gadgetbridge's DeviceType enum, as stored in
+// DEVICE.TYPE_NAME, to the hardware they stand for. The names are used rather
+// than the numbers in DEVICE.TYPE, which have changed between Gadgetbridge
+// versions. Device types that aren't listed fall back to what's in the DEVICE
+// table.
+var deviceModels = map[string]deviceModel{
+	"MIBAND":                {"Xiaomi", "Mi Band"},
+	"MIBAND2":               {"Xiaomi", "Mi Band 2"},
+	"MIBAND2_HRX":           {"Xiaomi", "Mi Band HRX"},
+	"MIBAND3":               {"Xiaomi", "Mi Band 3"},
+	"MIBAND4":               {"Xiaomi", "Mi Band 4"},
+	"MIBAND5":               {"Xiaomi", "Mi Band 5"},
+	"MIBAND6":               {"Xiaomi", "Mi Band 6"},
+	"MIBAND7":               {"Xiaomi", "Mi Band 7"},
+	"MIBAND7PRO":            {"Xiaomi", "Mi Band 7 Pro"},
+	"MIBAND8":               {"Xiaomi", "Mi Band 8"},
+	"AMAZFITBIP":            {"Amazfit", "Bip"},
+	"AMAZFITBIP_LITE":       {"Amazfit", "Bip Lite"},
+	"AMAZFITBIPS":           {"Amazfit", "Bip S"},
+	"AMAZFITBIP3PRO":        {"Amazfit", "Bip 3 Pro"},
+	"AMAZFITBIP5":           {"Amazfit", "Bip 5"},
+	"AMAZFITBAND5":          {"Amazfit", "Band 5"},
+	"AMAZFITBAND7":          {"Amazfit", "Band 7"},
+	"AMAZFITGTR":            {"Amazfit", "GTR"},
+	"AMAZFITGTR2":           {"Amazfit", "GTR 2"},
+	"AMAZFITGTR3":           {"Amazfit", "GTR 3"},
+	"AMAZFITGTR3PRO":        {"Amazfit", "GTR 3 Pro"},
+	"AMAZFITGTR4":           {"Amazfit", "GTR 4"},
+	"AMAZFITGTS":            {"Amazfit", "GTS"},
+	"AMAZFITGTS2":           {"Amazfit", "GTS 2"},
+	"AMAZFITGTS3":           {"Amazfit", "GTS 3"},
+	"AMAZFITGTS4":           {"Amazfit", "GTS 4"},
+	"AMAZFITGTS4MINI":       {"Amazfit", "GTS 4 Mini"},
+	"AMAZFITCHEETAHPRO":     {"Amazfit", "Cheetah Pro"},
+	"AMAZFITBALANCE":        {"Amazfit", "Balance"},
+	"AMAZFITTREXPRO":        {"Amazfit", "T-Rex Pro"},
+	"AMAZFITTREX2":          {"Amazfit", "T-Rex 2"},
+	"AMAZFITFALCON":         {"Amazfit", "Falcon"},
+	"FOSSILQHYBRID":         {"Fossil", "Q Hybrid"},
+	"PINETIME_JF":           {"Pine64", "PineTime"},
+	"BANGLEJS":              {"Espruino", "Bangle.js"},
+	"PEBBLE":                {"Pebble", "Pebble"},
+	"CASIOGB6900":           {"Casio", "GB-6900"},
+	"CASIOGBX100":           {"Casio", "GBX-100"},
+	"HPLUS":                 {"HPlus", "HPlus"},
+	"ZETIME":                {"MyKronoz", "ZeTime"},
+	"WATCHXPLUS":            {"Lenovo", "Watch X Plus"},
+	"GARMIN_FORERUNNER_245": {"Garmin", "Forerunner 245"},
+	"GARMIN_FORERUNNER_255": {"Garmin", "Forerunner 255"},
+	"GARMIN_FORERUNNER_265": {"Garmin", "Forerunner 265"},
+	"GARMIN_FENIX_7":        {"Garmin", "Fenix 7"},
+	"GARMIN_INSTINCT_2":     {"Garmin", "Instinct 2"},
+	"GARMIN_VENU_2":         {"Garmin", "Venu 2"},
+	"GARMIN_VIVOACTIVE_4":   {"Garmin", "Vivoactive 4"},
+	"GARMIN_VIVOSMART_5":    {"Garmin", "Vivosmart 5"},
+}
+
+// lookupDeviceModel returns the hardware of the device type with the given
+// name, falling back to the manufacturer recorded in the DEVICE table and the
+// type name itself.
+func lookupDeviceModel(typeName, manufacturer string) deviceModel {
+	if model, ok := deviceModels[typeName]; ok {
+		return model
+	}
+	return deviceModel{manufacturer, typeName}
+}
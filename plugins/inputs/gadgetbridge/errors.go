@@ -0,0 +1,49 @@
+package gadgetbridge
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// errorsMeasurement is the measurement that errors confined to a single table
+// are counted in. Such errors don't fail the gather, so that a single broken
+// table doesn't make the whole input look failed.
+const errorsMeasurement = "gadgetbridge_errors"
+
+// errorClass classifies the errors counted in errorsMeasurement.
+type errorClass string
+
+const (
+	// errorMissingTable is counted when an extra table is missing from a
+	// database.
+	errorMissingTable errorClass = "missing_table"
+	// errorDroppedRows counts the rows of a table that couldn't be read.
+	errorDroppedRows errorClass = "dropped_rows"
+	// errorMistypedValue counts the field values of a table that weren't
+	// numbers and couldn't be parsed as one.
+	errorMistypedValue errorClass = "mistyped_value"
+	// errorImplausibleValue counts the field values of a table that were out
+	// of their plausible range.
+	errorImplausibleValue errorClass = "implausible_value"
+	// errorBadTimestamp counts the rows of a table whose timestamps were out
+	// of the bounds of MinTimestamp and MaxFutureSkew.
+	errorBadTimestamp errorClass = "bad_timestamp"
+	// errorQuery is counted when a table couldn't be queried, such as when
+	// one of its configured columns is missing.
+	errorQuery errorClass = "query"
+	// errorActivityFile counts the activity files that couldn't be found or
+	// decoded.
+	errorActivityFile errorClass = "activity_file"
+)
+
+// addTableError adds a metric counting count errors of the given class at the
+// table of the database at dbPath. Activity files are counted under their
+// state keys, such as "BASE_ACTIVITY_SUMMARY/fit".
+func addTableError(acc telegraf.Accumulator, dbPath, table string, class errorClass, count int) {
+	acc.AddFields(errorsMeasurement,
+		map[string]interface{}{"count": count},
+		map[string]string{
+			"database_path": dbPath,
+			"table":         table,
+			"class":         string(class),
+		})
+}
@@ -0,0 +1,145 @@
+package gadgetbridge
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// deviceFamily describes the tables that a family of devices records into and
+// the options that suit what they record.
+type deviceFamily struct {
+	// description names the devices of the family, completing "Gather
+	// metrics from Gadgetbridge's auto-export databases of" within 80
+	// columns.
+	description string
+	options     []familyOption
+	tables      []TableDescription
+}
+
+// familyOption is an option set in the sample config of a device family.
+type familyOption struct {
+	comment string
+	key     string
+	value   string
+}
+
+// deviceFamilies are the device families that FamilySampleConfig renders
+// configs for, by name.
+var deviceFamilies = map[string]deviceFamily{
+	"huami": {
+		// Such as Amazfit watches, Zepp watches and Mi Bands.
+		description: "Huami devices",
+		tables: []TableDescription{
+			{
+				Name: "HUAMI_EXTENDED_ACTIVITY_SAMPLE",
+				Columns: TableColumns{
+					Timestamp: "TIMESTAMP",
+					Tags:      []string{"DEVICE_ID", "USER_ID"},
+					Fields:    []string{"RAW_INTENSITY", "STEPS", "RAW_KIND", "HEART_RATE", "SLEEP", "DEEP_SLEEP", "REM_SLEEP"},
+					Sentinels: map[string][]int64{"HEART_RATE": {0, 255}},
+				},
+			},
+			{
+				// Mi Bands before the 4 record into the table of the
+				// original Mi Band.
+				Name: "MI_BAND_ACTIVITY_SAMPLE",
+				Columns: TableColumns{
+					Timestamp: "TIMESTAMP",
+					Tags:      []string{"DEVICE_ID", "USER_ID"},
+					Fields:    []string{"RAW_INTENSITY", "STEPS", "RAW_KIND", "HEART_RATE"},
+					Sentinels: map[string][]int64{"HEART_RATE": {0, 255}},
+				},
+			},
+			abstractTimeSample("HUAMI_STRESS_SAMPLE", "STRESS"),
+			abstractTimeSample("HUAMI_SPO2_SAMPLE", "SPO2"),
+			{
+				Name: "HUAMI_PAI_SAMPLE",
+				Columns: TableColumns{
+					Timestamp: "TIMESTAMP",
+					Tags:      []string{"DEVICE_ID", "USER_ID"},
+					Fields:    []string{"PAI_LOW", "PAI_MODERATE", "PAI_HIGH", "TIME_LOW", "TIME_MODERATE", "TIME_HIGH", "PAI_TODAY", "PAI_TOTAL"},
+				},
+			},
+		},
+	},
+	"garmin": {
+		description: "Garmin devices",
+		options: []familyOption{
+			{"Gather the per-record metrics of the workouts from their FIT files.", "gather_fit_files", "true"},
+		},
+		tables: []TableDescription{
+			abstractTimeSample("GARMIN_SPO2_SAMPLE", "SPO2"),
+			abstractTimeSample("GARMIN_SLEEP_STAGE_SAMPLE", "STAGE"),
+		},
+	},
+	"banglejs": {
+		description: "Bangle.js watches",
+		options: []familyOption{
+			{"Gather the tracks recorded by the watch's GPS.", "gather_gpx_tracks", "true"},
+		},
+		tables: []TableDescription{
+			{
+				Name: "BANGLE_JSACTIVITY_SAMPLE",
+				Columns: TableColumns{
+					Timestamp: "TIMESTAMP",
+					Tags:      []string{"DEVICE_ID", "USER_ID"},
+					Fields:    []string{"RAW_INTENSITY", "STEPS", "RAW_KIND", "HEART_RATE"},
+					Sentinels: map[string][]int64{"RAW_INTENSITY": {-1}, "HEART_RATE": {0}},
+				},
+			},
+		},
+	},
+}
+
+// abstractTimeSample describes a table of Gadgetbridge's AbstractTimeSample,
+// which is timestamped in milliseconds, with the given fields.
+func abstractTimeSample(name string, fields ...string) TableDescription {
+	return TableDescription{
+		Name:         name,
+		Milliseconds: true,
+		Columns: TableColumns{
+			Timestamp: "TIMESTAMP",
+			Tags:      []string{"DEVICE_ID", "USER_ID"},
+			Fields:    fields,
+		},
+	}
+}
+
+// DeviceFamilies returns the names of the device families that
+// FamilySampleConfig renders configs for, in order.
+func DeviceFamilies() []string {
+	names := make([]string, 0, len(deviceFamilies))
+	for name := range deviceFamilies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// FamilySampleConfig renders a sample config for the named device family, as
+// returned by DeviceFamilies, which gathers the tables its devices record into
+// with the options that suit them. Unlike SampleConfig, it leaves out every
+// other option.
+func FamilySampleConfig(name string) (string, error) {
+	family, ok := deviceFamilies[name]
+	if !ok {
+		return "", fmt.Errorf("unknown device family %q, expected one of: %s",
+			name, strings.Join(DeviceFamilies(), ", "))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Gather metrics from Gadgetbridge's auto-export databases of %s\n", family.description)
+	fmt.Fprintf(&b, "[[inputs.gadgetbridge]]\n")
+	fmt.Fprintf(&b, "  database_paths = [\"/path/to/gadgetbridge-export.db\"]\n")
+	for _, option := range family.options {
+		fmt.Fprintf(&b, "\n  ## %s\n", option.comment)
+		fmt.Fprintf(&b, "  %s = %s\n", option.key, option.value)
+	}
+	fmt.Fprintf(&b, "\n")
+
+	if err := WriteExtraTables(&b, family.tables); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,292 @@
+package gadgetbridge
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const fitRecordMeasurement = "gadgetbridge_fit_record"
+
+// fitStateKey is the key in the state of the activities whose FIT files are
+// gathered, as kept by gatherActivityFiles.
+const fitStateKey = "BASE_ACTIVITY_SUMMARY/fit"
+
+func (p *Plugin) gatherFITFiles(acc telegraf.Accumulator, db *sql.DB, dbPath string, opts gatherOptions) error {
+	return p.gatherActivityFiles(acc, db, dbPath, "RAW_DETAILS_PATH", "%.fit", fitStateKey, gatherFITFile, opts)
+}
+
+func gatherFITFile(acc telegraf.Accumulator, path string, tags map[string]string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	records, err := decodeFITRecords(b)
+	if err != nil {
+		return fmt.Errorf("failed to decode FIT file %q: %w", path, err)
+	}
+
+	for _, record := range records {
+		acc.AddFields(fitRecordMeasurement, record.Fields, tags, record.Time)
+	}
+
+	return nil
+}
+
+// fitEpoch is the time that FIT timestamps are relative to.
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+const (
+	fitRecordMessage     = 20
+	fitTimestampFieldNum = 253
+	fitCompressedHeader  = 0x80
+	fitDefinitionHeader  = 0x40
+	fitDeveloperDataFlag = 0x20
+	fitLocalMessageMask  = 0x0F
+	fitTimeOffsetMask    = 0x1F
+	fitMinimumHeaderSize = 12
+)
+
+// fitField describes how a field of a record message is decoded.
+type fitField struct {
+	name   string
+	size   int
+	signed bool
+	// scale and offset convert the raw value to its unit. A zero scale keeps
+	// the raw integer.
+	scale  float64
+	offset float64
+}
+
+// semicircles converts FIT's semicircle positions to degrees.
+const semicircles = (1 << 31) / 180.0
+
+var fitRecordFields = map[byte]fitField{
+	0:  {name: "latitude", size: 4, signed: true, scale: semicircles},
+	1:  {name: "longitude", size: 4, signed: true, scale: semicircles},
+	2:  {name: "altitude", size: 2, scale: 5, offset: 500},
+	3:  {name: "heart_rate", size: 1},
+	4:  {name: "cadence", size: 1},
+	5:  {name: "distance", size: 4, scale: 100},
+	6:  {name: "speed", size: 2, scale: 1000},
+	7:  {name: "power", size: 2},
+	13: {name: "temperature", size: 1, signed: true},
+	73: {name: "speed", size: 4, scale: 1000},
+	78: {name: "altitude", size: 4, scale: 5, offset: 500},
+}
+
+// fitRecord is a record message decoded from a FIT file.
+type fitRecord struct {
+	Time   time.Time
+	Fields map[string]interface{}
+}
+
+type fitDefinition struct {
+	order     binary.ByteOrder
+	global    uint16
+	fields    []fitFieldDefinition
+	devFields int
+}
+
+type fitFieldDefinition struct {
+	num  byte
+	size int
+}
+
+// decodeFITRecords decodes the record messages of a FIT activity file. Only
+// the fields in fitRecordFields are decoded; other messages are skipped.
+func decodeFITRecords(b []byte) ([]fitRecord, error) {
+	if len(b) < fitMinimumHeaderSize {
+		return nil, errors.New("file too short")
+	}
+
+	headerSize := int(b[0])
+	if headerSize < fitMinimumHeaderSize || headerSize > len(b) {
+		return nil, fmt.Errorf("invalid header size %d", headerSize)
+	}
+	if !bytes.Equal(b[8:12], []byte(".FIT")) {
+		return nil, errors.New("missing .FIT signature")
+	}
+
+	dataSize := int(binary.LittleEndian.Uint32(b[4:8]))
+	if headerSize+dataSize > len(b) {
+		return nil, errors.New("file is truncated")
+	}
+	data := b[headerSize : headerSize+dataSize]
+
+	var records []fitRecord
+	var definitions [16]*fitDefinition
+	var lastTimestamp uint32
+
+	for len(data) > 0 {
+		header := data[0]
+		data = data[1:]
+
+		var timestamp uint32
+		var hasTimestamp bool
+		var local byte
+
+		switch {
+		case header&fitCompressedHeader != 0:
+			local = (header >> 5) & 0x03
+			offset := uint32(header & fitTimeOffsetMask)
+			timestamp = lastTimestamp&^fitTimeOffsetMask + offset
+			if offset < lastTimestamp&fitTimeOffsetMask {
+				timestamp += fitTimeOffsetMask + 1
+			}
+			hasTimestamp = true
+
+		case header&fitDefinitionHeader != 0:
+			def, n, err := decodeFITDefinition(data, header&fitDeveloperDataFlag != 0)
+			if err != nil {
+				return nil, err
+			}
+			definitions[header&fitLocalMessageMask] = def
+			data = data[n:]
+			continue
+
+		default:
+			local = header & fitLocalMessageMask
+		}
+
+		def := definitions[local]
+		if def == nil {
+			return nil, fmt.Errorf("data message for undefined local message %d", local)
+		}
+
+		fields := make(map[string]interface{})
+		for _, f := range def.fields {
+			if len(data) < f.size {
+				return nil, errors.New("data message is truncated")
+			}
+			raw := data[:f.size]
+			data = data[f.size:]
+
+			if f.num == fitTimestampFieldNum && f.size == 4 {
+				timestamp = def.order.Uint32(raw)
+				hasTimestamp = true
+				continue
+			}
+
+			if def.global != fitRecordMessage {
+				continue
+			}
+
+			field, ok := fitRecordFields[f.num]
+			if !ok || field.size != f.size {
+				continue
+			}
+
+			if v, ok := field.decode(def.order, raw); ok {
+				fields[field.name] = v
+			}
+		}
+
+		if len(data) < def.devFields {
+			return nil, errors.New("developer data is truncated")
+		}
+		data = data[def.devFields:]
+
+		if hasTimestamp {
+			lastTimestamp = timestamp
+		}
+
+		if def.global == fitRecordMessage && hasTimestamp && len(fields) > 0 {
+			records = append(records, fitRecord{
+				Time:   fitEpoch.Add(time.Duration(timestamp) * time.Second),
+				Fields: fields,
+			})
+		}
+	}
+
+	return records, nil
+}
+
+func decodeFITDefinition(data []byte, hasDevFields bool) (*fitDefinition, int, error) {
+	if len(data) < 5 {
+		return nil, 0, errors.New("definition message is truncated")
+	}
+
+	def := &fitDefinition{order: binary.LittleEndian}
+	if data[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(data[2:4])
+
+	nfields := int(data[4])
+	n := 5
+	if len(data) < n+nfields*3 {
+		return nil, 0, errors.New("definition message is truncated")
+	}
+
+	def.fields = make([]fitFieldDefinition, nfields)
+	for i := range def.fields {
+		def.fields[i] = fitFieldDefinition{
+			num:  data[n],
+			size: int(data[n+1]),
+		}
+		n += 3
+	}
+
+	if hasDevFields {
+		if len(data) < n+1 {
+			return nil, 0, errors.New("definition message is truncated")
+		}
+		ndev := int(data[n])
+		n++
+		if len(data) < n+ndev*3 {
+			return nil, 0, errors.New("definition message is truncated")
+		}
+		for i := 0; i < ndev; i++ {
+			def.devFields += int(data[n+1])
+			n += 3
+		}
+	}
+
+	return def, n, nil
+}
+
+// decode decodes the raw bytes of the field. It returns false if the value
+// is FIT's invalid marker for the field's type.
+func (f fitField) decode(order binary.ByteOrder, raw []byte) (interface{}, bool) {
+	var u uint64
+	switch f.size {
+	case 1:
+		u = uint64(raw[0])
+	case 2:
+		u = uint64(order.Uint16(raw))
+	case 4:
+		u = uint64(order.Uint32(raw))
+	default:
+		return nil, false
+	}
+
+	bits := uint(f.size * 8)
+
+	var v int64
+	if f.signed {
+		if u == 1<<(bits-1)-1 {
+			return nil, false
+		}
+		// Sign-extend the value to 64 bits.
+		v = int64(u<<(64-bits)) >> (64 - bits)
+	} else {
+		if u == 1<<bits-1 {
+			return nil, false
+		}
+		v = int64(u)
+	}
+
+	if f.scale == 0 {
+		return v, true
+	}
+
+	return float64(v)/f.scale - f.offset, true
+}
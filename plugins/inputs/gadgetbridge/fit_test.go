@@ -0,0 +1,61 @@
+package gadgetbridge
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestDecodeFITRecords(t *testing.T) {
+	var data []byte
+	// Definition of local message 0 as a record with a timestamp, position and
+	// heart rate.
+	data = append(data,
+		0x40, 0, 0, fitRecordMessage, 0, 4,
+		fitTimestampFieldNum, 4, 0x86,
+		0, 4, 0x85,
+		1, 4, 0x85,
+		3, 1, 0x02,
+	)
+	data = append(data, 0x00)
+	data = binary.LittleEndian.AppendUint32(data, 1_000_000_000)
+	data = binary.LittleEndian.AppendUint32(data, uint32(semicirclesOf(45)))
+	data = binary.LittleEndian.AppendUint32(data, uint32(semicirclesOf(-90)))
+	data = append(data, 150)
+	// Definition of local message 1 as a record with only a heart rate, used
+	// with compressed timestamp headers.
+	data = append(data,
+		0x41, 0, 0, fitRecordMessage, 0, 1,
+		3, 1, 0x02,
+	)
+	data = append(data, fitCompressedHeader|1<<5|5, 0xFF) // invalid heart rate
+	data = append(data, fitCompressedHeader|1<<5|6, 152)
+
+	file := []byte{12, 0x10, 0, 0, 0, 0, 0, 0, '.', 'F', 'I', 'T'}
+	binary.LittleEndian.PutUint32(file[4:8], uint32(len(data)))
+	file = append(file, data...)
+	file = append(file, 0, 0) // CRC
+
+	records, err := decodeFITRecords(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []fitRecord{
+		{
+			Time: fitEpoch.Add(1_000_000_000 * time.Second),
+			Fields: map[string]interface{}{
+				"latitude":   45.0,
+				"longitude":  -90.0,
+				"heart_rate": int64(150),
+			},
+		},
+		{
+			Time: fitEpoch.Add(1_000_000_006 * time.Second),
+			Fields: map[string]interface{}{
+				"heart_rate": int64(152),
+			},
+		},
+	}, records)
+}
+
+func semicirclesOf(degrees float64) int32 { return int32(degrees * semicircles) }
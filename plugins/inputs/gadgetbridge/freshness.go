@@ -0,0 +1,121 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/influxdata/telegraf"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// freshnessMeasurement is the measurement that the freshness gathered with
+// GatherFreshness is added to.
+const freshnessMeasurement = "gadgetbridge_freshness"
+
+// gatherFreshness adds a metric for every device of the database at dbPath
+// with the time of its newest sample across the given tables and the time the
+// database was last modified. Tables without a DEVICE_ID column or missing
+// from the database are left out.
+func gatherFreshness(acc telegraf.Accumulator, db *sql.DB, dbPath string, tables []TableDescription) error {
+	stat, err := os.Stat(dbPath)
+	if err != nil {
+		return err
+	}
+
+	summaries, err := summarizeDeviceSamples(db, tables)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for deviceID, summary := range summaries {
+		acc.AddFields(freshnessMeasurement, map[string]interface{}{
+			"newest_sample_time": summary.newest,
+			"file_mtime":         stat.ModTime().Unix(),
+		}, map[string]string{
+			"database_path": dbPath,
+			"device_id":     deviceID,
+		}, now)
+	}
+
+	return nil
+}
+
+// sampleSummary summarizes the samples of a device.
+type sampleSummary struct {
+	// newest is the time of the newest sample in Unix seconds.
+	newest int64
+	count  int64
+}
+
+// summarizeDeviceSamples summarizes the samples of every device across the
+// given tables, keyed by device ID. Tables without a DEVICE_ID column or
+// missing from the database are left out.
+func summarizeDeviceSamples(db *sql.DB, tables []TableDescription) (map[string]sampleSummary, error) {
+	summaries := make(map[string]sampleSummary)
+	for _, t := range tables {
+		if !slices.Contains(t.Columns.Tags, "DEVICE_ID") {
+			continue
+		}
+
+		columns, err := gadgetbridgedb.Columns(db, t.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error at table %q: %w", t.Name, err)
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		if err := summarizeSamples(db, t, summaries); err != nil {
+			return nil, fmt.Errorf("error at table %q: %w", t.Name, err)
+		}
+	}
+	return summaries, nil
+}
+
+// summarizeSamples adds the samples of every device in the table t, which must
+// have a DEVICE_ID column, to summaries.
+func summarizeSamples(db *sql.DB, t TableDescription, summaries map[string]sampleSummary) error {
+	qSQL, qArgs, err := sqliteBuilder.
+		From(t.Name).
+		Select(goqu.C("DEVICE_ID"), goqu.MAX(t.Columns.Timestamp), goqu.COUNT(goqu.Star())).
+		GroupBy("DEVICE_ID").
+		ToSQL()
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for r.Next() {
+		var deviceID sql.NullString
+		var newest sql.NullInt64
+		var count int64
+		if err := r.Scan(&deviceID, &newest, &count); err != nil {
+			return fmt.Errorf("error scanning row: %w", err)
+		}
+		if !deviceID.Valid || !newest.Valid {
+			continue
+		}
+
+		summary := summaries[deviceID.String]
+		summary.newest = max(summary.newest, t.TableReader().Time(newest.Int64).Unix())
+		summary.count += count
+		summaries[deviceID.String] = summary
+	}
+
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return nil
+}
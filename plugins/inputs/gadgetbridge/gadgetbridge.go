@@ -0,0 +1,1167 @@
+// Package gadgetbridge implements a Telegraf plugin that ingests data from
+// Gadgetbridge's auto-export file and sends it to Telegraf. It's laid out like
+// Telegraf's own inputs, so that the directory can be copied into
+// plugins/inputs of a Telegraf tree and built in as a native input.
+//
+//go:generate ../../../tools/readme_config_includer/generator
+package gadgetbridge
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	_ "embed"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/fsnotify/fsnotify"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+
+	_ "github.com/doug-martin/goqu/v9/dialect/sqlite3"
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	inputs.Add("gadgetbridge", func() telegraf.Input {
+		return &Plugin{
+			MinTimestamp:            "2010-01-01",
+			MaxFutureSkew:           config.Duration(24 * time.Hour),
+			GatherUserAttributes:    true,
+			GatherActivitySummaries: true,
+			GatherDeviceAttributes:  true,
+		}
+	})
+}
+
+//go:embed sample.conf
+var sampleConfig string
+
+// Plugin implements the Telegraf input plugin. Any credentials it needs must be
+// config.Secret fields, so that they can be taken from Telegraf's secret stores
+// rather than written into the config in plain text.
+type Plugin struct {
+	// InstanceID tells this instance apart from others in the same process,
+	// such as one per family member. It tags the plugin's own statistics and
+	// prefixes its log messages. It defaults to a short hash of DatabasePaths.
+	InstanceID string `toml:"instance_id,omitempty"`
+	// DatabasePaths is a list of paths to the databases to gather. Paths may
+	// be glob patterns, in which case every matching database is gathered in
+	// lexical order.
+	DatabasePaths []string           `toml:"database_paths"`
+	ExtraTables   []TableDescription `toml:"extra_tables,omitempty"`
+	// SettingsPaths is a list of JSON preference files written by
+	// Gadgetbridge's data export. Selected values are gathered into the
+	// gadgetbridge_settings measurement.
+	SettingsPaths []string `toml:"settings_paths,omitempty"`
+	// SettingsKeys is a list of glob patterns matching the preference keys to
+	// gather from SettingsPaths. It defaults to defaultSettingsKeys.
+	SettingsKeys []string `toml:"settings_keys,omitempty"`
+	// GatherFITFiles enables gathering the per-record workout metrics of the
+	// FIT files referenced by recorded activities, such as those of Garmin
+	// devices.
+	GatherFITFiles bool `toml:"gather_fit_files,omitempty"`
+	// GatherGPXTracks enables gathering the track points of the GPX files
+	// referenced by recorded activities.
+	GatherGPXTracks bool `toml:"gather_gpx_tracks,omitempty"`
+	// GatherUserAttributes enables gathering the height, weight and goals of
+	// every user over time from the USER_ATTRIBUTES table into the
+	// gadgetbridge_user_attributes measurement.
+	GatherUserAttributes bool `toml:"gather_user_attributes,omitempty"`
+	// GatherActivitySummaries enables gathering every recorded activity from
+	// the BASE_ACTIVITY_SUMMARY table into the gadgetbridge_activity_summary
+	// measurement, tagged with its sport and the device that recorded it.
+	GatherActivitySummaries bool `toml:"gather_activity_summaries,omitempty"`
+	// GatherDeviceAttributes enables gathering the firmware versions and
+	// volatile identifiers of every device over time from the
+	// DEVICE_ATTRIBUTES table into the gadgetbridge_device_attributes
+	// measurement, such as to graph firmware upgrades.
+	GatherDeviceAttributes bool `toml:"gather_device_attributes,omitempty"`
+	// GatherSessions enables gathering every workout from the
+	// BASE_ACTIVITY_SUMMARY table and every sleep session found in the tables
+	// of SleepSessions into the gadgetbridge_session measurement, each with
+	// its start, end and duration and tagged with its session_type and a
+	// session_id, for tools that want discrete sessions rather than samples.
+	GatherSessions bool `toml:"gather_sessions,omitempty"`
+	// SleepSessions are the sample tables that GatherSessions finds sleep
+	// sessions in.
+	SleepSessions []SleepSessionTable `toml:"sleep_sessions,omitempty"`
+	// GatherTableStats enables gathering how many rows each gather read from
+	// every sample table and how far behind its newest row is, per device,
+	// into the gadgetbridge_table measurement.
+	GatherTableStats bool `toml:"gather_table_stats,omitempty"`
+	// GatherFreshness enables gathering the time of the newest sample of
+	// every device along with the time its database was last modified into
+	// the gadgetbridge_freshness measurement.
+	GatherFreshness bool `toml:"gather_freshness,omitempty"`
+	// GatherDeviceInventory enables gathering a summary of every paired
+	// device, with when its newest sample was recorded, how many samples it
+	// has and how its battery is doing, into the gadgetbridge_device
+	// measurement.
+	GatherDeviceInventory bool `toml:"gather_device_inventory,omitempty"`
+	// GatherUserProfiles enables gathering the age, gender and estimated
+	// maximum heart rate of every user from the USER table into the
+	// gadgetbridge_user measurement, as context for heart rate zones and
+	// calorie estimates.
+	GatherUserProfiles bool `toml:"gather_user_profiles,omitempty"`
+	// GatherHeartbeat enables gathering the size, modification time, schema
+	// version and whether it could be opened of every database on every
+	// gather into the gadgetbridge_heartbeat measurement, even when it has
+	// no new rows.
+	GatherHeartbeat bool `toml:"gather_heartbeat,omitempty"`
+	// DeviceTags enables tagging every metric of a device with its name,
+	// type and identifier, such as its MAC address, from the DEVICE table.
+	DeviceTags bool `toml:"device_tags,omitempty"`
+	// UserTags enables tagging every metric of a user with their name from
+	// the USER table as the user tag, which tells the profiles of a shared
+	// database apart.
+	UserTags bool `toml:"user_tags,omitempty"`
+	// FirmwareTags enables tagging every metric of a device with the
+	// firmware versions it ran when the metric was recorded, from the
+	// DEVICE_ATTRIBUTES table.
+	FirmwareTags bool `toml:"firmware_tags,omitempty"`
+	// DeviceAliases maps device IDs or identifiers, such as MAC addresses,
+	// to the names that the metrics of those devices are tagged with as the
+	// device_alias tag. Identifiers are matched case-insensitively.
+	DeviceAliases map[string]string `toml:"device_aliases,omitempty"`
+	// DevicesExclude is a list of glob patterns matching the IDs, names or
+	// identifiers, such as MAC addresses, of the devices whose metrics aren't
+	// gathered, such as retired or test devices. Identifiers are matched in
+	// either case.
+	DevicesExclude []string `toml:"devices_exclude,omitempty"`
+	// UsersInclude and UsersExclude are lists of glob patterns matching the
+	// IDs or names of the users whose metrics are gathered, such as to only
+	// gather one person's data from a database shared by several. Metrics
+	// without a user, such as battery levels, are always gathered.
+	UsersInclude []string `toml:"users_include,omitempty"`
+	UsersExclude []string `toml:"users_exclude,omitempty"`
+	// LookupFiles is a list of CSV or TOML files mapping the values of tags,
+	// such as device_id, to more tags that metrics with them are tagged with,
+	// such as the room the device is in. They're read once on Init.
+	LookupFiles []string `toml:"lookup_files,omitempty"`
+	// TimestampPrecision, if set, truncates the timestamps of the gathered
+	// samples to a multiple of it, such as a second or a minute. Samples of
+	// the same minute whose timestamps drift by a few seconds between syncs
+	// then replace each other downstream instead of being duplicated.
+	TimestampPrecision config.Duration `toml:"timestamp_precision,omitempty"`
+	// KeepSentinels keeps the values that devices write when nothing was
+	// measured, such as a heart rate of 255, in the tables gathered by
+	// default instead of leaving them out.
+	KeepSentinels bool `toml:"keep_sentinels,omitempty"`
+	// GatherUnknownColumns gathers the numeric columns of the tables gathered
+	// by default that they don't otherwise gather as fields named after the
+	// columns, such as those added by newer versions of Gadgetbridge. Such
+	// columns are logged either way.
+	GatherUnknownColumns bool `toml:"gather_unknown_columns,omitempty"`
+	// MinTimestamp, if set, is the date or RFC 3339 time that rows of sample
+	// tables with earlier timestamps are dropped before, such as those that
+	// devices with a dead clock write in 1970.
+	MinTimestamp string `toml:"min_timestamp,omitempty"`
+	// MaxFutureSkew, if set, is how far past the time of the gather rows of
+	// sample tables can be before they're dropped, such as those that devices
+	// with a dead clock write in 2106.
+	MaxFutureSkew config.Duration `toml:"max_future_skew,omitempty"`
+	// DuplicateRows is how the rows of a table that share a timestamp and tags
+	// are handled. It defaults to DuplicateRowsKeep.
+	DuplicateRows DuplicateRowsBehavior `toml:"duplicate_rows,omitempty"`
+	// DatabaseTimezones maps database paths, or glob patterns matching them,
+	// to the IANA timezones that the devices of those databases are in. It's
+	// used for the extra tables with local_time timestamps, which are
+	// otherwise taken to be in UTC.
+	DatabaseTimezones map[string]string `toml:"database_timezones,omitempty"`
+	// MissingDatabaseBehavior is how a database in DatabasePaths that doesn't
+	// exist is handled. It defaults to MissingDatabaseError.
+	MissingDatabaseBehavior MissingDatabaseBehavior `toml:"missing_database_behavior,omitempty"`
+	// MissingTableBehavior is how an extra table that doesn't exist in a
+	// database is handled. It defaults to MissingTableIgnore.
+	MissingTableBehavior MissingTableBehavior `toml:"missing_table_behavior,omitempty"`
+	// Strict makes Init fail unless every database in DatabasePaths exists
+	// and has every extra table, with every configured column of a type that
+	// can hold what's read from it. The built-in tables are checked too if
+	// they exist.
+	Strict bool `toml:"strict,omitempty"`
+	// WatchDatabases enables gathering as soon as a database matching
+	// DatabasePaths is created or written to, in addition to every Gather.
+	// The directories of DatabasePaths are watched from Start until Stop, so
+	// they can't be glob patterns.
+	WatchDatabases bool `toml:"watch_databases,omitempty"`
+	// TrackSearchPaths is a list of directories that files referenced by
+	// recorded activities, such as FIT files and GPX tracks, are looked up in.
+	// The database's own directory is always searched last.
+	TrackSearchPaths []string `toml:"track_search_paths,omitempty"`
+	// VerifyChecksums enables verifying each database against the SHA-256
+	// checksum in its ".sha256" sidecar file before gathering it. Databases
+	// that fail verification are skipped.
+	VerifyChecksums bool `toml:"verify_checksums,omitempty"`
+	// ChecksumManifest is the path to a manifest in sha256sum format that is
+	// used instead of sidecar files. Setting it implies VerifyChecksums.
+	ChecksumManifest string `toml:"checksum_manifest,omitempty"`
+	// ProcessedAction is the action taken on a database once it has been
+	// gathered without errors. It defaults to ProcessedKeep.
+	ProcessedAction ProcessedAction `toml:"processed_action,omitempty"`
+	// ProcessedDirectory is the directory that databases are moved into when
+	// ProcessedAction is ProcessedMove.
+	ProcessedDirectory string `toml:"processed_directory,omitempty"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	// log is Log with every message prefixed with the instance ID.
+	log        telegraf.Logger
+	instanceID string
+	gatherTime selfstat.Stat
+
+	mu             sync.Mutex
+	state          pluginState
+	settingsFilter filter.Filter
+	// deviceAliases is DeviceAliases with the keys in upper case.
+	deviceAliases map[string]string
+	// devicesFilter leaves out the devices of DevicesExclude.
+	devicesFilter identityFilter
+	// usersFilter selects the users of UsersInclude and UsersExclude.
+	usersFilter identityFilter
+	// minTimestamp is MinTimestamp parsed.
+	minTimestamp time.Time
+	// lookups are the lookup tables read from LookupFiles.
+	lookups []lookupTable
+	// databaseLocations are the locations of DatabaseTimezones.
+	databaseLocations []databaseLocation
+	// tableLocations maps the extra tables with a Timezone to its location.
+	tableLocations map[string]*time.Location
+	// identityCaches holds the identities of every database for DeviceTags,
+	// UserTags and FirmwareTags.
+	identityCaches map[string]identityCache
+	// missingDatabases holds the paths of the databases that were skipped
+	// for being missing, so that they're only warned about once.
+	missingDatabases map[string]bool
+	// reportedColumns holds the databases and known tables whose unknown
+	// columns were logged, so that they're only logged once.
+	reportedColumns map[string]bool
+	// gathered holds the databases that the last gather gathered completely,
+	// for ProcessedAction to be taken on by the next one.
+	gathered []gatheredDatabase
+
+	statusMu sync.Mutex
+	status   Status
+
+	// watchMu guards the watcher, which watches the databases from Start
+	// until Stop, gathering into watchAcc.
+	watchMu   sync.Mutex
+	watcher   *fsnotify.Watcher
+	watchDone chan struct{}
+	watchAcc  telegraf.Accumulator
+}
+
+type pluginState struct {
+	// LastTableTimes is a map of the last timestamp for each table that was
+	// read. Typically, this tracks the `TIMESTAMP` column for certain tables
+	// that are read periodically.
+	LastTableTimes map[string]int64 `json:"last_table_times"`
+	// LastTableRows is a map of the keys of the rows read at the last
+	// timestamp of each sample table, so that the rows written at that
+	// timestamp after they were read are still gathered without gathering
+	// them again. A table without keys, such as one of an older state, only
+	// gathers the rows after its last timestamp.
+	LastTableRows map[string][]string `json:"last_table_rows,omitempty"`
+}
+
+var (
+	_ telegraf.Input          = (*Plugin)(nil)
+	_ telegraf.Initializer    = (*Plugin)(nil)
+	_ telegraf.StatefulPlugin = (*Plugin)(nil)
+)
+
+func (p *Plugin) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Plugin) Init() error {
+	p.SetState(nil)
+
+	p.instanceID = p.InstanceID
+	if p.instanceID == "" {
+		p.instanceID = defaultInstanceID(p.DatabasePaths)
+	}
+	p.log = newInstanceLogger(p.Log, p.instanceID)
+	p.gatherTime = newGatherTimeStat(p.instanceID)
+
+	settingsKeys := p.SettingsKeys
+	if len(settingsKeys) == 0 {
+		settingsKeys = defaultSettingsKeys
+	}
+
+	var err error
+	p.settingsFilter, err = filter.Compile(settingsKeys)
+	if err != nil {
+		return fmt.Errorf("invalid settings_keys: %w", err)
+	}
+
+	p.deviceAliases = make(map[string]string, len(p.DeviceAliases))
+	for key, alias := range p.DeviceAliases {
+		p.deviceAliases[strings.ToUpper(key)] = alias
+	}
+
+	p.devicesFilter, err = newIdentityFilter(nil, p.DevicesExclude)
+	if err != nil {
+		return fmt.Errorf("invalid devices_exclude: %w", err)
+	}
+
+	p.usersFilter, err = newIdentityFilter(p.UsersInclude, p.UsersExclude)
+	if err != nil {
+		return fmt.Errorf("invalid users_include or users_exclude: %w", err)
+	}
+
+	p.lookups = nil
+	for _, path := range p.LookupFiles {
+		lookups, err := readLookupFile(path)
+		if err != nil {
+			return fmt.Errorf("invalid lookup file %q: %w", path, err)
+		}
+		p.lookups = append(p.lookups, lookups...)
+	}
+
+	if err := p.ProcessedAction.validate(); err != nil {
+		return fmt.Errorf("invalid processed_action: %w", err)
+	}
+	if p.ProcessedAction == ProcessedMove && p.ProcessedDirectory == "" {
+		return errors.New("processed_directory must be set when processed_action is \"move\"")
+	}
+
+	if p.WatchDatabases {
+		if _, err := watchDirs(p.DatabasePaths); err != nil {
+			return fmt.Errorf("invalid watch_databases: %w", err)
+		}
+	}
+
+	if err := p.MissingDatabaseBehavior.validate(); err != nil {
+		return fmt.Errorf("invalid missing_database_behavior: %w", err)
+	}
+	if err := p.MissingTableBehavior.validate(); err != nil {
+		return fmt.Errorf("invalid missing_table_behavior: %w", err)
+	}
+
+	p.minTimestamp, err = parseMinTimestamp(p.MinTimestamp)
+	if err != nil {
+		return fmt.Errorf("invalid min_timestamp: %w", err)
+	}
+	if p.MaxFutureSkew < 0 {
+		return errors.New("max_future_skew must not be negative")
+	}
+
+	if err := p.DuplicateRows.validate(); err != nil {
+		return fmt.Errorf("invalid duplicate_rows: %w", err)
+	}
+
+	if p.TimestampPrecision < 0 {
+		return errors.New("timestamp_precision must not be negative")
+	}
+
+	p.databaseLocations, err = loadDatabaseLocations(p.DatabaseTimezones)
+	if err != nil {
+		return fmt.Errorf("invalid database_timezones: %w", err)
+	}
+
+	p.tableLocations = make(map[string]*time.Location)
+	for _, t := range knownTables {
+		if t.Columns.Timezone != "" {
+			// RegisterTables already made sure that the timezone loads.
+			loc, _ := time.LoadLocation(t.Columns.Timezone)
+			p.tableLocations[t.Name] = loc
+		}
+	}
+	for i, t := range p.ExtraTables {
+		if t.Name == "" {
+			return fmt.Errorf("extra table #%d is missing its table name", i+1)
+		}
+		if isKnownTable(t.Name) {
+			// Configs generated before the table was gathered by default
+			// still list it, so rather than failing to start, the built-in
+			// description takes its place.
+			p.log.Warnf("Extra table %q is gathered by default now, so it's gathered as the plugin describes it instead; remove it from extra_tables", t.Name)
+			continue
+		}
+		if slices.ContainsFunc(p.ExtraTables[:i], func(other TableDescription) bool { return other.Name == t.Name }) {
+			return fmt.Errorf("extra table %q is listed more than once", t.Name)
+		}
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("invalid extra table %q: %w", t.Name, err)
+		}
+		if t.Columns.Timezone != "" {
+			loc, err := time.LoadLocation(t.Columns.Timezone)
+			if err != nil {
+				return fmt.Errorf("invalid timezone of extra table %q: %w", t.Name, err)
+			}
+			p.tableLocations[t.Name] = loc
+		}
+	}
+
+	p.ExtraTables = slices.DeleteFunc(slices.Clone(p.ExtraTables), func(t TableDescription) bool {
+		return isKnownTable(t.Name)
+	})
+	for _, ignored := range ignoredPackTables {
+		p.log.Warnf("Table %q of table pack %q is already gathered by default, so the pack's description of it is ignored", ignored.table, ignored.pack)
+	}
+
+	if err := p.validateSleepSessions(); err != nil {
+		return fmt.Errorf("invalid sleep_sessions: %w", err)
+	}
+
+	if p.Strict {
+		if err := p.validateDatabases(true); err != nil {
+			return fmt.Errorf("strict schema check failed:\n%w", err)
+		}
+	}
+
+	return nil
+}
+
+// The tables are described and read by the gadgetbridgedb package, which
+// other tools can use to read the databases without depending on Telegraf.
+type (
+	// TableDescription describes a table in the database.
+	TableDescription = gadgetbridgedb.TableDescription
+	// TableColumns describes the columns in a table.
+	TableColumns = gadgetbridgedb.TableColumns
+	// TableJoin describes a table that tags are looked up in.
+	TableJoin = gadgetbridgedb.TableJoin
+	// ValueRange is an inclusive range of values.
+	ValueRange = gadgetbridgedb.ValueRange
+	// ColumnInfo describes a column of a table.
+	ColumnInfo = gadgetbridgedb.ColumnInfo
+)
+
+// knownTables are the tables gathered by default. Like extra tables, each is
+// read by the Reader of its description, which keeps what's particular to a
+// device's tables, such as how they encode their values, out of gatherTable.
+var knownTables = []TableDescription{
+	gadgetbridgedb.HybridHRActivitySamples,
+	gadgetbridgedb.BatteryLevels,
+	gadgetbridgedb.HuamiHeartRateManualSamples,
+	gadgetbridgedb.HuamiHeartRateRestingSamples,
+	gadgetbridgedb.HuamiHeartRateMaxSamples,
+	gadgetbridgedb.XiaomiSleepStageSamples,
+	gadgetbridgedb.XiaomiSleepTimeSamples,
+	gadgetbridgedb.GarminActivitySamples,
+	gadgetbridgedb.GarminStressSamples,
+	gadgetbridgedb.GarminBodyEnergySamples,
+	gadgetbridgedb.GarminHRVValueSamples,
+	gadgetbridgedb.GarminHRVSummarySamples,
+	gadgetbridgedb.PebbleHealthActivitySamples,
+	gadgetbridgedb.PebbleHealthActivityOverlays,
+	gadgetbridgedb.PebbleMisfitSamples,
+	gadgetbridgedb.PebbleMorpheuzSamples,
+	gadgetbridgedb.FitProActivitySamples,
+	gadgetbridgedb.WithingsSteelHRActivitySamples,
+	gadgetbridgedb.SonySWR12Samples,
+	gadgetbridgedb.LefunActivitySamples,
+	gadgetbridgedb.LefunBiometricSamples,
+	gadgetbridgedb.LefunSleepSamples,
+	gadgetbridgedb.HuaweiActivitySamples,
+	gadgetbridgedb.HuaweiWorkoutSummarySamples,
+	gadgetbridgedb.HuaweiWorkoutDataSamples,
+	gadgetbridgedb.MiScaleWeightSamples,
+	gadgetbridgedb.ColmiActivitySamples,
+	gadgetbridgedb.ColmiHeartRateSamples,
+	gadgetbridgedb.ColmiSpo2Samples,
+	gadgetbridgedb.ColmiStressSamples,
+	gadgetbridgedb.ColmiSleepStageSamples,
+	gadgetbridgedb.ColmiSleepSessionSamples,
+}
+
+// gatherOptions changes how a gather is done.
+type gatherOptions struct {
+	// backfill, if true, ignores the state and leaves it untouched, gathering
+	// only the rows within [from, to) instead. A zero from or to leaves that
+	// end of the range open. What's left out of a backfill is listed at
+	// Backfill, and databases aren't processed during one.
+	backfill bool
+	from, to time.Time
+	// tables, if not nil, restricts the gather to the tables it matches.
+	// Activity files are gathered if it matches BASE_ACTIVITY_SUMMARY.
+	tables filter.Filter
+	// limit, if positive, restricts the gather to the newest limit rows of
+	// each table and the files of the newest limit activities.
+	limit int
+}
+
+// includes returns whether the table is gathered.
+func (o gatherOptions) includes(table string) bool {
+	return o.tables == nil || o.tables.Match(table)
+}
+
+// where restricts q to the rows whose column is within the backfill range.
+// unixTime converts a time into the unit of the column.
+func (o gatherOptions) where(q *goqu.SelectDataset, column string, unixTime func(time.Time) int64) *goqu.SelectDataset {
+	if !o.from.IsZero() {
+		q = q.Where(goqu.C(column).Gte(unixTime(o.from)))
+	}
+	if !o.to.IsZero() {
+		q = q.Where(goqu.C(column).Lt(unixTime(o.to)))
+	}
+	return q
+}
+
+// newest restricts q, which must be ordered by column, to the newest rows if
+// the gather is limited, keeping them in order.
+func (o gatherOptions) newest(q *goqu.SelectDataset, column string) *goqu.SelectDataset {
+	if o.limit <= 0 {
+		return q
+	}
+	newest := q.Order(goqu.C(column).Desc()).Limit(uint(o.limit))
+	return sqliteBuilder.From(newest).Order(goqu.C(column).Asc())
+}
+
+func (p *Plugin) Gather(acc telegraf.Accumulator) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.gather(acc, gatherOptions{})
+}
+
+// BackfillOptions selects the rows gathered by Backfill.
+type BackfillOptions struct {
+	// From and To restrict the rows to those recorded within [From, To). A
+	// zero From or To leaves that end of the range open.
+	From, To time.Time
+	// Tables is a list of glob patterns matching the names of the tables to
+	// gather, or empty to gather every configured table. The activity files
+	// of BASE_ACTIVITY_SUMMARY are gathered if it's matched.
+	Tables []string
+	// Limit, if positive, gathers only the newest Limit rows of each table
+	// and the files of the newest Limit activities, such as to quickly try
+	// out a config without going through every row.
+	Limit int
+}
+
+// Backfill gathers only the rows selected by opts, regardless of what has
+// been gathered before. The plugin's state is left untouched.
+//
+// Whatever describes the present rather than what was recorded, which is the
+// heartbeat, table stats, freshness, device inventory, user profiles and
+// settings, has no place in a backfill and is left out.
+func (p *Plugin) Backfill(acc telegraf.Accumulator, opts BackfillOptions) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tables, err := filter.Compile(opts.Tables)
+	if err != nil {
+		return fmt.Errorf("invalid tables: %w", err)
+	}
+
+	return p.gather(acc, gatherOptions{
+		backfill: true,
+		from:     opts.From,
+		to:       opts.To,
+		tables:   tables,
+		limit:    opts.Limit,
+	})
+}
+
+func (p *Plugin) gather(acc telegraf.Accumulator, opts gatherOptions) error {
+	defer p.recordGatherTime(time.Now())
+
+	if !opts.backfill {
+		p.startStatus()
+		if err := p.processGathered(); err != nil {
+			acc.AddError(err)
+		}
+	}
+
+	if p.TimestampPrecision > 0 {
+		acc = truncatingAccumulator{acc, time.Duration(p.TimestampPrecision)}
+	}
+	// The lookups come before the identities are tagged, so that they can
+	// look up those tags too.
+	if len(p.lookups) > 0 {
+		acc = lookupTaggingAccumulator{acc, p.lookups}
+	}
+
+	var errs []error
+
+	paths, err := expandDatabasePaths(p.DatabasePaths)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	dbErrs := make(map[string]error, len(paths))
+
+	heartbeat := func(path string, db *sql.DB) {
+		if p.GatherHeartbeat && !opts.backfill {
+			addHeartbeat(acc, path, db)
+		}
+	}
+
+	for _, path := range paths {
+		if p.MissingDatabaseBehavior == MissingDatabaseIgnore {
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+				p.skipMissingDatabase(path)
+				dbErrs[path] = err
+				heartbeat(path, nil)
+				continue
+			}
+			p.foundDatabase(path)
+		}
+
+		if p.VerifyChecksums || p.ChecksumManifest != "" {
+			// A database that doesn't match its checksum is most likely still
+			// being synced, so it's skipped with a warning rather than failing
+			// the whole gather.
+			if err := p.verifyChecksum(path); err != nil {
+				acc.AddError(fmt.Errorf("skipping database %q: %w", path, err))
+				dbErrs[path] = err
+				heartbeat(path, nil)
+				continue
+			}
+		}
+
+		start := time.Now()
+		// The database may be replaced while it's gathered, such as by the
+		// next sync, which must not be processed before it's gathered too.
+		info, statErr := os.Stat(path)
+
+		db, err := gadgetbridgedb.Open(path)
+		if err == nil {
+			// The database is only opened once it's used, so a missing one
+			// would otherwise fail every table on its own.
+			if err = db.Ping(); err != nil {
+				db.Close()
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to open database %q: %w", path, err))
+			dbErrs[path] = err
+			heartbeat(path, nil)
+			continue
+		}
+
+		heartbeat(path, db)
+
+		acc := acc
+		tagging := p.DeviceTags || p.UserTags || p.FirmwareTags || len(p.deviceAliases) > 0
+		filtering := p.devicesFilter.enabled() || p.usersFilter.enabled()
+		if tagging || filtering {
+			ids, err := p.loadIdentities(db, path)
+			if err != nil {
+				p.log.Warnf("Failed to read the devices and users of %q, leaving them untagged: %v", path, err)
+			}
+			if tagging {
+				tagged := ids
+				if !p.UserTags {
+					tagged.users = nil
+				}
+				if !p.FirmwareTags {
+					tagged.firmware = nil
+				}
+				acc = identityTaggingAccumulator{acc, tagged, p.DeviceTags, p.deviceAliases}
+			}
+			// The devices and users are filtered first, so that no work is
+			// spent on the metrics of those that are left out.
+			if filtering {
+				acc = identityFilteringAccumulator{acc, ids, p.devicesFilter, p.usersFilter}
+			}
+		}
+
+		nerrs := len(errs)
+
+		// An error at a single table is counted and reported without failing
+		// the gather, so that the other tables are still gathered.
+		var tableErrs []error
+		tableFailed := func(table string, err error) {
+			class := errorQuery
+			if errors.Is(err, errMissingTable) {
+				class = errorMissingTable
+			}
+			p.newTableStats(table).errors.Incr(1)
+			addTableError(acc, path, table, class, 1)
+			acc.AddError(fmt.Errorf("database %q: %w", path, err))
+			tableErrs = append(tableErrs, err)
+		}
+
+		for _, t := range slices.Concat(knownTables, p.ExtraTables) {
+			if !opts.includes(t.Name) {
+				continue
+			}
+
+			saved := p.saveTableState(t.Name)
+			err := p.gatherTable(acc, db, path, t, opts)
+			if isReadError(err) {
+				// The file may have been replaced while it was read, so the
+				// table is read once more from the start in a fresh
+				// connection. Rows that were already gathered are gathered
+				// again with the same timestamps and tags, which overwrite
+				// them downstream.
+				p.log.Warnf("Retrying table %q of %q after failing to read it: %v", t.Name, path, err)
+				p.restoreTableState(t.Name, saved)
+				if db, err = reopenDB(db, path); err == nil {
+					err = p.gatherTable(acc, db, path, t, opts)
+				}
+			}
+			if err != nil {
+				// The rows read before the failure are left to the next
+				// gather, so that the state doesn't skip those that weren't.
+				p.restoreTableState(t.Name, saved)
+				tableFailed(t.Name, fmt.Errorf("error at table %q: %w", t.Name, err))
+			}
+
+			p.progressStatus()
+		}
+
+		if p.GatherFITFiles && opts.includes("BASE_ACTIVITY_SUMMARY") {
+			if err := p.gatherFITFiles(acc, db, path, opts); err != nil {
+				tableFailed(fitStateKey, fmt.Errorf("error gathering FIT files: %w", err))
+			}
+		}
+
+		if p.GatherGPXTracks && opts.includes("BASE_ACTIVITY_SUMMARY") {
+			if err := p.gatherGPXTracks(acc, db, path, opts); err != nil {
+				tableFailed(gpxStateKey, fmt.Errorf("error gathering GPX tracks: %w", err))
+			}
+		}
+
+		if p.GatherUserAttributes && opts.includes(userAttributesTable) {
+			if err := p.gatherUserAttributes(acc, db, path, opts); err != nil {
+				tableFailed(userAttributesTable, fmt.Errorf("error gathering user attributes: %w", err))
+			}
+		}
+
+		if p.GatherActivitySummaries && opts.includes(activitySummaryTable) {
+			if err := p.gatherActivitySummaries(acc, db, path, opts); err != nil {
+				tableFailed(activitySummaryTable, fmt.Errorf("error gathering activity summaries: %w", err))
+			}
+		}
+
+		if p.GatherDeviceAttributes && opts.includes(deviceAttributesTable) {
+			if err := p.gatherDeviceAttributes(acc, db, path, opts); err != nil {
+				tableFailed(deviceAttributesTable, fmt.Errorf("error gathering device attributes: %w", err))
+			}
+		}
+
+		if p.GatherSessions {
+			p.gatherSessions(acc, db, path, opts, tableFailed)
+		}
+
+		if p.GatherFreshness && !opts.backfill {
+			if err := gatherFreshness(acc, db, path, slices.Concat(knownTables, p.ExtraTables)); err != nil {
+				errs = append(errs, fmt.Errorf("failed to gather freshness of database %q: %w", path, err))
+			}
+		}
+
+		if p.GatherDeviceInventory && !opts.backfill {
+			if err := gatherDeviceInventory(acc, db, path, slices.Concat(knownTables, p.ExtraTables)); err != nil {
+				errs = append(errs, fmt.Errorf("failed to gather device inventory of database %q: %w", path, err))
+			}
+		}
+
+		if p.GatherUserProfiles && !opts.backfill {
+			if err := p.gatherUserProfiles(acc, db, path); err != nil {
+				errs = append(errs, fmt.Errorf("failed to gather user profiles of database %q: %w", path, err))
+			}
+		}
+
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close database %q: %w", path, err))
+		}
+
+		p.databaseDurations(path).observe(time.Since(start))
+		p.progressStatus()
+
+		// Only databases that were gathered completely are processed, since
+		// moving or deleting them would otherwise lose the rows that failed.
+		if len(errs) == nerrs && len(tableErrs) == 0 && !opts.backfill && statErr == nil {
+			p.gathered = append(p.gathered, gatheredDatabase{path, info})
+		}
+
+		dbErrs[path] = errors.Join(slices.Concat(errs[nerrs:], tableErrs)...)
+	}
+
+	if !opts.backfill {
+		for _, path := range p.SettingsPaths {
+			if err := p.gatherSettings(acc, path); err != nil {
+				errs = append(errs, fmt.Errorf("failed to gather settings %q: %w", path, err))
+			}
+		}
+
+		p.updateStatus(dbErrs, len(errs) > 0)
+	}
+
+	return errors.Join(errs...)
+}
+
+// expandDatabasePaths expands the glob patterns in paths. Paths that aren't
+// patterns are kept as-is, so that a missing database is still reported.
+func expandDatabasePaths(paths []string) ([]string, error) {
+	expanded := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !strings.ContainsAny(path, "*?[") {
+			expanded = append(expanded, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return expanded, fmt.Errorf("invalid database path pattern %q: %w", path, err)
+		}
+		expanded = append(expanded, matches...)
+	}
+	return expanded, nil
+}
+
+var sqliteBuilder = goqu.Dialect("sqlite")
+
+// slowGatherThreshold is how long gathering a single table may take before
+// it's logged as slow.
+const slowGatherThreshold = 10 * time.Second
+
+func (p *Plugin) gatherTable(acc telegraf.Accumulator, db *sql.DB, dbPath string, t TableDescription, opts gatherOptions) error {
+	start := time.Now()
+
+	columns, err := gadgetbridgedb.Columns(db, t.Name)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		// Not every device records every table that's gathered by default, so
+		// only missing extra tables can be worth more than a debug message.
+		switch {
+		case isKnownTable(t.Name), p.MissingTableBehavior == "", p.MissingTableBehavior == MissingTableIgnore:
+			p.log.Debugf("Skipping table %q missing from %q", t.Name, dbPath)
+		case p.MissingTableBehavior == MissingTableWarn:
+			p.log.Warnf("Skipping table %q missing from %q", t.Name, dbPath)
+			addTableError(acc, dbPath, t.Name, errorMissingTable, 1)
+		case p.MissingTableBehavior == MissingTableError:
+			return errMissingTable
+		}
+		return nil
+	}
+
+	if isKnownTable(t.Name) {
+		t = p.withExistingColumns(t, dbPath, columns)
+		t = p.withUnknownColumns(t, dbPath, columns)
+	}
+
+	reader := t.TableReader()
+	unixTime := reader.Unix
+	var clock localClock
+	if t.Columns.LocalTime {
+		clock = localClock{p.tableLocation(t.Name, dbPath)}
+		unixTime = func(at time.Time) int64 {
+			return reader.Unix(time.Unix(clock.unix(at), int64(at.Nanosecond())))
+		}
+	}
+
+	// The rows at the last timestamp are read again, skipping those that
+	// were already read, in case more were written at that timestamp since.
+	lastTime, hasLastTime := p.state.LastTableTimes[t.Name]
+	lastRows, hasLastRows := p.state.LastTableRows[t.Name]
+	var readOpts gadgetbridgedb.ReadOptions
+	if opts.backfill {
+		if !opts.from.IsZero() {
+			from := unixTime(opts.from)
+			readOpts.From = &from
+		}
+		if !opts.to.IsZero() {
+			to := unixTime(opts.to)
+			readOpts.To = &to
+		}
+		readOpts.Newest = opts.limit
+	} else if hasLastTime && hasLastRows {
+		readOpts.From = &lastTime
+	} else if hasLastTime {
+		readOpts.After = &lastTime
+	}
+
+	r, err := gadgetbridgedb.Read(db, t, readOpts)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	sentinels := t.Columns.Sentinels
+	if p.KeepSentinels && isKnownTable(t.Name) {
+		sentinels = nil
+	}
+
+	rowAcc := acc
+	var dedup *deduplicatingAccumulator
+	if p.DuplicateRows != "" && p.DuplicateRows != DuplicateRowsKeep {
+		dedup = newDeduplicatingAccumulator(acc, p.DuplicateRows)
+		rowAcc = dedup
+	}
+
+	tags := make(map[string]string, len(t.Columns.Tags))
+	fields := make(map[string]interface{}, len(t.Columns.Fields))
+
+	joinedTags := t.JoinedTagNames()
+
+	// Every value of a field is coerced to the type of its column, so that
+	// the field keeps a single type however the values were written.
+	affinities := make([]gadgetbridgedb.Affinity, len(t.Columns.Fields))
+	for i, field := range t.Columns.Fields {
+		if j := slices.IndexFunc(columns, func(c gadgetbridgedb.ColumnInfo) bool { return strings.EqualFold(c.Name, field) }); j >= 0 {
+			affinities[i] = columns[j].Affinity()
+		}
+	}
+
+	// Rows are counted per device for the table stats, or under an empty
+	// device if the table has none, neither its own nor a joined one.
+	deviceTag := slices.Index(t.Columns.Tags, "DEVICE_ID")
+	joinedDeviceTag := slices.Index(joinedTags, "device_id")
+	deviceRows := make(map[string]int)
+
+	var n, dropped, mistyped, implausible, badTimestamps, emitted int
+	var dropErr error
+	for r.Next() {
+		row, err := r.Scan()
+		if err != nil {
+			if dropped == 0 {
+				dropErr = err
+			}
+			dropped++
+			continue
+		}
+
+		key := row.Key()
+		if !opts.backfill && hasLastRows && row.Timestamp == lastTime && slices.Contains(lastRows, key) {
+			continue
+		}
+
+		// What a TableReader decodes can differ between rows, so nothing is
+		// kept from the previous row.
+		clear(tags)
+		clear(fields)
+		tags["database_path"] = dbPath
+
+		nullTag := -1
+		for i, tag := range t.Columns.Tags {
+			v := row.Tags[i]
+			switch {
+			case v.Valid:
+				tags[t.Columns.KeyName(tag)] = v.String
+			case t.Columns.NullTags == NullTagsDropTag:
+				delete(tags, t.Columns.KeyName(tag))
+			case t.Columns.NullTags == NullTagsUnknown:
+				tags[t.Columns.KeyName(tag)] = gadgetbridgedb.NullTagValue
+			default:
+				nullTag = i
+			}
+		}
+		if nullTag != -1 {
+			if dropped == 0 {
+				dropErr = fmt.Errorf("tag column %q is NULL", t.Columns.Tags[nullTag])
+			}
+			dropped++
+			continue
+		}
+		if err := reader.Decode(row.Decoded, tags, fields); err != nil {
+			if dropped == 0 {
+				dropErr = err
+			}
+			dropped++
+			continue
+		}
+		n++
+
+		for i, field := range t.Columns.Fields {
+			v, ok := gadgetbridgedb.CoerceValue(row.Fields[i], affinities[i])
+			if !ok {
+				mistyped++
+				delete(fields, t.Columns.KeyName(field))
+				continue
+			}
+			if gadgetbridgedb.IsSentinel(sentinels[field], v) {
+				delete(fields, t.Columns.KeyName(field))
+				continue
+			}
+			if r, ok := t.Columns.Plausible[field]; ok && !r.Contains(v) {
+				implausible++
+				delete(fields, t.Columns.KeyName(field))
+				continue
+			}
+			fields[t.Columns.KeyName(field)] = v
+		}
+
+		for i, tag := range joinedTags {
+			if v := row.JoinedTags[i]; v.Valid {
+				tags[tag] = v.String
+			} else {
+				delete(tags, tag)
+			}
+		}
+
+		at := reader.Time(row.Timestamp)
+		if t.Columns.LocalTime {
+			at = clock.time(at.Unix(), row.UTCOffset).Add(time.Duration(at.Nanosecond()))
+		}
+
+		// The state isn't moved past a row from the future, so that the rows
+		// before the time it claims aren't skipped.
+		if !p.timestampInBounds(at, start) {
+			badTimestamps++
+			continue
+		}
+
+		// A row of nothing but sentinels has nothing left to gather.
+		if len(fields) > 0 || len(t.Columns.Fields) == 0 {
+			rowAcc.AddFields(t.MeasurementName(), fields, tags, at)
+			emitted++
+		}
+		if !opts.backfill {
+			if !hasLastTime || row.Timestamp != lastTime {
+				lastTime, hasLastTime = row.Timestamp, true
+				lastRows = nil
+			}
+			lastRows = append(lastRows, key)
+			p.state.LastTableTimes[t.Name] = row.Timestamp
+			p.state.LastTableRows[t.Name] = lastRows
+		}
+
+		switch {
+		case deviceTag != -1:
+			deviceRows[row.Tags[deviceTag].String]++
+		case joinedDeviceTag != -1:
+			deviceRows[row.JoinedTags[joinedDeviceTag].String]++
+		default:
+			deviceRows[""]++
+		}
+	}
+
+	// The state already covers the rows that are held back, so they're added
+	// even if the rest couldn't be read.
+	if dedup != nil {
+		dedup.Flush()
+		emitted -= dedup.merged
+	}
+
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("error reading rows: %w", err)
+	}
+
+	// Only one connection is allowed, so the rows must be closed before the
+	// table stats can be queried.
+	r.Close()
+
+	if p.GatherTableStats && !opts.backfill {
+		if err := gatherTableStats(acc, db, dbPath, t, deviceRows); err != nil {
+			return fmt.Errorf("error gathering table stats: %w", err)
+		}
+	}
+
+	stats := p.newTableStats(t.Name)
+	stats.rowsRead.Incr(int64(n + dropped))
+	stats.rowsDropped.Incr(int64(dropped))
+	stats.metricsEmitted.Incr(int64(emitted))
+
+	if dropped > 0 {
+		p.log.Warnf("Dropped %d unreadable rows from table %q of %q, the first because of: %v", dropped, t.Name, dbPath, dropErr)
+		addTableError(acc, dbPath, t.Name, errorDroppedRows, dropped)
+	}
+	if badTimestamps > 0 {
+		p.log.Warnf("Dropped %d rows with timestamps out of bounds from table %q of %q", badTimestamps, t.Name, dbPath)
+		addTableError(acc, dbPath, t.Name, errorBadTimestamp, badTimestamps)
+	}
+	if mistyped > 0 {
+		p.log.Warnf("Left out %d values that aren't numbers from table %q of %q", mistyped, t.Name, dbPath)
+		addTableError(acc, dbPath, t.Name, errorMistypedValue, mistyped)
+	}
+	if implausible > 0 {
+		p.log.Warnf("Left out %d implausible values from table %q of %q", implausible, t.Name, dbPath)
+		addTableError(acc, dbPath, t.Name, errorImplausibleValue, implausible)
+	}
+
+	took := time.Since(start)
+	p.tableDurations(t.Name).observe(took)
+	if took > slowGatherThreshold {
+		p.log.Warnf("Gathering table %q of %q took %s", t.Name, dbPath, took.Round(time.Millisecond))
+	}
+
+	p.log.Debugf("Gathered %d rows from table %q of %q", n, t.Name, dbPath)
+	return nil
+}
+
+func (p *Plugin) GetState() interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lastTableRows := make(map[string][]string, len(p.state.LastTableRows))
+	for table, rows := range p.state.LastTableRows {
+		lastTableRows[table] = slices.Clone(rows)
+	}
+
+	return pluginState{
+		LastTableTimes: maps.Clone(p.state.LastTableTimes),
+		LastTableRows:  lastTableRows,
+	}
+}
+
+func (p *Plugin) SetState(state interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch state := state.(type) {
+	case nil:
+		p.state = pluginState{
+			LastTableTimes: make(map[string]int64),
+			LastTableRows:  make(map[string][]string),
+		}
+	case pluginState:
+		p.state = state
+		if p.state.LastTableRows == nil {
+			p.state.LastTableRows = make(map[string][]string)
+		}
+	default:
+		return fmt.Errorf("invalid state type: %T", state)
+	}
+
+	return nil
+}
+
+// LastGathered returns the time of the last row gathered from each table, as
+// recorded in the plugin's state. The activities gathered from FIT and GPX
+// files are recorded under "BASE_ACTIVITY_SUMMARY/fit" and
+// "BASE_ACTIVITY_SUMMARY/gpx" respectively.
+func (p *Plugin) LastGathered() map[string]time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	times := make(map[string]time.Time, len(p.state.LastTableTimes))
+	for table, t := range p.state.LastTableTimes {
+		times[table] = guessUnixTime(t)
+	}
+	return times
+}
+
+// ResetState forgets what was gathered from the tables matching any of the
+// given glob patterns, so that they're gathered from the start again. No
+// patterns reset every table. It returns the tables that were reset.
+func (p *Plugin) ResetState(patterns []string) ([]string, error) {
+	f, err := filter.Compile(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid table patterns: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var reset []string
+	for table := range p.state.LastTableTimes {
+		if f == nil || f.Match(table) {
+			reset = append(reset, table)
+			delete(p.state.LastTableTimes, table)
+			delete(p.state.LastTableRows, table)
+		}
+	}
+	slices.Sort(reset)
+
+	return reset, nil
+}
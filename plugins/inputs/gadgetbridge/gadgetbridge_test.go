@@ -0,0 +1,2551 @@
+package gadgetbridge
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "embed"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/hexops/autogold/v2"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	telegraftest "github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+//go:embed testdata/gadgetbridge.sql
+var gadgetbridgeDump string
+
+func TestPlugin_Gather(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	var state any
+
+	t.Run("pass 1", func(t *testing.T) {
+		p := &Plugin{DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{}}
+		assert.NoError(t, p.Init())
+
+		assert.NoError(t, p.SetState(state))
+		t.Cleanup(func() { state = p.GetState() })
+
+		acc := new(telegraftest.Accumulator)
+		acc.TimeFunc = randomTime
+
+		assert.NoError(t, p.Gather(acc))
+
+		for _, metric := range acc.Metrics {
+			// Manually verify the 'database_path' tag.
+			assert.Equal(t, dbPath, metric.Tags["database_path"], "database_path tag mismatch")
+			delete(metric.Tags, "database_path")
+
+			// Manually verify the timestamp.
+			assert.False(t, metric.Time.IsZero(), "metric timestamp is zero")
+		}
+
+		autogold.ExpectFile(t, acc.Metrics, autogold.Name("TestPlugin_Gather/metrics"))
+	})
+
+	t.Run("pass 2", func(t *testing.T) {
+		p := &Plugin{DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{}}
+		assert.NoError(t, p.Init())
+		assert.NoError(t, p.SetState(state))
+
+		acc2 := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc2))
+
+		if len(acc2.Metrics) > 0 {
+			t.Errorf("unexpected %d metrics gathered on second pass", len(acc2.Metrics))
+			for _, metric := range acc2.Metrics {
+				t.Logf("  %s %s", metric.Time, metric.Measurement)
+			}
+		}
+
+		assert.Equal(t, state, p.GetState(), "state mismatch")
+	})
+}
+
+func newTestDB(t testing.TB, sqlDump string) string {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "gadgetbridge-test")
+	assert.NoError(t, err, "failed to create temp dir")
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	dbPath := filepath.Join(tmpDir, "gadgetbridge.db")
+
+	db, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err, "failed to open SQLite database")
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(sqlDump)
+	assert.NoError(t, err, "failed to apply SQLite database dump")
+
+	return dbPath
+}
+
+func openTestDB(t *testing.T, dbPath string) *sql.DB {
+	t.Helper()
+
+	db, err := gadgetbridgedb.Open(dbPath)
+	assert.NoError(t, err, "failed to open test database")
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func randomTime() time.Time { return time.Unix(rand.Int63(), 0) }
+
+func TestPlugin_GatherSettings(t *testing.T) {
+	p := &Plugin{SettingsPaths: []string{"testdata/settings.json"}, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	acc.TimeFunc = func() time.Time { return time.Unix(0, 0).UTC() }
+
+	assert.NoError(t, p.Gather(acc))
+	autogold.ExpectFile(t, acc.Metrics, autogold.Name("TestPlugin_GatherSettings/metrics"))
+}
+
+func TestPlugin_GatherSettingsTypes(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	assert.NoError(t, os.WriteFile(settingsPath, []byte(`{
+		"fitness_goal": "lots",
+		"heartrate_measurement_interval": 60,
+		"alarm_snooze_minutes": "10"
+	}`), 0o644))
+
+	log := new(telegraftest.CaptureLogger)
+	p := &Plugin{SettingsPaths: []string{settingsPath}, Log: log}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	// A number that isn't of a known preference stays a string, so that its
+	// type is the same as when it isn't a number.
+	fields, ok := acc.Get(settingsMeasurement)
+	assert.True(t, ok, "settings not gathered")
+	assert.Equal(t, map[string]any{
+		"heartrate_measurement_interval": int64(60),
+		"alarm_snooze_minutes":           "10",
+	}, fields.Fields)
+
+	warnings := log.Warnings()
+	assert.Equal(t, 1, len(warnings), "unexpected warnings: %q", warnings)
+	assert.Contains(t, warnings[0], `Skipping preference "fitness_goal"`)
+}
+
+func TestPlugin_GatherGPXTracks(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO BASE_ACTIVITY_SUMMARY (_id, START_TIME, END_TIME, ACTIVITY_KIND, GPX_TRACK, DEVICE_ID, USER_ID)
+		VALUES (1, 1725807600000, 1725807605000, 16, '/storage/emulated/0/Android/data/nodomain.freeyourgadget.gadgetbridge/files/gpx/track.gpx', 1, 1);
+	`)
+
+	p := &Plugin{
+		GatherGPXTracks:  true,
+		TrackSearchPaths: []string{"testdata"},
+		Log:              telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.gatherGPXTracks(acc, openTestDB(t, dbPath), dbPath, gatherOptions{}))
+	assert.NoError(t, acc.FirstError())
+
+	for _, metric := range acc.Metrics {
+		delete(metric.Tags, "database_path")
+	}
+
+	autogold.ExpectFile(t, acc.Metrics, autogold.Name("TestPlugin_GatherGPXTracks/metrics"))
+}
+
+func TestPlugin_GatherMissingActivityFiles(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO BASE_ACTIVITY_SUMMARY (_id, START_TIME, END_TIME, ACTIVITY_KIND, GPX_TRACK, DEVICE_ID, USER_ID)
+		VALUES
+			(1, 1725807600000, 1725807605000, 16, 'gpx/first.gpx', 1, 1),
+			(2, 1725811200000, 1725811205000, 16, 'gpx/second.gpx', 1, 1);
+	`)
+
+	track, err := os.ReadFile("testdata/gpx/track.gpx")
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "gpx"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "gpx", "second.gpx"), track, 0o644))
+
+	p := &Plugin{
+		GatherGPXTracks:  true,
+		TrackSearchPaths: []string{dir},
+		Log:              telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+	db := openTestDB(t, dbPath)
+
+	gatheredActivities := func() map[string]bool {
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.gatherGPXTracks(acc, db, dbPath, gatherOptions{}))
+
+		activities := make(map[string]bool)
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == gpxPointMeasurement {
+				activities[metric.Tags["activity_id"]] = true
+			}
+		}
+		return activities
+	}
+
+	// The first track is missing, which doesn't keep the second from being
+	// gathered.
+	assert.Equal(t, map[string]bool{"2": true}, gatheredActivities())
+
+	// Once the first track shows up, it's gathered without the second.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "gpx", "first.gpx"), track, 0o644))
+	assert.Equal(t, map[string]bool{"1": true}, gatheredActivities())
+
+	assert.Equal(t, map[string]bool{}, gatheredActivities())
+}
+
+func TestPlugin_GatherPartialGPXTrack(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO BASE_ACTIVITY_SUMMARY (_id, START_TIME, END_TIME, ACTIVITY_KIND, GPX_TRACK, DEVICE_ID, USER_ID)
+		VALUES (1, 1725807600000, 1725807605000, 16, 'track.gpx', 1, 1);
+	`)
+
+	track, err := os.ReadFile("testdata/gpx/track.gpx")
+	assert.NoError(t, err)
+
+	// The track is only half copied over from the phone on the first gather.
+	dir := t.TempDir()
+	trackPath := filepath.Join(dir, "track.gpx")
+	assert.NoError(t, os.WriteFile(trackPath, track[:len(track)/2], 0o644))
+
+	p := &Plugin{
+		GatherGPXTracks:  true,
+		TrackSearchPaths: []string{dir},
+		Log:              telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+	db := openTestDB(t, dbPath)
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.gatherGPXTracks(acc, db, dbPath, gatherOptions{}))
+	assert.Error(t, acc.FirstError())
+	assert.False(t, acc.HasMeasurement(gpxPointMeasurement))
+
+	assert.NoError(t, os.WriteFile(trackPath, track, 0o644))
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.gatherGPXTracks(acc, db, dbPath, gatherOptions{}))
+	assert.NoError(t, acc.FirstError())
+	assert.True(t, acc.HasMeasurement(gpxPointMeasurement))
+}
+
+// TestPlugin_GatherFixtures gathers every table of the databases dumped in
+// testdata/fixtures, which have the schemas of different Gadgetbridge versions
+// and devices, so that a change breaking any of them shows in their snapshot.
+func TestPlugin_GatherFixtures(t *testing.T) {
+	dumps, err := filepath.Glob("testdata/fixtures/*.sql")
+	assert.NoError(t, err)
+	assert.NotEqual(t, 0, len(dumps), "no fixtures found")
+
+	for _, dump := range dumps {
+		name := strings.TrimSuffix(filepath.Base(dump), ".sql")
+		t.Run(name, func(t *testing.T) {
+			sql, err := os.ReadFile(dump)
+			assert.NoError(t, err)
+
+			dbPath := newTestDB(t, string(sql))
+
+			extraTables, err := GenerateExtraTables(dbPath)
+			assert.NoError(t, err)
+
+			p := &Plugin{
+				DatabasePaths:           []string{dbPath},
+				ExtraTables:             extraTables,
+				GatherUserAttributes:    true,
+				GatherActivitySummaries: true,
+				GatherDeviceAttributes:  true,
+				DeviceTags:              true,
+				UserTags:                true,
+				FirmwareTags:            true,
+				Log:                     telegraftest.Logger{},
+			}
+			assert.NoError(t, p.Init())
+			assert.Equal(t, 0, len(p.validateDatabase(dbPath, true)), "schema problems")
+
+			acc := new(telegraftest.Accumulator)
+			acc.TimeFunc = func() time.Time { return time.Unix(0, 0).UTC() }
+
+			assert.NoError(t, p.Gather(acc))
+			assert.NoError(t, acc.FirstError())
+
+			for _, metric := range acc.Metrics {
+				delete(metric.Tags, "database_path")
+			}
+
+			autogold.ExpectFile(t, acc.Metrics, autogold.Name("TestPlugin_GatherFixtures/"+name))
+		})
+	}
+}
+
+func TestPlugin_Backfill(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	// Gather everything first so that the state would skip every row.
+	assert.NoError(t, p.Gather(new(telegraftest.Accumulator)))
+	state := p.GetState()
+
+	from := time.Unix(1725785700, 0)
+	to := time.Unix(1725816156, 0)
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Backfill(acc, BackfillOptions{From: from, To: to}))
+	assert.Equal(t, 8, len(acc.Metrics), "unexpected number of backfilled metrics")
+
+	for _, metric := range acc.Metrics {
+		if metric.Time.Before(from) || !metric.Time.Before(to) {
+			t.Errorf("metric %s at %s is outside of the backfill range", metric.Measurement, metric.Time)
+		}
+	}
+
+	assert.Equal(t, state, p.GetState(), "state changed by backfill")
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Backfill(acc, BackfillOptions{From: from, To: to, Tables: []string{"BATTERY_*"}}))
+	assert.Equal(t, 2, len(acc.Metrics), "unexpected number of backfilled battery metrics")
+	for _, metric := range acc.Metrics {
+		assert.Equal(t, "battery_level", metric.Measurement)
+	}
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Backfill(acc, BackfillOptions{Tables: []string{"BATTERY_*"}, Limit: 3}))
+	var times []int64
+	for _, metric := range acc.Metrics {
+		times = append(times, metric.Time.Unix())
+	}
+	assert.Equal(t, []int64{1725840846, 1725841618, 1725842806}, times, "not the newest rows in order")
+}
+
+func TestPlugin_GatherTableErrors(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE EXTRA_SAMPLE (TIMESTAMP INTEGER NOT NULL, DEVICE_ID INTEGER, VALUE INTEGER);
+		INSERT INTO EXTRA_SAMPLE VALUES (1725790192, 1, 10);
+		INSERT INTO EXTRA_SAMPLE VALUES (1725790252, NULL, 20);
+		INSERT INTO EXTRA_SAMPLE VALUES (1725790312, 1, 30);
+		CREATE TABLE GONE_SAMPLE (TIMESTAMP INTEGER NOT NULL, DEVICE_ID INTEGER, VALUE INTEGER);
+		CREATE VIEW BROKEN_SAMPLE AS SELECT * FROM GONE_SAMPLE;
+		DROP TABLE GONE_SAMPLE;
+	`)
+
+	columns := TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"DEVICE_ID"}, Fields: []string{"VALUE"}}
+
+	log := new(telegraftest.CaptureLogger)
+	p := &Plugin{
+		DatabasePaths: []string{dbPath},
+		ExtraTables: []TableDescription{
+			{Name: "EXTRA_SAMPLE", Columns: columns},
+			{Name: "MISSING_SAMPLE", Columns: columns},
+			{Name: "BROKEN_SAMPLE", Columns: columns},
+		},
+		MissingTableBehavior: MissingTableWarn,
+		Log:                  log,
+	}
+	assert.NoError(t, p.Init())
+
+	// Neither the missing table, the table that can't be queried nor the row
+	// with a NULL tag fail the gather.
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.Equal(t, 1, len(acc.Errors), "unexpected errors: %v", acc.Errors)
+
+	var values []any
+	tableErrors := make(map[string]any)
+	for _, metric := range acc.Metrics {
+		switch metric.Measurement {
+		case "extra_sample":
+			values = append(values, metric.Fields["value"])
+		case errorsMeasurement:
+			tableErrors[metric.Tags["table"]+" "+metric.Tags["class"]] = metric.Fields["count"]
+		}
+	}
+	assert.Equal(t, []any{int64(10), int64(30)}, values)
+	assert.Equal(t, map[string]any{
+		"EXTRA_SAMPLE dropped_rows":    1,
+		"MISSING_SAMPLE missing_table": 1,
+		"BROKEN_SAMPLE query":          1,
+	}, tableErrors)
+
+	warnings := log.Warnings()
+	assert.Equal(t, 2, len(warnings), "unexpected warnings: %q", warnings)
+	assert.Contains(t, warnings[0], `Dropped 1 unreadable rows from table "EXTRA_SAMPLE"`)
+	assert.Contains(t, warnings[1], `Skipping table "MISSING_SAMPLE"`)
+}
+
+func TestPlugin_ExtraTableJoins(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE EXTRA_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, KIND INTEGER, VALUE INTEGER);
+		INSERT INTO EXTRA_SAMPLE VALUES (1, 1, 1, 10), (2, 1, 2, 20), (3, 2, 3, 30);
+		CREATE TABLE SAMPLE_KIND (_id INTEGER, NAME TEXT, TIMESTAMP INTEGER);
+		INSERT INTO SAMPLE_KIND VALUES (1, 'walk', 0), (2, 'run', 0);
+	`)
+
+	p := &Plugin{
+		DatabasePaths: []string{dbPath},
+		ExtraTables: []TableDescription{{
+			Name:    "EXTRA_SAMPLE",
+			Columns: TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"DEVICE_ID"}, Fields: []string{"VALUE"}},
+			Joins: []TableJoin{
+				{Name: "DEVICE", On: map[string]string{"DEVICE_ID": "_id"}, Tags: []string{"NAME"}},
+				{Name: "SAMPLE_KIND", On: map[string]string{"KIND": "_id"}, Tags: []string{"NAME"}},
+			},
+		}},
+		Log: telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+	assert.Equal(t, 0, len(p.validateDatabase(dbPath, false)))
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	// Rows without a match leave out the tags of the join.
+	var tags []string
+	for _, metric := range acc.Metrics {
+		if metric.Measurement == "extra_sample" {
+			tags = append(tags, metric.Tags["device_name"]+" "+metric.Tags["sample_kind_name"])
+		}
+	}
+	assert.Equal(t, []string{"Hybrid HR walk", "Hybrid HR run", " "}, tags)
+
+	p = &Plugin{
+		DatabasePaths: []string{dbPath},
+		ExtraTables: []TableDescription{{
+			Name:    "EXTRA_SAMPLE",
+			Columns: TableColumns{Timestamp: "TIMESTAMP"},
+			Joins:   []TableJoin{{Name: "SAMPLE_KIND", On: map[string]string{"KIND": "MISSING"}, Tags: []string{"NAME"}}},
+		}},
+		Log: telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+	assert.Equal(t, 1, len(p.validateDatabase(dbPath, false)))
+
+	p.ExtraTables[0].Joins[0].Tags = nil
+	assert.Error(t, p.Init())
+}
+
+func TestPlugin_GatherTableStats(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, GatherTableStats: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	tableStats := func(acc *telegraftest.Accumulator) map[string]*telegraftest.Metric {
+		stats := make(map[string]*telegraftest.Metric)
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == tableStatsMeasurement {
+				stats[metric.Tags["table"]+" "+metric.Tags["device_id"]] = metric
+			}
+		}
+		return stats
+	}
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	stats := tableStats(acc)
+	assert.Equal(t, 2, len(stats), "unexpected table stats: %v", stats)
+	assert.Equal(t, 10, stats["BATTERY_LEVEL 1"].Fields["rows_read"])
+
+	// The newest battery level of the test database is from 2024-09-09.
+	lag := time.Since(time.Unix(1725842806, 0))
+	assert.True(t, stats["BATTERY_LEVEL 1"].Fields["lag_seconds"].(int64) >= int64(lag.Seconds()), "lag too small")
+
+	// Lag is still reported when no new rows were read.
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	stats = tableStats(acc)
+	assert.Equal(t, 2, len(stats), "unexpected table stats: %v", stats)
+	assert.Equal(t, 0, stats["BATTERY_LEVEL 1"].Fields["rows_read"])
+}
+
+func TestPlugin_GatherFreshness(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO HYBRID_HRACTIVITY_SAMPLE VALUES(1725900000,2,1,0,0,55,76,4,0,0,71);
+	`)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, GatherFreshness: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	stat, err := os.Stat(dbPath)
+	assert.NoError(t, err)
+
+	// The freshness is still reported when no new rows were read.
+	for range 2 {
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		newest := make(map[string]any)
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == freshnessMeasurement {
+				newest[metric.Tags["device_id"]] = metric.Fields["newest_sample_time"]
+				assert.Equal(t, stat.ModTime().Unix(), metric.Fields["file_mtime"].(int64))
+			}
+		}
+
+		// Device 1's newest sample is a battery level, while device 2 only
+		// has a single heart rate sample.
+		assert.Equal(t, map[string]any{
+			"1": int64(1725842806),
+			"2": int64(1725900000),
+		}, newest)
+	}
+}
+
+func TestPlugin_GatherDeviceInventory(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO DEVICE VALUES(2,'Mi Band 7','Xiaomi','11:11:11:11:11:11',0,'MIBAND7',NULL,NULL,NULL);
+		INSERT INTO BATTERY_LEVEL VALUES(1725900000,1,50,0);
+	`)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, GatherDeviceInventory: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	inventory := make(map[string]map[string]any)
+	for _, metric := range acc.Metrics {
+		if metric.Measurement == inventoryMeasurement {
+			inventory[metric.Tags["device_model"]] = metric.Fields
+		}
+	}
+
+	// The newest battery level is 50%, up from 14% a day before.
+	assert.Equal(t, map[string]map[string]any{
+		"Q Hybrid": {
+			"samples":            int64(21),
+			"last_seen":          int64(1725900000),
+			"battery_level":      int64(50),
+			"battery_change_24h": int64(36),
+		},
+		"Mi Band 7": {
+			"samples": int64(0),
+		},
+	}, inventory)
+}
+
+func TestPlugin_GatherUserProfiles(t *testing.T) {
+	birthday := time.Now().AddDate(-30, 0, -1)
+	dbPath := newTestDB(t, gadgetbridgeDump+fmt.Sprintf(`
+		UPDATE USER SET BIRTHDAY = %d, GENDER = 0 WHERE _id = 1;
+		INSERT INTO USER VALUES (2, 'unborn', 'unknown', 2);
+	`, birthday.UnixMilli()))
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, GatherUserProfiles: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	// The user whose birthday can't be read doesn't keep the other from
+	// being gathered.
+	var profiles []*telegraftest.Metric
+	var dropped any
+	for _, metric := range acc.Metrics {
+		switch metric.Measurement {
+		case userProfileMeasurement:
+			delete(metric.Tags, "database_path")
+			profiles = append(profiles, metric)
+		case errorsMeasurement:
+			if metric.Tags["table"] == "USER" && metric.Tags["class"] == string(errorDroppedRows) {
+				dropped = metric.Fields["count"]
+			}
+		}
+	}
+	assert.Equal(t, any(1), dropped)
+	assert.Equal(t, 1, len(profiles))
+	assert.Equal(t, map[string]string{"user_id": "1", "user": "gadgetbridge-user", "gender": "female"}, profiles[0].Tags)
+	assert.Equal(t, map[string]any{
+		"birthday":                 birthday.Unix(),
+		"age_years":                int64(30),
+		"estimated_max_heart_rate": int64(190),
+	}, profiles[0].Fields)
+}
+
+func TestAgeAt(t *testing.T) {
+	birthday := time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, int64(33), ageAt(birthday, time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, int64(34), ageAt(birthday, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, int64(34), ageAt(birthday, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestPlugin_GatherHeartbeat(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		PRAGMA user_version = 42;
+	`)
+	missingPath := filepath.Join(t.TempDir(), "missing.db")
+
+	p := &Plugin{
+		DatabasePaths:           []string{dbPath, missingPath},
+		GatherHeartbeat:         true,
+		MissingDatabaseBehavior: MissingDatabaseIgnore,
+		Log:                     telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	stat, err := os.Stat(dbPath)
+	assert.NoError(t, err)
+
+	// Heartbeats are added even when there are no new rows.
+	for range 2 {
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		heartbeats := make(map[string]map[string]any)
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == heartbeatMeasurement {
+				heartbeats[metric.Tags["database_path"]] = metric.Fields
+			}
+		}
+
+		assert.Equal(t, map[string]map[string]any{
+			dbPath: {
+				"open":         true,
+				"size_bytes":   stat.Size(),
+				"file_mtime":   stat.ModTime().Unix(),
+				"user_version": int64(42),
+			},
+			missingPath: {"open": false},
+		}, heartbeats)
+	}
+}
+
+func TestPlugin_DeviceTags(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO HYBRID_HRACTIVITY_SAMPLE VALUES(1725900000,2,1,0,0,55,76,4,0,0,71);
+	`)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, DeviceTags: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	devices := make(map[string]string)
+	for _, metric := range acc.Metrics {
+		devices[metric.Tags["device_id"]] = strings.Join([]string{
+			metric.Tags["device_name"],
+			metric.Tags["device_type"],
+			metric.Tags["device_identifier"],
+			metric.Tags["device_manufacturer"],
+			metric.Tags["device_model"],
+		}, " ")
+	}
+
+	// Device 2 isn't in the DEVICE table, so it's left untagged.
+	assert.Equal(t, map[string]string{
+		"1": "Hybrid HR FOSSILQHYBRID 00:00:00:00:00:00 Fossil Q Hybrid",
+		"2": "    ",
+	}, devices)
+
+	// The devices are read again once the database changes.
+	db, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`
+		UPDATE DEVICE SET NAME = 'Renamed' WHERE _id = 1;
+		INSERT INTO BATTERY_LEVEL VALUES(1725900000,1,98,0);
+	`)
+	assert.NoError(t, err)
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(dbPath, future, future))
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.Equal(t, 1, len(acc.Metrics))
+	assert.Equal(t, "Renamed", acc.Metrics[0].Tags["device_name"])
+}
+
+func TestPlugin_UserTags(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, UserTags: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	users := make(map[string]int)
+	for _, metric := range acc.Metrics {
+		users[metric.Measurement+" "+metric.Tags["user"]]++
+		_, hasDevice := metric.Tags["device_name"]
+		assert.False(t, hasDevice, "device tagged without device_tags")
+	}
+
+	// Battery levels aren't recorded per user.
+	assert.Equal(t, map[string]int{
+		"hybrid_hractivity_sample gadgetbridge-user": 10,
+		"battery_level ": 10,
+	}, users)
+}
+
+func TestPlugin_FirmwareTags(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		UPDATE DEVICE_ATTRIBUTES SET VALID_TO_UTC = 1725820000000 WHERE _id = 1;
+		INSERT INTO DEVICE_ATTRIBUTES VALUES(2,'IV0.0.3.1r.v14',NULL,1725820000000,NULL,1,NULL);
+	`)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, FirmwareTags: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	// The heart rate samples predate the first recorded firmware.
+	firmware := make(map[string]int)
+	for _, metric := range acc.Metrics {
+		firmware[metric.Measurement+" "+metric.Tags["firmware_version"]+" "+metric.Tags["firmware_version2"]]++
+	}
+	assert.Equal(t, map[string]int{
+		"hybrid_hractivity_sample  ":       10,
+		"battery_level IV0.0.3.0r.v13 3.0": 5,
+		"battery_level IV0.0.3.1r.v14 ":    5,
+	}, firmware)
+}
+
+func TestPlugin_DeviceAliases(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO DEVICE VALUES(2,'Band','Xiaomi','aa:bb:cc:dd:ee:ff',0,'MIBAND4',NULL,NULL,NULL);
+		UPDATE BATTERY_LEVEL SET DEVICE_ID = 2 WHERE TIMESTAMP > 1725820000;
+	`)
+
+	p := &Plugin{
+		DatabasePaths: []string{dbPath},
+		DeviceAliases: map[string]string{
+			"1":                 "Watch",
+			"AA:BB:CC:DD:EE:FF": "Band",
+		},
+		Log: telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	aliases := make(map[string]int)
+	for _, metric := range acc.Metrics {
+		assert.Equal(t, "", metric.Tags["device_name"])
+		aliases[metric.Measurement+" "+metric.Tags["device_alias"]]++
+	}
+	assert.Equal(t, map[string]int{
+		"hybrid_hractivity_sample Watch": 10,
+		"battery_level Watch":            5,
+		"battery_level Band":             5,
+	}, aliases)
+}
+
+func TestPlugin_DevicesExclude(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO DEVICE VALUES(2,'Test Band','Xiaomi','aa:bb:cc:dd:ee:ff',0,'MIBAND4',NULL,NULL,NULL);
+		UPDATE BATTERY_LEVEL SET DEVICE_ID = 2 WHERE TIMESTAMP > 1725820000;
+	`)
+
+	gather := func(exclude ...string) map[string]int {
+		p := &Plugin{
+			DatabasePaths:         []string{dbPath},
+			DevicesExclude:        exclude,
+			GatherDeviceInventory: true,
+			Log:                   telegraftest.Logger{},
+		}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		devices := make(map[string]int)
+		for _, metric := range acc.Metrics {
+			devices[metric.Measurement+" "+metric.Tags["device_id"]]++
+		}
+		return devices
+	}
+
+	withoutBand := map[string]int{
+		"hybrid_hractivity_sample 1": 10,
+		"battery_level 1":            5,
+		"gadgetbridge_device 1":      1,
+	}
+	assert.Equal(t, withoutBand, gather("2"))
+	assert.Equal(t, withoutBand, gather("Test *"))
+	assert.Equal(t, withoutBand, gather("AA:BB:CC:DD:EE:FF"))
+	assert.Equal(t, map[string]int{
+		"battery_level 2":       5,
+		"gadgetbridge_device 2": 1,
+	}, gather("Hybrid HR"))
+}
+
+func TestPlugin_UsersFilter(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO USER VALUES(2,'other-user',0,2);
+		UPDATE HYBRID_HRACTIVITY_SAMPLE SET USER_ID = 2 WHERE TIMESTAMP > 1725785700;
+	`)
+
+	gather := func(include, exclude []string) map[string]int {
+		p := &Plugin{
+			DatabasePaths: []string{dbPath},
+			UsersInclude:  include,
+			UsersExclude:  exclude,
+			Log:           telegraftest.Logger{},
+		}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		users := make(map[string]int)
+		for _, metric := range acc.Metrics {
+			users[metric.Measurement+" "+metric.Tags["user_id"]]++
+		}
+		return users
+	}
+
+	// Battery levels have no user, so they're always gathered.
+	assert.Equal(t, map[string]int{
+		"hybrid_hractivity_sample 1": 5,
+		"battery_level ":             10,
+	}, gather([]string{"gadgetbridge-*"}, nil))
+	assert.Equal(t, map[string]int{
+		"hybrid_hractivity_sample 2": 5,
+		"battery_level ":             10,
+	}, gather(nil, []string{"1"}))
+}
+
+func TestPlugin_LookupFiles(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "rooms.csv")
+	assert.NoError(t, os.WriteFile(csvPath, []byte("device_id,room,floor\n1,bedroom,\n2,kitchen,1\n"), 0o644))
+	tomlPath := filepath.Join(dir, "owners.toml")
+	assert.NoError(t, os.WriteFile(tomlPath, []byte("[device_alias.Watch]\nowner = \"alice\"\n"), 0o644))
+
+	p := &Plugin{
+		DatabasePaths: []string{dbPath},
+		DeviceAliases: map[string]string{"1": "Watch"},
+		LookupFiles:   []string{csvPath, tomlPath},
+		Log:           telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	// Empty values aren't added, and the tags of the identities can be looked
+	// up too.
+	for _, metric := range acc.Metrics {
+		assert.Equal(t, "bedroom", metric.Tags["room"])
+		assert.Equal(t, "alice", metric.Tags["owner"])
+		_, hasFloor := metric.Tags["floor"]
+		assert.False(t, hasFloor)
+	}
+
+	p.LookupFiles = []string{filepath.Join(dir, "rooms.json")}
+	assert.Error(t, p.Init())
+}
+
+func TestPlugin_GatherUserAttributes(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		UPDATE USER_ATTRIBUTES SET VALID_TO_UTC = 1725900000000 WHERE _id = 1;
+		INSERT INTO USER_ATTRIBUTES VALUES(2,175,68,NULL,10000,1725900000000,NULL,1);
+		INSERT INTO USER_ATTRIBUTES VALUES(3,180,80,8,8000,NULL,NULL,1);
+	`)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, GatherUserAttributes: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	attributes := func(acc *telegraftest.Accumulator) []*telegraftest.Metric {
+		var metrics []*telegraftest.Metric
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == userAttributesMeasurement {
+				delete(metric.Tags, "database_path")
+				metrics = append(metrics, metric)
+			}
+		}
+		return metrics
+	}
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	// The attributes without a start of their validity are left out.
+	metrics := attributes(acc)
+	assert.Equal(t, 2, len(metrics))
+	assert.Equal(t, time.UnixMilli(1725816157049), metrics[0].Time)
+	assert.Equal(t, map[string]any{
+		"height_cm":        int64(175),
+		"weight_kg":        int64(70),
+		"sleep_goal_hours": int64(7),
+		"steps_goal":       int64(8000),
+		"valid_to":         int64(1725900000),
+	}, metrics[0].Fields)
+	assert.Equal(t, map[string]any{
+		"height_cm":  int64(175),
+		"weight_kg":  int64(68),
+		"steps_goal": int64(10000),
+	}, metrics[1].Fields)
+	assert.Equal(t, map[string]string{"user_id": "1"}, metrics[1].Tags)
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.Equal(t, 0, len(attributes(acc)), "user attributes gathered again")
+}
+
+func TestPlugin_GatherActivitySummaries(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO BASE_ACTIVITY_SUMMARY (_id, NAME, START_TIME, END_TIME, ACTIVITY_KIND, DEVICE_ID, USER_ID)
+		VALUES (1, 'Morning run', 1725786000000, 1725787800000, 16, 1, 1), (2, NULL, 1725790000000, 1725790600000, 123456, 1, 1);
+	`)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, GatherActivitySummaries: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	summaries := func(acc *telegraftest.Accumulator) []*telegraftest.Metric {
+		var metrics []*telegraftest.Metric
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == activitySummaryMeasurement {
+				delete(metric.Tags, "database_path")
+				metrics = append(metrics, metric)
+			}
+		}
+		return metrics
+	}
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	// Unknown activity kinds keep their code as the sport.
+	metrics := summaries(acc)
+	assert.Equal(t, 2, len(metrics))
+	assert.Equal(t, time.UnixMilli(1725786000000), metrics[0].Time)
+	assert.Equal(t, map[string]any{
+		"duration_seconds": float64(1800),
+		"end_time":         int64(1725787800),
+	}, metrics[0].Fields)
+	assert.Equal(t, map[string]string{
+		"device_id":           "1",
+		"user_id":             "1",
+		"activity_id":         "1",
+		"activity_kind":       "16",
+		"activity_name":       "Morning run",
+		"sport":               "running",
+		"device_name":         "Hybrid HR",
+		"device_type":         "FOSSILQHYBRID",
+		"device_identifier":   "00:00:00:00:00:00",
+		"device_manufacturer": "Fossil",
+		"device_model":        "Q Hybrid",
+	}, metrics[0].Tags)
+	assert.Equal(t, "123456", metrics[1].Tags["sport"])
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.Equal(t, 0, len(summaries(acc)), "activity summaries gathered again")
+}
+
+func TestPlugin_GatherDeviceAttributes(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		UPDATE DEVICE_ATTRIBUTES SET VALID_TO_UTC = 1725820000000 WHERE _id = 1;
+		INSERT INTO DEVICE_ATTRIBUTES VALUES(2,'IV0.0.3.1r.v14',NULL,1725820000000,NULL,1,'DE:AD:BE:EF:00:01');
+		INSERT INTO DEVICE_ATTRIBUTES VALUES(3,'IV0.0.2.9r.v12',NULL,NULL,NULL,1,NULL);
+	`)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, GatherDeviceAttributes: true, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	attributes := func(acc *telegraftest.Accumulator) []*telegraftest.Metric {
+		var metrics []*telegraftest.Metric
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == deviceAttributesMeasurement {
+				delete(metric.Tags, "database_path")
+				metrics = append(metrics, metric)
+			}
+		}
+		return metrics
+	}
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	// The attributes without a start of their validity are left out.
+	metrics := attributes(acc)
+	assert.Equal(t, 2, len(metrics))
+	assert.Equal(t, time.UnixMilli(1725790000000), metrics[0].Time)
+	assert.Equal(t, map[string]any{
+		"version1": "IV0.0.3.0r.v13",
+		"version2": "3.0",
+		"valid_to": int64(1725820000),
+	}, metrics[0].Fields)
+	assert.Equal(t, map[string]any{
+		"version1":            "IV0.0.3.1r.v14",
+		"volatile_identifier": "DE:AD:BE:EF:00:01",
+	}, metrics[1].Fields)
+	assert.Equal(t, map[string]string{"device_id": "1"}, metrics[1].Tags)
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.Equal(t, 0, len(attributes(acc)), "device attributes gathered again")
+}
+
+func TestPlugin_GatherByDefault(t *testing.T) {
+	p := inputs.Inputs["gadgetbridge"]().(*Plugin)
+	assert.True(t, p.GatherUserAttributes)
+	assert.True(t, p.GatherActivitySummaries)
+	assert.True(t, p.GatherDeviceAttributes)
+
+	assert.NoError(t, toml.Unmarshal([]byte("gather_activity_summaries = false"), p))
+	assert.False(t, p.GatherActivitySummaries)
+}
+
+func TestLookupDeviceModel(t *testing.T) {
+	assert.Equal(t, deviceModel{"Xiaomi", "Mi Band 7"}, lookupDeviceModel("MIBAND7", "Xiaomi"))
+	assert.Equal(t, deviceModel{"Acme", "ACME_WATCH"}, lookupDeviceModel("ACME_WATCH", "Acme"))
+}
+
+func TestPlugin_TimestampPrecision(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	p := &Plugin{
+		DatabasePaths:      []string{dbPath},
+		TimestampPrecision: config.Duration(time.Minute),
+		Log:                telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.NotZero(t, len(acc.Metrics))
+
+	for _, metric := range acc.Metrics {
+		assert.Equal(t, metric.Time.Truncate(time.Minute), metric.Time, "timestamp of %s not truncated", metric.Measurement)
+	}
+
+	// The state keeps the exact timestamps, so that no row is gathered twice.
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestPlugin_LocalTime(t *testing.T) {
+	// Both rows were recorded at 12:00 on the wall clock of a device in
+	// Berlin, one hour ahead of UTC in winter.
+	wall := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Unix()
+	dbPath := newTestDB(t, gadgetbridgeDump+fmt.Sprintf(`
+		CREATE TABLE LOCAL_SAMPLE (TIMESTAMP INTEGER, UTC_OFFSET INTEGER, VALUE INTEGER);
+		INSERT INTO LOCAL_SAMPLE VALUES (%[1]d, 3600, 1), (%[1]d, NULL, 2);
+	`, wall))
+
+	p := &Plugin{
+		DatabasePaths:     []string{dbPath},
+		DatabaseTimezones: map[string]string{filepath.Join(filepath.Dir(dbPath), "*.db"): "Europe/Berlin"},
+		ExtraTables: []TableDescription{{
+			Name:    "LOCAL_SAMPLE",
+			Columns: TableColumns{Timestamp: "TIMESTAMP", Fields: []string{"VALUE"}, LocalTime: true, UTCOffset: "UTC_OFFSET"},
+		}},
+		Log: telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	utc := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Backfill(acc, BackfillOptions{From: utc, To: utc.Add(time.Second), Tables: []string{"LOCAL_SAMPLE"}}))
+	assert.Equal(t, 2, len(acc.Metrics))
+	for _, metric := range acc.Metrics {
+		assert.True(t, metric.Time.Equal(utc), "unexpected time %v", metric.Time)
+	}
+
+	p.DatabaseTimezones = map[string]string{dbPath: "Nowhere/Special"}
+	assert.Error(t, p.Init())
+
+	// The table's own timezone wins over the database's.
+	p.DatabaseTimezones = nil
+	p.ExtraTables[0].Columns.Timezone = "America/New_York"
+	assert.NoError(t, p.Init())
+	assert.Equal(t, "America/New_York", p.tableLocation("LOCAL_SAMPLE", dbPath).String())
+	assert.Equal(t, time.UTC, p.tableLocation("OTHER_SAMPLE", dbPath))
+
+	p.ExtraTables[0].Columns.LocalTime = false
+	p.ExtraTables[0].Columns.UTCOffset = ""
+	assert.Error(t, p.Init())
+}
+
+func TestLocalClock(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	clock := localClock{berlin}
+
+	// In 2024, Berlin's clocks sprang forward from 02:00 to 03:00 on March 31
+	// and fell back from 03:00 to 02:00 on October 27.
+	tests := []struct {
+		name string
+		wall time.Time
+		want time.Time
+	}{
+		{
+			name: "winter",
+			wall: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "summer",
+			wall: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "before gap",
+			wall: time.Date(2024, 3, 31, 1, 59, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 31, 0, 59, 0, 0, time.UTC),
+		},
+		{
+			name: "in gap",
+			wall: time.Date(2024, 3, 31, 2, 30, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "after gap",
+			wall: time.Date(2024, 3, 31, 3, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "before fold",
+			wall: time.Date(2024, 10, 27, 1, 59, 0, 0, time.UTC),
+			want: time.Date(2024, 10, 26, 23, 59, 0, 0, time.UTC),
+		},
+		{
+			name: "in fold",
+			wall: time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC),
+			want: time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "after fold",
+			wall: time.Date(2024, 10, 27, 3, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 10, 27, 2, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := clock.time(test.wall.Unix(), sql.NullInt64{})
+			assert.True(t, got.Equal(test.want), "got %v, want %v", got.UTC(), test.want)
+		})
+	}
+}
+
+func TestPlugin_NullTags(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE NULL_TAG_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, VALUE INTEGER);
+		INSERT INTO NULL_TAG_SAMPLE VALUES (1725785460, 1, 1), (1725785520, NULL, 2);
+	`)
+
+	gather := func(behavior NullTagsBehavior) (devices []string, dropped any) {
+		p := &Plugin{
+			DatabasePaths: []string{dbPath},
+			ExtraTables: []TableDescription{{
+				Name:    "NULL_TAG_SAMPLE",
+				Columns: TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"DEVICE_ID"}, Fields: []string{"VALUE"}, NullTags: behavior},
+			}},
+			Log: telegraftest.Logger{},
+		}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		for _, metric := range acc.Metrics {
+			switch metric.Measurement {
+			case "null_tag_sample":
+				deviceID, ok := metric.Tags["device_id"]
+				if !ok {
+					deviceID = "<none>"
+				}
+				devices = append(devices, deviceID)
+			case errorsMeasurement:
+				if metric.Tags["class"] == string(errorDroppedRows) {
+					dropped = metric.Fields["count"]
+				}
+			}
+		}
+		return devices, dropped
+	}
+
+	devices, dropped := gather("")
+	assert.Equal(t, []string{"1"}, devices)
+	assert.Equal(t, any(1), dropped)
+
+	devices, dropped = gather(NullTagsDropTag)
+	assert.Equal(t, []string{"1", "<none>"}, devices)
+	assert.Equal(t, nil, dropped)
+
+	devices, dropped = gather(NullTagsUnknown)
+	assert.Equal(t, []string{"1", "unknown"}, devices)
+	assert.Equal(t, nil, dropped)
+
+	p := &Plugin{
+		ExtraTables: []TableDescription{{Name: "NULL_TAG_SAMPLE", Columns: TableColumns{NullTags: "guess"}}},
+		Log:         telegraftest.Logger{},
+	}
+	assert.Error(t, p.Init())
+}
+
+func TestPlugin_TableReader(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE KIND_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, RAW_KIND INTEGER, STEPS INTEGER);
+		INSERT INTO KIND_SAMPLE VALUES
+			(1725785460000, 1, 1, 10),
+			(1725785520500, 1, 2, 20),
+			(1725785580000, 1, 'walking', 30),
+			(1725785640000, 1, NULL, 40);
+	`)
+
+	p := &Plugin{
+		DatabasePaths: []string{dbPath},
+		ExtraTables: []TableDescription{{
+			Name:    "KIND_SAMPLE",
+			Columns: TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"DEVICE_ID"}, Fields: []string{"STEPS"}},
+			Reader: gadgetbridgedb.KindReader{
+				ColumnReader: gadgetbridgedb.ColumnReader{Milliseconds: true},
+				Column:       "RAW_KIND",
+				Tag:          "kind",
+				Kinds:        map[int64]string{1: "sleep"},
+			},
+		}},
+		Log: telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	var got []string
+	var dropped any
+	for _, metric := range acc.Metrics {
+		switch metric.Measurement {
+		case "kind_sample":
+			got = append(got, fmt.Sprintf("%s kind=%q steps=%v", metric.Time.UTC().Format(time.RFC3339Nano), metric.Tags["kind"], metric.Fields["steps"]))
+		case errorsMeasurement:
+			if metric.Tags["class"] == string(errorDroppedRows) {
+				dropped = metric.Fields["count"]
+			}
+		}
+	}
+	assert.Equal(t, []string{
+		`2024-09-08T08:51:00Z kind="sleep" steps=10`,
+		`2024-09-08T08:52:00.5Z kind="2" steps=20`,
+		`2024-09-08T08:54:00Z kind="" steps=40`,
+	}, got)
+	assert.Equal(t, any(1), dropped, "the row whose kind isn't an integer")
+	assert.Equal(t, int64(1725785640000), p.state.LastTableTimes["KIND_SAMPLE"])
+
+	// The state is in milliseconds too, so nothing is gathered again.
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	for _, metric := range acc.Metrics {
+		assert.NotEqual(t, "kind_sample", metric.Measurement)
+	}
+}
+
+func TestRegisterTables(t *testing.T) {
+	builtin := knownTables
+	t.Cleanup(func() {
+		knownTables = builtin
+		ignoredPackTables = nil
+	})
+
+	pack := TablePack{
+		Name: "test",
+		Tables: []TableDescription{{
+			Name: "PACK_SAMPLE",
+			Columns: TableColumns{
+				Timestamp: "TIMESTAMP",
+				Tags:      []string{"DEVICE_ID"},
+				Fields:    []string{"VALUE"},
+				Sentinels: map[string][]int64{"VALUE": {-1}},
+			},
+		}},
+	}
+	RegisterTables(pack)
+
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE PACK_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, VALUE INTEGER);
+		INSERT INTO PACK_SAMPLE VALUES (1725785460, 1, 5), (1725785520, 1, -1);
+	`)
+
+	gather := func(keepSentinels bool) (values []any) {
+		p := &Plugin{DatabasePaths: []string{dbPath}, KeepSentinels: keepSentinels, Log: telegraftest.Logger{}}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == "pack_sample" {
+				values = append(values, metric.Fields["value"])
+			}
+		}
+		return values
+	}
+	assert.Equal(t, []any{int64(5)}, gather(false))
+	assert.Equal(t, []any{int64(5), int64(-1)}, gather(true))
+
+	// Packs written before the plugin came to gather their tables keep
+	// working, with the pack's description ignored.
+	RegisterTables(TablePack{Name: "older", Tables: []TableDescription{{
+		Name:    "PACK_SAMPLE",
+		Columns: TableColumns{Timestamp: "TIMESTAMP", Fields: []string{"VALUE"}},
+	}}})
+	log := new(telegraftest.CaptureLogger)
+	p := &Plugin{DatabasePaths: []string{dbPath}, Log: log}
+	assert.NoError(t, p.Init())
+	assert.Equal(t, 1, len(warnings(log)), "unexpected warnings: %q", warnings(log))
+	assert.Contains(t, warnings(log)[0], `Table "PACK_SAMPLE" of table pack "older"`)
+	assert.Equal(t, []any{int64(5)}, gather(false))
+
+	assert.Panics(t, func() {
+		RegisterTables(TablePack{Name: "invalid", Tables: []TableDescription{{Name: "INVALID_SAMPLE"}}})
+	})
+}
+
+// TestPlugin_UpgradedExtraTables gathers with a config generated before some
+// of its extra tables were gathered by default, which must keep working with
+// the built-in descriptions of those tables.
+// TestPlugin_JoinedIdentities gathers the Huawei workout samples, whose device
+// and user are joined from their workout, which must be filtered and counted by
+// them like the samples of other tables.
+func TestPlugin_JoinedIdentities(t *testing.T) {
+	sql, err := os.ReadFile("testdata/fixtures/huawei.sql")
+	assert.NoError(t, err)
+	dbPath := newTestDB(t, string(sql))
+
+	gather := func(p *Plugin) []*telegraftest.Metric {
+		p.DatabasePaths = []string{dbPath}
+		p.Log = telegraftest.Logger{}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+		assert.NoError(t, acc.FirstError())
+		return acc.Metrics
+	}
+
+	for _, metric := range gather(&Plugin{UsersExclude: []string{"gadgetbridge-user"}}) {
+		assert.NotEqual(t, "huawei_workout_data_sample", metric.Measurement, "workout of excluded user gathered")
+	}
+	for _, metric := range gather(&Plugin{DevicesExclude: []string{"1"}}) {
+		assert.NotEqual(t, "huawei_workout_data_sample", metric.Measurement, "workout of excluded device gathered")
+	}
+
+	var stats []map[string]string
+	for _, metric := range gather(&Plugin{GatherTableStats: true}) {
+		if metric.Measurement == tableStatsMeasurement && metric.Tags["table"] == "HUAWEI_WORKOUT_DATA_SAMPLE" {
+			stats = append(stats, metric.Tags)
+			assert.Equal[any](t, 2, metric.Fields["rows_read"])
+		}
+	}
+	assert.Equal(t, 1, len(stats), "unexpected table stats: %v", stats)
+	assert.Equal(t, "1", stats[0]["device_id"])
+}
+
+func TestPlugin_UpgradedExtraTables(t *testing.T) {
+	sql, err := os.ReadFile("testdata/fixtures/garmin.sql")
+	assert.NoError(t, err)
+	dbPath := newTestDB(t, string(sql))
+
+	// As generate-config wrote GARMIN_STRESS_SAMPLE before it was known that
+	// its timestamps are in milliseconds.
+	var config struct {
+		ExtraTables []TableDescription `toml:"extra_tables"`
+	}
+	assert.NoError(t, toml.Unmarshal([]byte(`
+[[extra_tables]]
+  table = "GARMIN_STRESS_SAMPLE"
+  [extra_tables.columns]
+    timestamp = "TIMESTAMP"
+    tags = ["DEVICE_ID", "USER_ID"]
+    fields = ["STRESS"]
+
+[[extra_tables]]
+  table = "GARMIN_SPO2_SAMPLE"
+  milliseconds = true
+  [extra_tables.columns]
+    timestamp = "TIMESTAMP"
+    tags = ["DEVICE_ID", "USER_ID"]
+    fields = ["SPO2"]
+`), &config))
+
+	log := new(telegraftest.CaptureLogger)
+	p := &Plugin{DatabasePaths: []string{dbPath}, ExtraTables: config.ExtraTables, Log: log}
+	assert.NoError(t, p.Init())
+
+	assert.Equal(t, 1, len(warnings(log)), "unexpected warnings: %q", warnings(log))
+	assert.Contains(t, warnings(log)[0], `Extra table "GARMIN_STRESS_SAMPLE"`)
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.NoError(t, acc.FirstError())
+
+	var stress, spo2 int
+	for _, metric := range acc.Metrics {
+		switch metric.Measurement {
+		case "garmin_stress_sample":
+			stress++
+			assert.Equal(t, time.UnixMilli(1725807600000), metric.Time)
+			// The built-in description drops the sentinel of -1.
+			assert.Equal[any](t, int64(27), metric.Fields["stress"])
+		case "garmin_spo2_sample":
+			spo2++
+		}
+	}
+	assert.Equal(t, 1, stress)
+	assert.NotEqual(t, 0, spo2)
+}
+
+func warnings(log *telegraftest.CaptureLogger) []string {
+	var texts []string
+	for _, entry := range log.Messages() {
+		if entry.Level == 'W' {
+			texts = append(texts, entry.Text)
+		}
+	}
+	return texts
+}
+
+func TestPlugin_UnknownColumns(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		ALTER TABLE BATTERY_LEVEL ADD COLUMN VOLTAGE INTEGER;
+		ALTER TABLE BATTERY_LEVEL ADD COLUMN NOTE TEXT;
+		UPDATE BATTERY_LEVEL SET VOLTAGE = 3700, NOTE = 'charging';
+	`)
+
+	gather := func(gatherUnknown bool) (infos []string, voltages int) {
+		log := new(telegraftest.CaptureLogger)
+		p := &Plugin{DatabasePaths: []string{dbPath}, GatherUnknownColumns: gatherUnknown, Log: log}
+		assert.NoError(t, p.Init())
+
+		for range 2 {
+			acc := new(telegraftest.Accumulator)
+			assert.NoError(t, p.Gather(acc))
+			for _, metric := range acc.Metrics {
+				if _, ok := metric.Fields["voltage"]; ok {
+					voltages++
+				}
+				_, ok := metric.Fields["note"]
+				assert.False(t, ok, "text column gathered")
+			}
+		}
+
+		// The other known tables of the dump, which is of an older
+		// Gadgetbridge, are logged as well.
+		for _, entry := range log.Messages() {
+			if entry.Level == 'I' && strings.Contains(entry.Text, `"BATTERY_LEVEL"`) {
+				infos = append(infos, entry.Text)
+			}
+		}
+		return infos, voltages
+	}
+
+	infos, voltages := gather(false)
+	assert.Equal(t, 0, voltages)
+	assert.Equal(t, 1, len(infos), "unexpected infos: %q", infos)
+	assert.Contains(t, infos[0], `Table "BATTERY_LEVEL"`)
+	assert.Contains(t, infos[0], "VOLTAGE, NOTE")
+
+	infos, voltages = gather(true)
+	assert.Equal(t, 10, voltages)
+	assert.Equal(t, 1, len(infos), "unexpected infos: %q", infos)
+}
+
+func TestPlugin_GatherSessions(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO BASE_ACTIVITY_SUMMARY (_id, NAME, START_TIME, END_TIME, ACTIVITY_KIND, DEVICE_ID, USER_ID)
+		VALUES (1, 'Morning run', 1725786000000, 1725787800000, 16, 1, 1);
+
+		CREATE TABLE SLEEP_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, RAW_KIND INTEGER);
+		INSERT INTO SLEEP_SAMPLE VALUES
+			(1725746400, 1, 4),
+			(1725746460, 1, 5),
+			(1725746520, 1, 1),
+			(1725750000, 1, 1),
+			(1725753600, 1, 4);
+	`)
+
+	p := &Plugin{
+		DatabasePaths:  []string{dbPath},
+		GatherSessions: true,
+		ExtraTables: []TableDescription{{
+			Name: "SLEEP_SAMPLE",
+			Columns: TableColumns{
+				Timestamp: "TIMESTAMP",
+				Tags:      []string{"DEVICE_ID"},
+				Fields:    []string{"RAW_KIND"},
+			},
+		}},
+		SleepSessions: []SleepSessionTable{{
+			Table:      "SLEEP_SAMPLE",
+			KindColumn: "RAW_KIND",
+			Kinds:      []int64{4, 5},
+			MaxGap:     config.Duration(30 * time.Minute),
+		}},
+		Log: telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	sessions := func() []*telegraftest.Metric {
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		var metrics []*telegraftest.Metric
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == sessionMeasurement {
+				delete(metric.Tags, "database_path")
+				metrics = append(metrics, metric)
+			}
+		}
+		return metrics
+	}
+
+	// The last session hasn't ended, so it's left to a later gather.
+	metrics := sessions()
+	assert.Equal(t, 2, len(metrics))
+
+	assert.Equal(t, time.UnixMilli(1725786000000), metrics[0].Time)
+	assert.Equal(t, map[string]string{
+		"device_id":    "1",
+		"user_id":      "1",
+		"session_type": "workout",
+		"session_id":   "workout-1",
+		"sport":        "running",
+	}, metrics[0].Tags)
+	assert.Equal(t, map[string]any{
+		"start_time":       int64(1725786000),
+		"end_time":         int64(1725787800),
+		"duration_seconds": float64(1800),
+	}, metrics[0].Fields)
+
+	assert.Equal(t, time.Unix(1725746400, 0), metrics[1].Time)
+	assert.Equal(t, "sleep", metrics[1].Tags["session_type"])
+	assert.Equal(t, "1", metrics[1].Tags["device_id"])
+	assert.Equal(t, map[string]any{
+		"start_time":       int64(1725746400),
+		"end_time":         int64(1725746520),
+		"duration_seconds": float64(120),
+		"asleep_seconds":   float64(120),
+	}, metrics[1].Fields)
+	nightID := metrics[1].Tags["session_id"]
+
+	assert.Equal(t, 0, len(sessions()), "sessions gathered again")
+
+	db, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO SLEEP_SAMPLE VALUES (1725753660, 1, 5), (1725757200, 1, 1)")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	metrics = sessions()
+	assert.Equal(t, 1, len(metrics))
+	assert.Equal(t, time.Unix(1725753600, 0), metrics[0].Time)
+	assert.Equal[any](t, float64(120), metrics[0].Fields["asleep_seconds"])
+	assert.NotEqual(t, nightID, metrics[0].Tags["session_id"])
+
+	assert.Equal(t, 0, len(sessions()), "sessions gathered again")
+}
+
+func TestPlugin_MissingColumns(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		ALTER TABLE HYBRID_HRACTIVITY_SAMPLE DROP COLUMN MAX_VARIABILITY;
+		ALTER TABLE HYBRID_HRACTIVITY_SAMPLE DROP COLUMN HEARTRATE_QUALITY;
+	`)
+
+	log := new(telegraftest.CaptureLogger)
+	p := &Plugin{DatabasePaths: []string{dbPath}, Strict: true, Log: log}
+	assert.NoError(t, p.Init())
+
+	for i := range 2 {
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+		var samples int
+		for _, metric := range acc.Metrics {
+			if metric.Measurement != "hybrid_hractivity_sample" {
+				continue
+			}
+			samples++
+			_, ok := metric.Fields["max_variability"]
+			assert.False(t, ok, "missing column gathered")
+			_, ok = metric.Fields["steps"]
+			assert.True(t, ok, "existing column not gathered")
+		}
+		if i == 0 {
+			assert.Equal(t, 10, samples)
+		}
+	}
+
+	var infos []string
+	for _, entry := range log.Messages() {
+		if entry.Level == 'I' && strings.Contains(entry.Text, `"HYBRID_HRACTIVITY_SAMPLE"`) {
+			infos = append(infos, entry.Text)
+		}
+	}
+	assert.Equal(t, 1, len(infos), "unexpected infos: %q", infos)
+	assert.Contains(t, infos[0], `Table "HYBRID_HRACTIVITY_SAMPLE"`)
+	assert.Contains(t, infos[0], "MAX_VARIABILITY, HEARTRATE_QUALITY")
+}
+
+func TestPlugin_InvalidExtraTables(t *testing.T) {
+	columns := TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"DEVICE_ID"}, Fields: []string{"VALUE"}}
+
+	tests := []struct {
+		name   string
+		tables []TableDescription
+		err    string
+	}{
+		{
+			name:   "missing name",
+			tables: []TableDescription{{Columns: columns}},
+			err:    "extra table #1 is missing its table name",
+		},
+		{
+			name:   "duplicate table",
+			tables: []TableDescription{{Name: "SAMPLE", Columns: columns}, {Name: "SAMPLE", Columns: columns}},
+			err:    `extra table "SAMPLE" is listed more than once`,
+		},
+		{
+			name:   "missing timestamp",
+			tables: []TableDescription{{Name: "SAMPLE", Columns: TableColumns{Fields: []string{"VALUE"}}}},
+			err:    `invalid extra table "SAMPLE": columns: missing timestamp`,
+		},
+		{
+			name:   "duplicate field",
+			tables: []TableDescription{{Name: "SAMPLE", Columns: TableColumns{Timestamp: "TIMESTAMP", Fields: []string{"VALUE", "VALUE"}}}},
+			err:    `invalid extra table "SAMPLE": columns: fields: column "VALUE" is listed more than once`,
+		},
+		{
+			name:   "tag and field",
+			tables: []TableDescription{{Name: "SAMPLE", Columns: TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"VALUE"}, Fields: []string{"VALUE"}}}},
+			err:    `invalid extra table "SAMPLE": columns: column "VALUE" is listed in both tags and fields`,
+		},
+		{
+			name:   "sentinels of tag",
+			tables: []TableDescription{{Name: "SAMPLE", Columns: TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"VALUE"}, Sentinels: map[string][]int64{"VALUE": {0}}}}},
+			err:    `invalid extra table "SAMPLE": columns: sentinels: column "VALUE" isn't listed in fields`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &Plugin{ExtraTables: test.tables, Log: telegraftest.Logger{}}
+			err := p.Init()
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
+
+// FuzzPlugin_ExtraTables gathers with extra tables parsed from arbitrary
+// configs, which must either fail Init or gather without failing.
+func FuzzPlugin_ExtraTables(f *testing.F) {
+	f.Add(`[[extra_tables]]
+table = "HUAMI_STRESS_SAMPLE"
+[extra_tables.columns]
+timestamp = "TIMESTAMP"
+tags = ["DEVICE_ID", "USER_ID"]
+fields = ["STRESS"]
+`)
+	f.Add(`[[extra_tables]]
+table = "HUAMI_HEART_RATE_RESTING_SAMPLE"
+[extra_tables.columns]
+timestamp = "TIMESTAMP"
+fields = ["HEART_RATE"]
+local_time = true
+utc_offset = "UTC_OFFSET"
+sentinels = { HEART_RATE = [0, 255] }
+[[extra_tables.joins]]
+table = "DEVICE"
+on = { DEVICE_ID = "_id" }
+tags = ["NAME"]
+`)
+	f.Add(`[[extra_tables]]
+table = "MISSING_SAMPLE"
+[extra_tables.columns]
+timestamp = "NOPE"
+fields = ["NOPE"]
+`)
+	f.Add(`[[extra_tables]]
+table = ""
+`)
+
+	dbPath := newTestDB(f, gadgetbridgeDump)
+
+	f.Fuzz(func(t *testing.T, config string) {
+		// Only the extra tables are parsed, as other options could have the
+		// plugin listen on ports or write files.
+		var parsed struct {
+			ExtraTables []TableDescription `toml:"extra_tables"`
+		}
+		if err := toml.Unmarshal([]byte(config), &parsed); err != nil {
+			return
+		}
+
+		p := &Plugin{DatabasePaths: []string{dbPath}, ExtraTables: parsed.ExtraTables, Log: telegraftest.Logger{}}
+		if err := p.Init(); err != nil {
+			return
+		}
+		assert.NoError(t, p.Gather(new(telegraftest.Accumulator)))
+	})
+}
+
+func TestPlugin_Sentinels(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		UPDATE HYBRID_HRACTIVITY_SAMPLE SET HEART_RATE = 255 WHERE TIMESTAMP = 1725785460;
+		UPDATE HYBRID_HRACTIVITY_SAMPLE SET STEPS = 65535 WHERE TIMESTAMP = 1725785520;
+		UPDATE BATTERY_LEVEL SET LEVEL = -1;
+	`)
+
+	gather := func(keep bool) (heartRates, steps, batteryLevels int) {
+		p := &Plugin{DatabasePaths: []string{dbPath}, KeepSentinels: keep, Log: telegraftest.Logger{}}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		for _, metric := range acc.Metrics {
+			if _, ok := metric.Fields["heart_rate"]; ok {
+				heartRates++
+			}
+			if _, ok := metric.Fields["steps"]; ok {
+				steps++
+			}
+			if metric.Measurement == "battery_level" {
+				batteryLevels++
+			}
+		}
+		return heartRates, steps, batteryLevels
+	}
+
+	// Battery levels of nothing but sentinels aren't gathered at all.
+	heartRates, steps, batteryLevels := gather(false)
+	assert.Equal(t, 9, heartRates)
+	assert.Equal(t, 9, steps)
+	assert.Equal(t, 0, batteryLevels)
+
+	// The wrapped-around steps are still out of their plausible range.
+	heartRates, steps, batteryLevels = gather(true)
+	assert.Equal(t, 10, heartRates)
+	assert.Equal(t, 9, steps)
+	assert.Equal(t, 10, batteryLevels)
+}
+
+func TestPlugin_ImplausibleValues(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		UPDATE HYBRID_HRACTIVITY_SAMPLE SET STEPS = -20 WHERE TIMESTAMP = 1725785460;
+		UPDATE HYBRID_HRACTIVITY_SAMPLE SET STEPS = 40000 WHERE TIMESTAMP = 1725785520;
+	`)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	var steps int
+	tableErrors := make(map[string]any)
+	for _, metric := range acc.Metrics {
+		if _, ok := metric.Fields["steps"]; ok {
+			steps++
+		}
+		if metric.Measurement == errorsMeasurement {
+			tableErrors[metric.Tags["table"]+" "+metric.Tags["class"]] = metric.Fields["count"]
+		}
+	}
+	assert.Equal(t, 8, steps)
+	assert.Equal(t, map[string]any{"HYBRID_HRACTIVITY_SAMPLE implausible_value": 2}, tableErrors)
+}
+
+func TestPlugin_MistypedValues(t *testing.T) {
+	// SQLite keeps what doesn't fit a column's type as it's given, and keeps
+	// every value of VALUE, which has no declared type, as it's given.
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE MIXED_SAMPLE (TIMESTAMP INTEGER, STEPS INTEGER, LEVEL REAL, VALUE);
+		INSERT INTO MIXED_SAMPLE VALUES
+			(1725785460, 7, 7, 7),
+			(1725785520, '42', '42', '42'),
+			(1725785580, 1.5, 1.5, ' 1.5'),
+			(1725785640, 'n/a', 'n/a', 'n/a'),
+			(1725785700, x'01', x'01', x'01');
+	`)
+
+	p := &Plugin{
+		DatabasePaths: []string{dbPath},
+		ExtraTables: []TableDescription{{
+			Name:    "MIXED_SAMPLE",
+			Columns: TableColumns{Timestamp: "TIMESTAMP", Fields: []string{"STEPS", "LEVEL", "VALUE"}},
+		}},
+		Log: telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	values := make(map[string][]any)
+	tableErrors := make(map[string]any)
+	for _, metric := range acc.Metrics {
+		if metric.Measurement == "mixed_sample" {
+			for field, v := range metric.Fields {
+				values[field] = append(values[field], v)
+			}
+		}
+		if metric.Measurement == errorsMeasurement {
+			tableErrors[metric.Tags["table"]+" "+metric.Tags["class"]] = metric.Fields["count"]
+		}
+	}
+
+	// Each field has the type of its column, leaving out what doesn't fit.
+	assert.Equal(t, map[string][]any{
+		"steps": {int64(7), int64(42)},
+		"level": {7.0, 42.0, 1.5},
+		"value": {7.0, 42.0, 1.5},
+	}, values)
+	assert.Equal(t, map[string]any{"MIXED_SAMPLE mistyped_value": 7}, tableErrors)
+}
+
+func TestPlugin_TimestampBounds(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO BATTERY_LEVEL VALUES(0,1,50,0);
+		INSERT INTO BATTERY_LEVEL VALUES(4294967295,1,50,0);
+	`)
+
+	p := &Plugin{
+		DatabasePaths: []string{dbPath},
+		MinTimestamp:  "2010-01-01",
+		MaxFutureSkew: config.Duration(24 * time.Hour),
+		Log:           telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	var levels int
+	tableErrors := make(map[string]any)
+	for _, metric := range acc.Metrics {
+		switch metric.Measurement {
+		case "battery_level":
+			levels++
+		case errorsMeasurement:
+			tableErrors[metric.Tags["table"]+" "+metric.Tags["class"]] = metric.Fields["count"]
+		}
+	}
+	assert.Equal(t, 10, levels)
+	assert.Equal(t, map[string]any{"BATTERY_LEVEL bad_timestamp": 2}, tableErrors)
+
+	// The row from the future doesn't keep later rows from being gathered.
+	assert.Equal(t, int64(1725842806), p.state.LastTableTimes["BATTERY_LEVEL"])
+
+	p.MinTimestamp = "last week"
+	assert.Error(t, p.Init())
+}
+
+func TestPlugin_DuplicateRows(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE DUPLICATE_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, VALUE INTEGER);
+		INSERT INTO DUPLICATE_SAMPLE VALUES (1, 1, 5), (1, 1, 3), (1, 2, 1), (2, 1, 7);
+	`)
+
+	gather := func(behavior DuplicateRowsBehavior) []string {
+		p := &Plugin{
+			DatabasePaths: []string{dbPath},
+			ExtraTables: []TableDescription{{
+				Name:    "DUPLICATE_SAMPLE",
+				Columns: TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"DEVICE_ID"}, Fields: []string{"VALUE"}},
+			}},
+			DuplicateRows: behavior,
+			Log:           telegraftest.Logger{},
+		}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Backfill(acc, BackfillOptions{Tables: []string{"DUPLICATE_SAMPLE"}}))
+
+		var rows []string
+		for _, metric := range acc.Metrics {
+			rows = append(rows, fmt.Sprintf("%d %s %v", metric.Time.Unix(), metric.Tags["device_id"], metric.Fields["value"]))
+		}
+		return rows
+	}
+
+	assert.Equal(t, []string{"1 1 5", "1 1 3", "1 2 1", "2 1 7"}, gather(DuplicateRowsKeep))
+	assert.Equal(t, []string{"1 1 3", "1 2 1", "2 1 7"}, gather(DuplicateRowsLast))
+	assert.Equal(t, []string{"1 1 5", "1 2 1", "2 1 7"}, gather(DuplicateRowsMax))
+}
+
+func TestPlugin_LastTimestampRows(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE SHARED_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID INTEGER, VALUE INTEGER);
+		INSERT INTO SHARED_SAMPLE VALUES (1725785460, 1, 1), (1725785520, 2, 2), (1725785520, 1, 3);
+	`)
+
+	newPlugin := func() *Plugin {
+		p := &Plugin{
+			DatabasePaths: []string{dbPath},
+			ExtraTables: []TableDescription{{
+				Name:    "SHARED_SAMPLE",
+				Columns: TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"DEVICE_ID"}, Fields: []string{"VALUE"}},
+			}},
+			Log: telegraftest.Logger{},
+		}
+		assert.NoError(t, p.Init())
+		return p
+	}
+
+	gather := func(p *Plugin) []any {
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		var values []any
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == "shared_sample" {
+				values = append(values, metric.Fields["value"])
+			}
+		}
+		return values
+	}
+
+	p := newPlugin()
+	assert.Equal(t, []any{int64(1), int64(2), int64(3)}, gather(p))
+	assert.Equal(t, nil, gather(p))
+
+	// A row written at the last timestamp since is still gathered, once, even
+	// by a plugin that was restarted in between.
+	db, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO SHARED_SAMPLE VALUES (1725785520, 3, 4)")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	restarted := newPlugin()
+	assert.NoError(t, restarted.SetState(p.GetState()))
+	assert.Equal(t, []any{int64(4)}, gather(restarted))
+	assert.Equal(t, nil, gather(restarted))
+
+	// A state from before the rows were kept only gathers later rows.
+	old := newPlugin()
+	assert.NoError(t, old.SetState(pluginState{LastTableTimes: map[string]int64{"SHARED_SAMPLE": 1725785460}}))
+	assert.Equal(t, []any{int64(2), int64(3), int64(4)}, gather(old))
+}
+
+func TestPlugin_LastTimestampHistory(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		INSERT INTO DEVICE_ATTRIBUTES VALUES(2,'1.0',NULL,1726000000000,NULL,1,NULL);
+		INSERT INTO BASE_ACTIVITY_SUMMARY (_id, START_TIME, END_TIME, ACTIVITY_KIND, DEVICE_ID, USER_ID)
+		VALUES (1, 1726000000000, 1726000600000, 16, 1, 1);
+	`)
+
+	p := &Plugin{
+		DatabasePaths:           []string{dbPath},
+		GatherActivitySummaries: true,
+		GatherDeviceAttributes:  true,
+		GatherSessions:          true,
+		Log:                     telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	gather := func() map[string]int {
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+
+		counts := make(map[string]int)
+		for _, metric := range acc.Metrics {
+			switch metric.Measurement {
+			case deviceAttributesMeasurement, activitySummaryMeasurement, sessionMeasurement:
+				if metric.Time.Equal(time.UnixMilli(1726000000000)) {
+					counts[metric.Measurement]++
+				}
+			}
+		}
+		return counts
+	}
+
+	all := map[string]int{deviceAttributesMeasurement: 1, activitySummaryMeasurement: 1, sessionMeasurement: 1}
+	assert.Equal(t, all, gather())
+	assert.Equal(t, map[string]int{}, gather())
+
+	// The rows written since at the last time are still gathered, once.
+	db, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO DEVICE_ATTRIBUTES VALUES(3,'1.0',NULL,1726000000000,NULL,2,NULL);
+		INSERT INTO BASE_ACTIVITY_SUMMARY (_id, START_TIME, END_TIME, ACTIVITY_KIND, DEVICE_ID, USER_ID)
+		VALUES (2, 1726000000000, 1726000600000, 16, 2, 1);
+	`)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	assert.Equal(t, all, gather())
+	assert.Equal(t, map[string]int{}, gather())
+}
+
+func TestPlugin_ReadErrors(t *testing.T) {
+	// The big table fills the end of the file, which is overwritten as if the
+	// file were replaced while it's read.
+	dbPath := newTestDB(t, `
+		CREATE TABLE BIG_SAMPLE (TIMESTAMP INTEGER, VALUE INTEGER, PADDING TEXT);
+	`+gadgetbridgeDump+`
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 2000)
+		INSERT INTO BIG_SAMPLE SELECT 1725785460 + i, i, printf('%.200c', 'x') FROM n;
+	`)
+	f, err := os.OpenFile(dbPath, os.O_RDWR, 0)
+	assert.NoError(t, err)
+	stat, err := f.Stat()
+	assert.NoError(t, err)
+	_, err = f.WriteAt(bytes.Repeat([]byte{0xff}, 16*4096), stat.Size()-16*4096)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	log := new(telegraftest.CaptureLogger)
+	p := &Plugin{
+		DatabasePaths: []string{dbPath},
+		ExtraTables: []TableDescription{{
+			Name:    "BIG_SAMPLE",
+			Columns: TableColumns{Timestamp: "TIMESTAMP", Fields: []string{"VALUE"}},
+		}},
+		Log: log,
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	assert.Equal(t, 1, len(acc.Errors))
+	assert.Contains(t, acc.Errors[0].Error(), `table "BIG_SAMPLE"`)
+	assert.Contains(t, strings.Join(log.Warnings(), "\n"), `Retrying table "BIG_SAMPLE"`)
+
+	// The rows read before the failure aren't skipped by the next gather.
+	_, ok := p.state.LastTableTimes["BIG_SAMPLE"]
+	assert.False(t, ok)
+	assert.Equal(t, int64(1725786000), p.state.LastTableTimes["HYBRID_HRACTIVITY_SAMPLE"])
+}
+
+func TestPlugin_SelfStats(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	// The statistics are shared by every plugin in the process with the same
+	// instance ID, so only their change is checked.
+	stats := p.newTableStats("BATTERY_LEVEL")
+	rowsRead := stats.rowsRead.Get()
+	metricsEmitted := stats.metricsEmitted.Get()
+	tableGathers := p.tableDurations("BATTERY_LEVEL").count.Get()
+	databaseGathers := p.databaseDurations(dbPath).count.Get()
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+
+	var batteryMetrics int64
+	for _, metric := range acc.Metrics {
+		if metric.Measurement == "battery_level" {
+			batteryMetrics++
+		}
+	}
+
+	assert.Equal(t, batteryMetrics, stats.rowsRead.Get()-rowsRead)
+	assert.Equal(t, batteryMetrics, stats.metricsEmitted.Get()-metricsEmitted)
+
+	tableDurations := p.tableDurations("BATTERY_LEVEL")
+	assert.Equal(t, 1, tableDurations.count.Get()-tableGathers)
+	assert.Equal(t, 1, p.databaseDurations(dbPath).count.Get()-databaseGathers)
+
+	// The buckets are cumulative, so the last one counts every gather.
+	last := tableDurations.buckets[len(tableDurations.buckets)-1]
+	assert.Equal(t, tableDurations.count.Get(), last.Get())
+}
+
+func TestPlugin_Instances(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	aliceLog := new(telegraftest.CaptureLogger)
+	alice := &Plugin{
+		InstanceID:           "alice",
+		DatabasePaths:        []string{dbPath},
+		ExtraTables:          []TableDescription{{Name: "MISSING_SAMPLE", Columns: TableColumns{Timestamp: "TIMESTAMP"}}},
+		MissingTableBehavior: MissingTableWarn,
+		Log:                  aliceLog,
+	}
+	assert.NoError(t, alice.Init())
+
+	bob := &Plugin{InstanceID: "bob", DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{}}
+	assert.NoError(t, bob.Init())
+
+	aliceRows := alice.newTableStats("BATTERY_LEVEL").rowsRead.Get()
+	bobRows := bob.newTableStats("BATTERY_LEVEL").rowsRead.Get()
+
+	assert.NoError(t, alice.Gather(new(telegraftest.Accumulator)))
+	assert.NotEqual(t, aliceRows, alice.newTableStats("BATTERY_LEVEL").rowsRead.Get(), "rows not counted")
+	assert.Equal(t, bobRows, bob.newTableStats("BATTERY_LEVEL").rowsRead.Get(), "rows counted for other instance")
+
+	// Gathering one instance leaves the state of the other untouched.
+	assert.NotEqual(t, 0, len(alice.LastGathered()))
+	assert.Equal(t, 0, len(bob.LastGathered()))
+
+	warnings := aliceLog.Warnings()
+	assert.Equal(t, 1, len(warnings), "unexpected warnings: %q", warnings)
+	assert.Contains(t, warnings[0], `[alice] Skipping table "MISSING_SAMPLE"`)
+
+	// Without an ID, instances gathering different databases still differ.
+	assert.NotEqual(t, defaultInstanceID([]string{"a.db"}), defaultInstanceID([]string{"b.db"}))
+}
+
+func TestPlugin_WatchDatabases(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+	watchDir := t.TempDir()
+
+	settleDelay := watchSettleDelay
+	watchSettleDelay = 10 * time.Millisecond
+	t.Cleanup(func() { watchSettleDelay = settleDelay })
+
+	p := &Plugin{
+		DatabasePaths:  []string{filepath.Join(watchDir, "*.db")},
+		WatchDatabases: true,
+		Log:            telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Start(acc))
+	t.Cleanup(p.Stop)
+
+	// The database lands without Gather being called.
+	assert.NoError(t, os.Rename(dbPath, filepath.Join(watchDir, "export.db")))
+	acc.Wait(20)
+
+	p.Stop()
+	assert.Equal(t, 20, len(acc.Metrics))
+}
+
+func TestPlugin_WatchReloadedDatabases(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+
+	settleDelay := watchSettleDelay
+	watchSettleDelay = 10 * time.Millisecond
+	t.Cleanup(func() { watchSettleDelay = settleDelay })
+
+	p := &Plugin{
+		DatabasePaths:  []string{filepath.Join(oldDir, "*.db")},
+		WatchDatabases: true,
+		Log:            telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Start(acc))
+	t.Cleanup(p.Stop)
+
+	newPlugin := &Plugin{DatabasePaths: []string{filepath.Join(newDir, "*.db")}, Log: telegraftest.Logger{}}
+	assert.NoError(t, newPlugin.Init())
+	assert.NoError(t, p.Reload(newPlugin))
+
+	// A database landing in the reloaded directory is gathered.
+	assert.NoError(t, os.Rename(dbPath, filepath.Join(newDir, "export.db")))
+	acc.Wait(20)
+
+	p.Stop()
+	assert.Equal(t, 20, len(acc.Metrics))
+
+	// A reload that can't be watched keeps watching the directory from before.
+	assert.NoError(t, p.Start(acc))
+	globPlugin := &Plugin{DatabasePaths: []string{filepath.Join(newDir, "*", "export.db")}, Log: telegraftest.Logger{}}
+	assert.NoError(t, globPlugin.Init())
+	assert.Error(t, p.Reload(globPlugin))
+	assert.NotZero(t, p.watcher)
+}
+
+func TestPlugin_WatchGlobDirectory(t *testing.T) {
+	pattern := filepath.Join(t.TempDir(), "*", "export.db")
+
+	p := &Plugin{DatabasePaths: []string{pattern}, WatchDatabases: true, Log: telegraftest.Logger{}}
+	assert.Error(t, p.Init())
+
+	// Watching may also be turned on after Init, such as by -watch.
+	p = &Plugin{DatabasePaths: []string{pattern}, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+	p.WatchDatabases = true
+	assert.Error(t, p.Start(new(telegraftest.Accumulator)))
+}
+
+func TestPlugin_Status(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+	missingPath := filepath.Join(t.TempDir(), "missing.db")
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+	assert.Zero(t, p.Status())
+
+	assert.NoError(t, p.Gather(new(telegraftest.Accumulator)))
+	status := p.Status()
+	assert.False(t, status.LastSuccess.IsZero(), "successful gather not recorded")
+	assert.Zero(t, status.GatheringSince)
+	assert.Zero(t, status.ProgressedAt)
+	assert.Equal(t, map[string]string{dbPath: ""}, status.Databases)
+
+	p.DatabasePaths = append(p.DatabasePaths, missingPath)
+	assert.Error(t, p.Gather(new(telegraftest.Accumulator)))
+	failed := p.Status()
+	assert.Equal(t, status.LastSuccess, failed.LastSuccess, "failed gather recorded as successful")
+	assert.Equal(t, "", failed.Databases[dbPath])
+	assert.NotEqual(t, "", failed.Databases[missingPath], "missing database not reported")
+}
+
+func TestPlugin_MissingDatabaseBehavior(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+	missingPath := filepath.Join(t.TempDir(), "missing.db")
+
+	log := new(telegraftest.CaptureLogger)
+	p := &Plugin{
+		DatabasePaths:           []string{missingPath},
+		MissingDatabaseBehavior: MissingDatabaseIgnore,
+		Log:                     log,
+	}
+	assert.NoError(t, p.Init())
+
+	// The missing database is skipped quietly on every gather, with only the
+	// first one warning about it.
+	for range 2 {
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+		assert.Equal(t, 0, len(acc.Metrics), "metrics gathered from missing database")
+	}
+	assert.Equal(t, 1, len(log.Warnings()), "unexpected warnings: %q", log.Warnings())
+	assert.NotEqual(t, "", p.Status().Databases[missingPath], "missing database not reported")
+
+	data, err := os.ReadFile(dbPath)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(missingPath, data, 0o644))
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.NotEqual(t, 0, len(acc.Metrics), "no metrics gathered once database appeared")
+	assert.Equal(t, "", p.Status().Databases[missingPath])
+
+	p.MissingDatabaseBehavior = "retry"
+	assert.Error(t, p.Init(), "unknown behavior accepted")
+}
+
+func TestPlugin_MissingTableBehavior(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	gather := func(behavior MissingTableBehavior) (*telegraftest.Accumulator, []string) {
+		log := new(telegraftest.CaptureLogger)
+		p := &Plugin{
+			DatabasePaths:        []string{dbPath},
+			ExtraTables:          []TableDescription{{Name: "MISSING_SAMPLE", Columns: TableColumns{Timestamp: "TIMESTAMP"}}},
+			MissingTableBehavior: behavior,
+			Log:                  log,
+		}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+		return acc, log.Warnings()
+	}
+
+	missingTables := func(acc *telegraftest.Accumulator) int {
+		var n int
+		for _, metric := range acc.Metrics {
+			if metric.Measurement == errorsMeasurement && metric.Tags["class"] == string(errorMissingTable) {
+				n++
+			}
+		}
+		return n
+	}
+
+	// The table is skipped quietly by default.
+	acc, warnings := gather("")
+	assert.Equal(t, 0, len(warnings), "unexpected warnings: %q", warnings)
+	assert.Equal(t, 0, len(acc.Errors))
+	assert.Equal(t, 0, missingTables(acc))
+
+	acc, warnings = gather(MissingTableWarn)
+	assert.Equal(t, 1, len(warnings), "unexpected warnings: %q", warnings)
+	assert.Equal(t, 0, len(acc.Errors))
+	assert.Equal(t, 1, missingTables(acc))
+
+	acc, _ = gather(MissingTableError)
+	assert.Equal(t, 1, len(acc.Errors))
+	assert.Equal(t, 1, missingTables(acc))
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, MissingTableBehavior: "panic", Log: telegraftest.Logger{}}
+	assert.Error(t, p.Init())
+}
+
+func TestPlugin_Strict(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump+`
+		CREATE TABLE TEXT_SAMPLE (TIMESTAMP INTEGER, DEVICE_ID TEXT, VALUE TEXT);
+	`)
+
+	initStrict := func(paths []string, extraTables ...TableDescription) error {
+		p := &Plugin{DatabasePaths: paths, ExtraTables: extraTables, Strict: true, Log: telegraftest.Logger{}}
+		return p.Init()
+	}
+
+	assert.NoError(t, initStrict([]string{dbPath}))
+
+	err := initStrict([]string{filepath.Join(t.TempDir(), "missing.db")})
+	assert.Error(t, err)
+
+	err = initStrict([]string{dbPath}, TableDescription{
+		Name:    "MISSING_SAMPLE",
+		Columns: TableColumns{Timestamp: "TIMESTAMP"},
+	})
+	assert.Contains(t, err.Error(), `table "MISSING_SAMPLE": table does not exist`)
+
+	// Text tags are fine, but not text fields.
+	err = initStrict([]string{dbPath}, TableDescription{
+		Name:    "TEXT_SAMPLE",
+		Columns: TableColumns{Timestamp: "TIMESTAMP", Tags: []string{"DEVICE_ID"}, Fields: []string{"VALUE", "MISSING"}},
+	})
+	assert.Contains(t, err.Error(), `column "VALUE" has type "TEXT" with TEXT affinity`)
+	assert.Contains(t, err.Error(), `missing column "MISSING"`)
+	assert.NotContains(t, err.Error(), `"DEVICE_ID"`)
+}
+
+func TestPlugin_Validate(t *testing.T) {
+	// The dump is of an older Gadgetbridge, which is missing most of the
+	// known tables, such as GARMIN_BODY_ENERGY_SAMPLE.
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	p := &Plugin{DatabasePaths: []string{dbPath}, Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+	assert.NoError(t, p.Validate())
+
+	p = &Plugin{
+		DatabasePaths: []string{dbPath},
+		ExtraTables:   []TableDescription{{Name: "MISSING_SAMPLE", Columns: TableColumns{Timestamp: "TIMESTAMP"}}},
+		Log:           telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+	err := p.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `table "MISSING_SAMPLE": table does not exist`)
+	assert.NotContains(t, err.Error(), "GARMIN_BODY_ENERGY_SAMPLE")
+}
+
+func TestPlugin_Reload(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	p := &Plugin{Log: telegraftest.Logger{}}
+	assert.NoError(t, p.Init())
+
+	acc := new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.Equal(t, 0, len(acc.Metrics), "metrics gathered without databases")
+
+	newPlugin := &Plugin{DatabasePaths: []string{dbPath}}
+	assert.NoError(t, newPlugin.Init())
+	assert.NoError(t, p.Reload(newPlugin))
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.NotEqual(t, 0, len(acc.Metrics), "no metrics gathered from reloaded databases")
+
+	// The state must survive another reload.
+	assert.NoError(t, p.Reload(newPlugin))
+
+	acc = new(telegraftest.Accumulator)
+	assert.NoError(t, p.Gather(acc))
+	assert.Equal(t, 0, len(acc.Metrics), "metrics gathered twice after reload")
+
+	// Sessions are only gathered once their options are reloaded.
+	newPlugin = &Plugin{
+		DatabasePaths:  []string{dbPath},
+		GatherSessions: true,
+		SleepSessions: []SleepSessionTable{{
+			Table:      "XIAOMI_SLEEP_STAGE_SAMPLE",
+			KindColumn: "STAGE",
+			Kinds:      []int64{2, 3, 4},
+		}},
+	}
+	assert.NoError(t, newPlugin.Init())
+	assert.NoError(t, p.Reload(newPlugin))
+	assert.True(t, p.GatherSessions)
+	assert.Equal(t, newPlugin.SleepSessions, p.SleepSessions)
+}
+
+func TestPlugin_ReloadCopiesEveryOption(t *testing.T) {
+	// The options that Reload deliberately leaves as they were.
+	kept := map[string]bool{
+		"instance_id":     true,
+		"watch_databases": true,
+	}
+
+	var newPlugin Plugin
+	v := reflect.ValueOf(&newPlugin).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("toml"), ",")
+		if name == "" || name == "-" || kept[name] {
+			continue
+		}
+
+		// Set every option to something other than its zero value.
+		switch f := v.Field(i); f.Kind() {
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.String:
+			f.SetString("set")
+		case reflect.Int64:
+			f.SetInt(1)
+		case reflect.Slice:
+			f.Set(reflect.MakeSlice(f.Type(), 1, 1))
+		case reflect.Map:
+			f.Set(reflect.MakeMap(f.Type()))
+			f.SetMapIndex(reflect.Zero(f.Type().Key()), reflect.Zero(f.Type().Elem()))
+		default:
+			t.Fatalf("option %q has a kind %s that this test can't set", name, f.Kind())
+		}
+	}
+
+	p := &Plugin{}
+	assert.NoError(t, p.Reload(&newPlugin))
+
+	got := reflect.ValueOf(p).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("toml"), ",")
+		if name == "" || name == "-" || kept[name] {
+			continue
+		}
+		assert.True(t, reflect.DeepEqual(v.Field(i).Interface(), got.Field(i).Interface()),
+			"option %q isn't copied by Reload", name)
+	}
+}
+
+func TestPlugin_VerifyChecksums(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	gather := func(t *testing.T) *telegraftest.Accumulator {
+		p := &Plugin{DatabasePaths: []string{dbPath}, VerifyChecksums: true, Log: telegraftest.Logger{}}
+		assert.NoError(t, p.Init())
+
+		acc := new(telegraftest.Accumulator)
+		assert.NoError(t, p.Gather(acc))
+		return acc
+	}
+
+	t.Run("mismatch", func(t *testing.T) {
+		err := os.WriteFile(dbPath+".sha256", []byte("deadbeef  gadgetbridge.db\n"), 0644)
+		assert.NoError(t, err)
+
+		acc := gather(t)
+		assert.Equal(t, 0, len(acc.Metrics), "metrics gathered from mismatching database")
+		assert.Error(t, acc.FirstError())
+	})
+
+	t.Run("match", func(t *testing.T) {
+		sum, err := fileChecksum(dbPath)
+		assert.NoError(t, err)
+
+		err = os.WriteFile(dbPath+".sha256", []byte(sum+"  gadgetbridge.db\n"), 0644)
+		assert.NoError(t, err)
+
+		acc := gather(t)
+		assert.NotEqual(t, 0, len(acc.Metrics), "no metrics gathered from matching database")
+		assert.NoError(t, acc.FirstError())
+	})
+}
+
+func TestGenerateExtraTables(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	tables, err := GenerateExtraTables(dbPath)
+	assert.NoError(t, err)
+
+	var config strings.Builder
+	assert.NoError(t, WriteExtraTables(&config, tables))
+	autogold.ExpectFile(t, autogold.Raw(config.String()), autogold.Name("TestGenerateExtraTables/config"))
+}
+
+// TestSampleConfig_READMEs checks that both READMEs show the sample config
+// as it is, which they used to drift from.
+func TestSampleConfig_READMEs(t *testing.T) {
+	for _, path := range []string{"README.md", "../../../README.md"} {
+		readme, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(readme), "\n"+sampleConfig+"```\n", "sample config of %s", path)
+	}
+}
+
+func TestFamilySampleConfig(t *testing.T) {
+	for _, family := range DeviceFamilies() {
+		t.Run(family, func(t *testing.T) {
+			sample, err := FamilySampleConfig(family)
+			assert.NoError(t, err)
+			autogold.ExpectFile(t, autogold.Raw(sample), autogold.Name("TestFamilySampleConfig/"+family))
+
+			var config struct {
+				Inputs struct {
+					Gadgetbridge []*Plugin `toml:"gadgetbridge"`
+				} `toml:"inputs"`
+			}
+			assert.NoError(t, toml.Unmarshal([]byte(sample), &config))
+			assert.Equal(t, 1, len(config.Inputs.Gadgetbridge))
+
+			p := config.Inputs.Gadgetbridge[0]
+			p.Log = telegraftest.Logger{}
+			assert.NoError(t, p.Init())
+			assert.NotEqual(t, 0, len(p.ExtraTables))
+		})
+	}
+
+	_, err := FamilySampleConfig("pebble")
+	assert.Error(t, err)
+}
+
+func TestPlugin_ProcessedAction(t *testing.T) {
+	dbPath := newTestDB(t, gadgetbridgeDump)
+
+	p := &Plugin{
+		DatabasePaths:   []string{dbPath},
+		ProcessedAction: ProcessedDelete,
+		Log:             telegraftest.Logger{},
+	}
+	assert.NoError(t, p.Init())
+
+	exists := func() bool {
+		_, err := os.Stat(dbPath)
+		return err == nil
+	}
+
+	// The database is only deleted once the metrics of the gather are
+	// written, which the next gather is taken to mean.
+	assert.NoError(t, p.Gather(new(telegraftest.Accumulator)))
+	assert.True(t, exists())
+
+	// A database replaced since it was gathered is gathered again instead.
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(dbPath, later, later))
+	assert.NoError(t, p.Gather(new(telegraftest.Accumulator)))
+	assert.True(t, exists())
+
+	assert.NoError(t, p.ProcessGathered())
+	assert.False(t, exists())
+}
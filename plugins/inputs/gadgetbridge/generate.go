@@ -0,0 +1,152 @@
+package gadgetbridge
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// GenerateExtraTables inspects the database at path and describes every
+// sample table in it that isn't already gathered by default. Sample tables
+// are those whose name ends in _SAMPLE and that have a TIMESTAMP column.
+//
+// Columns are classified by their name and type: identifiers (columns
+// ending in _ID or _INDEX) and text columns become tags, numeric columns
+// become fields and everything else, such as raw BLOBs, is left out.
+func GenerateExtraTables(path string) ([]TableDescription, error) {
+	tables, err := InspectDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptions []TableDescription
+	for _, table := range tables {
+		if !strings.HasSuffix(table.Name, "_SAMPLE") || !gadgetbridgedb.HasColumn(table.Columns, "TIMESTAMP") {
+			continue
+		}
+		if isKnownTable(table.Name) {
+			continue
+		}
+
+		description := TableDescription{
+			Name:    table.Name,
+			Columns: TableColumns{Timestamp: "TIMESTAMP"},
+			// The tables of AbstractTimeSample are in milliseconds, which
+			// only their values tell apart.
+			Milliseconds: table.Milliseconds,
+		}
+
+		for _, column := range table.Columns {
+			switch classifyColumn(column) {
+			case columnTag:
+				description.Columns.Tags = append(description.Columns.Tags, column.Name)
+			case columnField:
+				description.Columns.Fields = append(description.Columns.Fields, column.Name)
+			}
+		}
+
+		if len(description.Columns.Fields) > 0 {
+			descriptions = append(descriptions, description)
+		}
+	}
+
+	return descriptions, nil
+}
+
+func isKnownTable(name string) bool {
+	return slices.ContainsFunc(knownTables, func(t TableDescription) bool { return t.Name == name })
+}
+
+type columnKind int
+
+const (
+	columnSkip columnKind = iota
+	columnTag
+	columnField
+)
+
+func classifyColumn(column ColumnInfo) columnKind {
+	if column.Name == "TIMESTAMP" {
+		return columnSkip
+	}
+
+	if strings.HasSuffix(column.Name, "_ID") || strings.HasSuffix(column.Name, "_INDEX") {
+		return columnTag
+	}
+
+	// Classify by SQLite's type affinity rules.
+	typ := strings.ToUpper(column.Type)
+	switch {
+	case strings.Contains(typ, "INT"):
+		return columnField
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"), strings.Contains(typ, "TEXT"):
+		return columnTag
+	case strings.Contains(typ, "BLOB"), typ == "":
+		return columnSkip
+	default:
+		// REAL, FLOA, DOUB and NUMERIC affinities.
+		return columnField
+	}
+}
+
+// WriteExtraTables writes the table descriptions as extra_tables entries of
+// the plugin's TOML config.
+func WriteExtraTables(w io.Writer, tables []TableDescription) error {
+	var b strings.Builder
+	for i, t := range tables {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "[[inputs.gadgetbridge.extra_tables]]\n")
+		fmt.Fprintf(&b, "  table = %s\n", tomlString(t.Name))
+		if t.Milliseconds {
+			fmt.Fprintf(&b, "  milliseconds = true\n")
+		}
+		fmt.Fprintf(&b, "  [inputs.gadgetbridge.extra_tables.columns]\n")
+		fmt.Fprintf(&b, "    timestamp = %s\n", tomlString(t.Columns.Timestamp))
+		fmt.Fprintf(&b, "    tags = %s\n", tomlStrings(t.Columns.Tags))
+		fmt.Fprintf(&b, "    fields = %s\n", tomlStrings(t.Columns.Fields))
+		if len(t.Columns.Sentinels) > 0 {
+			fmt.Fprintf(&b, "    sentinels = %s\n", tomlSentinels(t.Columns.Sentinels))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func tomlString(s string) string {
+	// Go's quoting is a superset of what's needed for TOML basic strings
+	// when it comes to the identifiers written here.
+	return fmt.Sprintf("%q", s)
+}
+
+func tomlSentinels(sentinels map[string][]int64) string {
+	columns := make([]string, 0, len(sentinels))
+	for column := range sentinels {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	entries := make([]string, 0, len(sentinels))
+	for _, column := range columns {
+		values := make([]string, len(sentinels[column]))
+		for i, v := range sentinels[column] {
+			values[i] = strconv.FormatInt(v, 10)
+		}
+		entries = append(entries, fmt.Sprintf("%s = [%s]", column, strings.Join(values, ", ")))
+	}
+	return "{ " + strings.Join(entries, ", ") + " }"
+}
+
+func tomlStrings(ss []string) string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		quoted[i] = tomlString(s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,77 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const gpxPointMeasurement = "gadgetbridge_gpx_point"
+
+// gpxStateKey is the key in the state of the activities whose GPX tracks are
+// gathered, as kept by gatherActivityFiles.
+const gpxStateKey = "BASE_ACTIVITY_SUMMARY/gpx"
+
+// gpxTrack is the subset of a GPX document that is gathered. Element names
+// are matched without their namespaces, which covers the Garmin
+// TrackPointExtension that Gadgetbridge writes heart rate and cadence into.
+type gpxTrack struct {
+	Points []struct {
+		Latitude    float64    `xml:"lat,attr"`
+		Longitude   float64    `xml:"lon,attr"`
+		Elevation   *float64   `xml:"ele"`
+		Time        *time.Time `xml:"time"`
+		HeartRate   *int64     `xml:"extensions>TrackPointExtension>hr"`
+		Cadence     *int64     `xml:"extensions>TrackPointExtension>cad"`
+		Temperature *float64   `xml:"extensions>TrackPointExtension>atemp"`
+	} `xml:"trk>trkseg>trkpt"`
+}
+
+func (p *Plugin) gatherGPXTracks(acc telegraf.Accumulator, db *sql.DB, dbPath string, opts gatherOptions) error {
+	return p.gatherActivityFiles(acc, db, dbPath, "GPX_TRACK", "%.gpx", gpxStateKey, gatherGPXTrack, opts)
+}
+
+func gatherGPXTrack(acc telegraf.Accumulator, path string, tags map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var track gpxTrack
+	if err := xml.NewDecoder(f).Decode(&track); err != nil {
+		return fmt.Errorf("failed to decode GPX file %q: %w", path, err)
+	}
+
+	for _, point := range track.Points {
+		// Points without a time can't be placed in a time series.
+		if point.Time == nil {
+			continue
+		}
+
+		fields := map[string]interface{}{
+			"latitude":  point.Latitude,
+			"longitude": point.Longitude,
+		}
+		if point.Elevation != nil {
+			fields["elevation"] = *point.Elevation
+		}
+		if point.HeartRate != nil {
+			fields["heart_rate"] = *point.HeartRate
+		}
+		if point.Cadence != nil {
+			fields["cadence"] = *point.Cadence
+		}
+		if point.Temperature != nil {
+			fields["temperature"] = *point.Temperature
+		}
+
+		acc.AddFields(gpxPointMeasurement, fields, tags, *point.Time)
+	}
+
+	return nil
+}
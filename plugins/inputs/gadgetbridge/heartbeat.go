@@ -0,0 +1,37 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"os"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// heartbeatMeasurement is the measurement that the heartbeats gathered with
+// GatherHeartbeat are added to.
+const heartbeatMeasurement = "gadgetbridge_heartbeat"
+
+// addHeartbeat adds a metric describing the database at path, which db is
+// opened on, or nil if it couldn't be opened. The file's size and modification
+// time are left out if it can't be found, and its schema version is left out
+// if it can't be read.
+func addHeartbeat(acc telegraf.Accumulator, path string, db *sql.DB) {
+	fields := map[string]interface{}{"open": false}
+
+	if stat, err := os.Stat(path); err == nil {
+		fields["size_bytes"] = stat.Size()
+		fields["file_mtime"] = stat.ModTime().Unix()
+	}
+
+	if db != nil {
+		if userVersion, err := gadgetbridgedb.SchemaVersion(db); err == nil {
+			fields["open"] = true
+			fields["user_version"] = userVersion
+		}
+	}
+
+	acc.AddFields(heartbeatMeasurement, fields, map[string]string{"database_path": path}, time.Now())
+}
@@ -0,0 +1,74 @@
+package gadgetbridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// gatherDurationMeasurement is the measurement that the gather duration
+// histograms are registered under. Telegraf's internal input reports them as
+// internal_gadgetbridge_gather_duration.
+const gatherDurationMeasurement = "gadgetbridge_gather_duration"
+
+// gatherDurationBuckets are the upper bounds of the buckets of the gather
+// duration histograms.
+var gatherDurationBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// durationHistogram is a cumulative histogram of gather durations, like those
+// of Prometheus. Each bucket counts the gathers that took at most its upper
+// bound, and count counts all of them.
+type durationHistogram struct {
+	buckets []selfstat.Stat
+	count   selfstat.Stat
+	sum     selfstat.Stat
+}
+
+// newDurationHistogram returns the histogram with the given tags. Registering
+// the same tags again returns the same histogram.
+func newDurationHistogram(tags map[string]string) durationHistogram {
+	h := durationHistogram{
+		buckets: make([]selfstat.Stat, len(gatherDurationBuckets)),
+		count:   selfstat.Register(gatherDurationMeasurement, "count", tags),
+		sum:     selfstat.Register(gatherDurationMeasurement, "sum_ns", tags),
+	}
+	for i, bound := range gatherDurationBuckets {
+		field := fmt.Sprintf("le_%g", bound.Seconds())
+		h.buckets[i] = selfstat.Register(gatherDurationMeasurement, field, tags)
+	}
+	return h
+}
+
+// observe records a gather that took d.
+func (h durationHistogram) observe(d time.Duration) {
+	for i, bound := range gatherDurationBuckets {
+		if d <= bound {
+			h.buckets[i].Incr(1)
+		}
+	}
+	h.count.Incr(1)
+	h.sum.Incr(d.Nanoseconds())
+}
+
+// tableDurations returns the histogram of how long gathering the given table
+// takes.
+func (p *Plugin) tableDurations(table string) durationHistogram {
+	return newDurationHistogram(map[string]string{"instance": p.instanceID, "table": table})
+}
+
+// databaseDurations returns the histogram of how long gathering every table
+// of the database at path takes.
+func (p *Plugin) databaseDurations(path string) durationHistogram {
+	return newDurationHistogram(map[string]string{"instance": p.instanceID, "database_path": path})
+}
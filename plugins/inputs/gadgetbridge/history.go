@@ -0,0 +1,153 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/influxdata/telegraf"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// historyTable describes a table whose rows each start at a time in Unix
+// milliseconds, such as the attributes of users and devices over time, for
+// gatherHistory to gather.
+type historyTable struct {
+	// name is the name of the table. It doubles as its key in
+	// pluginState.LastTableTimes, which tracks the time of the newest row
+	// gathered, and in pluginState.LastTableRows, which holds the rows
+	// gathered at that time.
+	name string
+	// what names the rows in log messages, such as "user attributes".
+	what string
+	// measurement is the measurement that the rows are added to.
+	measurement string
+	// timeColumn is the column of the time that each row starts at.
+	timeColumn string
+	// columns are the columns selected for scan.
+	columns []any
+	// where, if not nil, leaves out the rows that it doesn't match, such as
+	// those without a time.
+	where exp.Expression
+	// prepare, if not nil, is called once the table is known to exist,
+	// such as to read the tables that its rows refer to.
+	prepare func(db *sql.DB) error
+	// scan reads a row of the columns into the tags and fields of its metric,
+	// along with its time.
+	scan func(r *sql.Rows) (historyRow, error)
+}
+
+// historyRow is a row of a historyTable as read by its scan.
+type historyRow struct {
+	// time is the time of the row in Unix milliseconds.
+	time   int64
+	tags   map[string]string
+	fields map[string]any
+}
+
+// gatherHistory gathers the rows of the table that weren't gathered yet, or
+// those within the range of a backfill, each tagged with the database it was
+// gathered from and timestamped with its start. Like gatherTable, the rows that
+// start at the same time as the newest one of the last gather are read again,
+// skipping those that were already gathered, in case more were written since.
+func (p *Plugin) gatherHistory(acc telegraf.Accumulator, db *sql.DB, dbPath string, opts gatherOptions, t historyTable) error {
+	columns, err := gadgetbridgedb.Columns(db, t.name)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		p.log.Debugf("Skipping %s missing from %q", t.what, dbPath)
+		return nil
+	}
+
+	if t.prepare != nil {
+		if err := t.prepare(db); err != nil {
+			return err
+		}
+	}
+
+	q := sqliteBuilder.
+		From(t.name).
+		Select(t.columns...).
+		Order(goqu.C(t.timeColumn).Asc())
+	if t.where != nil {
+		q = q.Where(t.where)
+	}
+	lastTime, hasLastTime := p.state.LastTableTimes[t.name]
+	lastRows, hasLastRows := p.state.LastTableRows[t.name]
+	if opts.backfill {
+		q = opts.where(q, t.timeColumn, time.Time.UnixMilli)
+		q = opts.newest(q, t.timeColumn)
+	} else if hasLastTime && hasLastRows {
+		q = q.Where(goqu.C(t.timeColumn).Gte(lastTime))
+	} else if hasLastTime {
+		q = q.Where(goqu.C(t.timeColumn).Gt(lastTime))
+	}
+
+	qSQL, qArgs, err := q.ToSQL()
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	stats := p.newTableStats(t.name)
+
+	var n, dropped int
+	var dropErr error
+	for r.Next() {
+		row, err := t.scan(r)
+		if err != nil {
+			if dropped == 0 {
+				dropErr = err
+			}
+			dropped++
+			continue
+		}
+
+		// Maps are printed with their keys sorted, so the key is the same
+		// for the same row.
+		key := fmt.Sprint(row.tags, row.fields)
+		if !opts.backfill && hasLastRows && row.time == lastTime && slices.Contains(lastRows, key) {
+			continue
+		}
+		n++
+
+		row.tags["database_path"] = dbPath
+		acc.AddFields(t.measurement, row.fields, row.tags, time.UnixMilli(row.time))
+
+		if !opts.backfill {
+			if !hasLastTime || row.time != lastTime {
+				lastTime, hasLastTime = row.time, true
+				lastRows = nil
+			}
+			lastRows = append(lastRows, key)
+			p.state.LastTableTimes[t.name] = row.time
+			p.state.LastTableRows[t.name] = lastRows
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("error reading rows: %w", err)
+	}
+
+	stats.rowsRead.Incr(int64(n + dropped))
+	stats.rowsDropped.Incr(int64(dropped))
+	stats.metricsEmitted.Incr(int64(n))
+
+	if dropped > 0 {
+		p.log.Warnf("Dropped %d unreadable %s of %q, the first because of: %v", dropped, t.what, dbPath, dropErr)
+		addTableError(acc, dbPath, t.name, errorDroppedRows, dropped)
+	}
+
+	p.log.Debugf("Gathered %d %s of %q", n, t.what, dbPath)
+	return nil
+}
@@ -0,0 +1,307 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/influxdata/telegraf"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// deviceInfo identifies a device paired with Gadgetbridge, as recorded in the
+// DEVICE table.
+type deviceInfo struct {
+	name       string
+	typeName   string
+	identifier string
+	model      deviceModel
+}
+
+// firmwareVersion is a firmware that a device ran within [validFrom,
+// validTo), both in Unix milliseconds, as recorded in the DEVICE_ATTRIBUTES
+// table. A zero validFrom or validTo leaves that end of the range open.
+type firmwareVersion struct {
+	version1           string
+	version2           sql.NullString
+	validFrom, validTo int64
+}
+
+// contains returns whether the firmware ran at t.
+func (f firmwareVersion) contains(t time.Time) bool {
+	ms := t.UnixMilli()
+	return (f.validFrom == 0 || ms >= f.validFrom) && (f.validTo == 0 || ms < f.validTo)
+}
+
+// identities are the devices and users of a database, keyed by their IDs.
+type identities struct {
+	devices map[string]deviceInfo
+	// users maps user IDs to their names in the USER table.
+	users map[string]string
+	// firmware maps device IDs to the firmware versions they ran.
+	firmware map[string][]firmwareVersion
+}
+
+// identityCache holds the identities of a database as of its modification
+// time.
+type identityCache struct {
+	modTime time.Time
+	identities
+}
+
+// loadIdentities returns the identities of the database at path, which db is
+// opened on. They're only read again once the database has been modified.
+func (p *Plugin) loadIdentities(db *sql.DB, path string) (identities, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return identities{}, err
+	}
+
+	if cache, ok := p.identityCaches[path]; ok && cache.modTime.Equal(stat.ModTime()) {
+		return cache.identities, nil
+	}
+
+	devices, err := readDevices(db)
+	if err != nil {
+		return identities{}, fmt.Errorf("error reading devices: %w", err)
+	}
+
+	users, err := readUsers(db)
+	if err != nil {
+		return identities{}, fmt.Errorf("error reading users: %w", err)
+	}
+
+	firmware, err := readFirmware(db)
+	if err != nil {
+		return identities{}, fmt.Errorf("error reading firmware versions: %w", err)
+	}
+
+	ids := identities{devices, users, firmware}
+
+	if p.identityCaches == nil {
+		p.identityCaches = make(map[string]identityCache)
+	}
+	p.identityCaches[path] = identityCache{stat.ModTime(), ids}
+
+	return ids, nil
+}
+
+// readDevices reads the DEVICE table, which is empty if it's missing. Like
+// the other identity readers, it skips the rows that can't be read, such as
+// ones with a NULL name, which only leaves those devices untagged.
+func readDevices(db *sql.DB) (map[string]deviceInfo, error) {
+	columns, err := gadgetbridgedb.Columns(db, "DEVICE")
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	// Databases older than the TYPE_NAME column are left with the
+	// manufacturer, as SQLite would otherwise read the missing column as the
+	// string "TYPE_NAME".
+	var typeName any = "TYPE_NAME"
+	if !gadgetbridgedb.HasColumn(columns, "TYPE_NAME") {
+		typeName = goqu.V("")
+	}
+
+	qSQL, qArgs, err := sqliteBuilder.
+		From("DEVICE").
+		Select("_id", "NAME", typeName, "IDENTIFIER", "MANUFACTURER").
+		ToSQL()
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	devices := make(map[string]deviceInfo)
+	for r.Next() {
+		var id string
+		var device deviceInfo
+		var manufacturer string
+		if err := r.Scan(&id, &device.name, &device.typeName, &device.identifier, &manufacturer); err != nil {
+			continue
+		}
+		device.model = lookupDeviceModel(device.typeName, manufacturer)
+		devices[id] = device
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return devices, nil
+}
+
+// readUsers reads the names in the USER table, which is empty if it's
+// missing.
+func readUsers(db *sql.DB) (map[string]string, error) {
+	columns, err := gadgetbridgedb.Columns(db, "USER")
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	qSQL, qArgs, err := sqliteBuilder.
+		From("USER").
+		Select("_id", "NAME").
+		ToSQL()
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	users := make(map[string]string)
+	for r.Next() {
+		var id, name string
+		if err := r.Scan(&id, &name); err != nil {
+			continue
+		}
+		users[id] = name
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return users, nil
+}
+
+// readFirmware reads the firmware versions in the DEVICE_ATTRIBUTES table,
+// which is empty if it's missing.
+func readFirmware(db *sql.DB) (map[string][]firmwareVersion, error) {
+	columns, err := gadgetbridgedb.Columns(db, "DEVICE_ATTRIBUTES")
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	qSQL, qArgs, err := sqliteBuilder.
+		From("DEVICE_ATTRIBUTES").
+		Select("DEVICE_ID", "FIRMWARE_VERSION1", "FIRMWARE_VERSION2", "VALID_FROM_UTC", "VALID_TO_UTC").
+		ToSQL()
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	firmware := make(map[string][]firmwareVersion)
+	for r.Next() {
+		var deviceID string
+		var f firmwareVersion
+		var validFrom, validTo sql.NullInt64
+		if err := r.Scan(&deviceID, &f.version1, &f.version2, &validFrom, &validTo); err != nil {
+			continue
+		}
+		f.validFrom = validFrom.Int64
+		f.validTo = validTo.Int64
+		firmware[deviceID] = append(firmware[deviceID], f)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return firmware, nil
+}
+
+// identityTaggingAccumulator adds the tags identifying the device and user of
+// every metric added through AddFields, which is all that the plugin uses,
+// that has a device_id or user_id tag of a known device or user. The firmware
+// is that which the device ran at the metric's time.
+type identityTaggingAccumulator struct {
+	telegraf.Accumulator
+	identities
+	// deviceTags enables the tags of the devices, which are otherwise only
+	// used to look up aliases by their identifiers.
+	deviceTags bool
+	// aliases maps device IDs and upper-case identifiers to the aliases that
+	// devices are tagged with.
+	aliases map[string]string
+}
+
+func (a identityTaggingAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	deviceID := tags["device_id"]
+	device, hasDevice := a.devices[deviceID]
+	user, hasUser := a.users[tags["user_id"]]
+
+	alias, hasAlias := a.aliases[deviceID]
+	if !hasAlias && hasDevice {
+		alias, hasAlias = a.aliases[strings.ToUpper(device.identifier)]
+	}
+	hasDevice = hasDevice && a.deviceTags
+
+	var firmware firmwareVersion
+	var hasFirmware bool
+	if versions := a.firmware[deviceID]; len(versions) > 0 {
+		at := time.Now()
+		if len(t) > 0 {
+			at = t[0]
+		}
+		for _, f := range versions {
+			if f.contains(at) {
+				firmware, hasFirmware = f, true
+				break
+			}
+		}
+	}
+
+	if hasDevice || hasAlias || hasUser || hasFirmware {
+		// The tags are reused for every row of a table, so they're copied
+		// rather than carrying one row's identities over to the next.
+		tags = maps.Clone(tags)
+	}
+
+	if hasDevice {
+		tags["device_name"] = device.name
+		tags["device_identifier"] = device.identifier
+		tags["device_manufacturer"] = device.model.manufacturer
+		// Devices of databases older than their type names have neither.
+		if device.typeName != "" {
+			tags["device_type"] = device.typeName
+			tags["device_model"] = device.model.model
+		}
+	}
+
+	if hasAlias {
+		tags["device_alias"] = alias
+	}
+
+	if hasUser {
+		tags["user"] = user
+	}
+
+	if hasFirmware {
+		tags["firmware_version"] = firmware.version1
+		if firmware.version2.Valid {
+			tags["firmware_version2"] = firmware.version2.String
+		}
+	}
+
+	a.Accumulator.AddFields(measurement, fields, tags, t...)
+}
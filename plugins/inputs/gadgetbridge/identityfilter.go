@@ -0,0 +1,98 @@
+package gadgetbridge
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+)
+
+// identityFilter selects devices or users by glob patterns matching any of
+// their keys, such as their IDs and names.
+type identityFilter struct {
+	include filter.Filter
+	exclude filter.Filter
+}
+
+// newIdentityFilter compiles the include and exclude patterns, either of which
+// may be empty.
+func newIdentityFilter(include, exclude []string) (identityFilter, error) {
+	var f identityFilter
+	var err error
+
+	f.include, err = filter.Compile(include)
+	if err != nil {
+		return identityFilter{}, fmt.Errorf("invalid include patterns: %w", err)
+	}
+
+	f.exclude, err = filter.Compile(exclude)
+	if err != nil {
+		return identityFilter{}, fmt.Errorf("invalid exclude patterns: %w", err)
+	}
+
+	return f, nil
+}
+
+// enabled returns whether the filter leaves out anything at all.
+func (f identityFilter) enabled() bool {
+	return f.include != nil || f.exclude != nil
+}
+
+// keeps returns whether the identity with the given keys is selected: at least
+// one key must be included and none may be excluded. Empty keys are ignored.
+func (f identityFilter) keeps(keys ...string) bool {
+	if f.include != nil && !matchesAnyKey(f.include, keys) {
+		return false
+	}
+	if f.exclude != nil && matchesAnyKey(f.exclude, keys) {
+		return false
+	}
+	return true
+}
+
+// matchesAnyKey returns whether f matches any of the non-empty keys.
+func matchesAnyKey(f filter.Filter, keys []string) bool {
+	for _, key := range keys {
+		if key != "" && f.Match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// identityFilteringAccumulator drops the metrics added through AddFields,
+// which is all that the plugin uses, of the devices and users that aren't
+// selected. Metrics are only filtered by the tags they have, so battery
+// levels, which have no user_id, are kept regardless of the users.
+type identityFilteringAccumulator struct {
+	telegraf.Accumulator
+	identities
+	devices identityFilter
+	users   identityFilter
+}
+
+func (a identityFilteringAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if deviceID, ok := tags["device_id"]; ok && !a.keepsDevice(deviceID) {
+		return
+	}
+	if userID, ok := tags["user_id"]; ok && !a.users.keeps(userID, a.identities.users[userID]) {
+		return
+	}
+	a.Accumulator.AddFields(measurement, fields, tags, t...)
+}
+
+// keepsDevice returns whether the device is selected by its ID, name or
+// identifier, the last of which is matched in either case since MAC
+// addresses are written both ways.
+func (a identityFilteringAccumulator) keepsDevice(deviceID string) bool {
+	device := a.identities.devices[deviceID]
+	return a.devices.keeps(
+		deviceID,
+		device.name,
+		device.identifier,
+		strings.ToUpper(device.identifier),
+		strings.ToLower(device.identifier),
+	)
+}
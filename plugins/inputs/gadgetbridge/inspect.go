@@ -0,0 +1,168 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// TableInfo describes a table found in a Gadgetbridge database.
+type TableInfo struct {
+	// Name is the name of the table.
+	Name string
+	// Columns are the columns of the table in their declared order.
+	Columns []ColumnInfo
+	// Rows is the number of rows in the table.
+	Rows int64
+	// TimestampColumn is the column that was guessed to hold the time of
+	// each row, or empty if there's none.
+	TimestampColumn string
+	// MinTime and MaxTime are the oldest and newest times in TimestampColumn.
+	// They are zero if the table has no timestamp column or no rows.
+	MinTime, MaxTime time.Time
+	// Milliseconds is whether the times in TimestampColumn were guessed to
+	// be in Unix milliseconds rather than seconds.
+	Milliseconds bool
+}
+
+// timestampColumns are the columns that Gadgetbridge commonly stores the
+// time of a row in, in order of preference.
+var timestampColumns = []string{
+	"TIMESTAMP",
+	"TIMESTAMP_FROM",
+	"START_TIME",
+	"START_TIMESTAMP",
+	"VALID_FROM_UTC",
+	"DOWNLOAD_TIMESTAMP",
+}
+
+// InspectDatabase describes every table in the database at path.
+func InspectDatabase(path string) ([]TableInfo, error) {
+	// SQLite reports missing files rather obscurely, so check for them first.
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	db, err := gadgetbridgedb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	names, err := tableNames(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]TableInfo, 0, len(names))
+	for _, name := range names {
+		table, err := inspectTable(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: %w", name, err)
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// tableNames returns the names of the tables in the database, excluding
+// SQLite's internal tables.
+func tableNames(db *sql.DB) ([]string, error) {
+	r, err := db.Query(`
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var names []string
+	for r.Next() {
+		var name string
+		if err := r.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		names = append(names, name)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("error reading table names: %w", err)
+	}
+
+	return names, nil
+}
+
+func inspectTable(db *sql.DB, name string) (TableInfo, error) {
+	columns, err := gadgetbridgedb.Columns(db, name)
+	if err != nil {
+		return TableInfo{}, err
+	}
+
+	table := TableInfo{
+		Name:    name,
+		Columns: columns,
+	}
+
+	for _, column := range timestampColumns {
+		if gadgetbridgedb.HasColumn(columns, column) {
+			table.TimestampColumn = column
+			break
+		}
+	}
+
+	selects := []any{goqu.COUNT(goqu.Star())}
+	if table.TimestampColumn != "" {
+		selects = append(selects,
+			goqu.MIN(table.TimestampColumn),
+			goqu.MAX(table.TimestampColumn),
+		)
+	}
+
+	qSQL, qArgs, err := sqliteBuilder.From(name).Select(selects...).ToSQL()
+	if err != nil {
+		return TableInfo{}, fmt.Errorf("error building query: %w", err)
+	}
+
+	var minTime, maxTime sql.NullInt64
+	dst := []any{&table.Rows}
+	if table.TimestampColumn != "" {
+		dst = append(dst, &minTime, &maxTime)
+	}
+
+	if err := db.QueryRow(qSQL, qArgs...).Scan(dst...); err != nil {
+		return TableInfo{}, err
+	}
+
+	if minTime.Valid {
+		table.MinTime = guessUnixTime(minTime.Int64)
+	}
+	if maxTime.Valid {
+		table.MaxTime = guessUnixTime(maxTime.Int64)
+		table.Milliseconds = isUnixMilli(maxTime.Int64)
+	}
+
+	return table, nil
+}
+
+// guessUnixTime converts a timestamp that is either in seconds or in
+// milliseconds, as Gadgetbridge uses both depending on the table. Seconds
+// past 1e11 would be well beyond the year 5000, so those are treated as
+// milliseconds.
+func guessUnixTime(v int64) time.Time {
+	if isUnixMilli(v) {
+		return time.UnixMilli(v)
+	}
+	return time.Unix(v, 0)
+}
+
+// isUnixMilli returns whether guessUnixTime treats v as milliseconds.
+func isUnixMilli(v int64) bool {
+	return v > 1e11 || v < -1e11
+}
@@ -0,0 +1,60 @@
+package gadgetbridge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// defaultInstanceID returns the instance ID of a plugin gathering the given
+// database paths. It's derived from the paths so that it stays the same
+// across restarts.
+func defaultInstanceID(databasePaths []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(databasePaths, "\n")))
+	return hex.EncodeToString(sum[:4])
+}
+
+// instanceLogger prefixes every message with the instance ID of the plugin,
+// so that the messages of several instances can be told apart.
+type instanceLogger struct {
+	telegraf.Logger
+	prefix string
+}
+
+func newInstanceLogger(log telegraf.Logger, instanceID string) instanceLogger {
+	return instanceLogger{log, "[" + instanceID + "] "}
+}
+
+func (l instanceLogger) Errorf(format string, args ...interface{}) {
+	l.Logger.Errorf(l.prefix+format, args...)
+}
+
+func (l instanceLogger) Error(args ...interface{}) {
+	l.Logger.Error(append([]interface{}{l.prefix}, args...)...)
+}
+
+func (l instanceLogger) Debugf(format string, args ...interface{}) {
+	l.Logger.Debugf(l.prefix+format, args...)
+}
+
+func (l instanceLogger) Debug(args ...interface{}) {
+	l.Logger.Debug(append([]interface{}{l.prefix}, args...)...)
+}
+
+func (l instanceLogger) Warnf(format string, args ...interface{}) {
+	l.Logger.Warnf(l.prefix+format, args...)
+}
+
+func (l instanceLogger) Warn(args ...interface{}) {
+	l.Logger.Warn(append([]interface{}{l.prefix}, args...)...)
+}
+
+func (l instanceLogger) Infof(format string, args ...interface{}) {
+	l.Logger.Infof(l.prefix+format, args...)
+}
+
+func (l instanceLogger) Info(args ...interface{}) {
+	l.Logger.Info(append([]interface{}{l.prefix}, args...)...)
+}
@@ -0,0 +1,117 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/influxdata/telegraf"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// inventoryMeasurement is the measurement that the device inventory gathered
+// with GatherDeviceInventory is added to.
+const inventoryMeasurement = "gadgetbridge_device"
+
+// batteryTrendWindow is how far back the battery level of a device is
+// compared against for its trend.
+const batteryTrendWindow = 24 * time.Hour
+
+// gatherDeviceInventory adds a metric for every device paired in the database
+// at dbPath, which db is opened on, summarizing its samples across the given
+// tables and its battery.
+func gatherDeviceInventory(acc telegraf.Accumulator, db *sql.DB, dbPath string, tables []TableDescription) error {
+	devices, err := readDevices(db)
+	if err != nil {
+		return fmt.Errorf("error reading devices: %w", err)
+	}
+
+	summaries, err := summarizeDeviceSamples(db, tables)
+	if err != nil {
+		return err
+	}
+
+	columns, err := gadgetbridgedb.Columns(db, "BATTERY_LEVEL")
+	if err != nil {
+		return fmt.Errorf("error at table %q: %w", "BATTERY_LEVEL", err)
+	}
+	hasBattery := len(columns) > 0
+
+	now := time.Now()
+	for deviceID, device := range devices {
+		summary := summaries[deviceID]
+		fields := map[string]interface{}{
+			"samples": summary.count,
+		}
+		if summary.count > 0 {
+			fields["last_seen"] = summary.newest
+		}
+
+		if hasBattery {
+			if err := addBatteryTrend(db, deviceID, fields); err != nil {
+				return fmt.Errorf("error at table %q: %w", "BATTERY_LEVEL", err)
+			}
+		}
+
+		acc.AddFields(inventoryMeasurement, fields, map[string]string{
+			"database_path":       dbPath,
+			"device_id":           deviceID,
+			"device_name":         device.name,
+			"device_type":         device.typeName,
+			"device_identifier":   device.identifier,
+			"device_manufacturer": device.model.manufacturer,
+			"device_model":        device.model.model,
+		}, now)
+	}
+
+	return nil
+}
+
+// addBatteryTrend adds the newest level of the main battery of the device to
+// fields, along with how much it changed since batteryTrendWindow before
+// then. Nothing is added for a device without battery levels.
+func addBatteryTrend(db *sql.DB, deviceID string, fields map[string]interface{}) error {
+	newest, newestTime, err := batteryLevelAt(db, deviceID, nil)
+	if err != nil || newestTime == 0 {
+		return err
+	}
+	fields["battery_level"] = newest
+
+	before := newestTime - int64(batteryTrendWindow.Seconds())
+	earlier, earlierTime, err := batteryLevelAt(db, deviceID, &before)
+	if err != nil || earlierTime == 0 {
+		return err
+	}
+	fields["battery_change_24h"] = newest - earlier
+
+	return nil
+}
+
+// batteryLevelAt returns the newest level of the main battery of the device
+// recorded at or before the Unix time at, or at any time if at is nil, along
+// with when it was recorded. The time is zero if there's no such level.
+func batteryLevelAt(db *sql.DB, deviceID string, at *int64) (level, ts int64, err error) {
+	q := sqliteBuilder.
+		From("BATTERY_LEVEL").
+		Select("LEVEL", "TIMESTAMP").
+		Where(goqu.C("DEVICE_ID").Eq(deviceID), goqu.C("BATTERY_INDEX").Eq(0)).
+		Order(goqu.C("TIMESTAMP").Desc()).
+		Limit(1)
+	if at != nil {
+		q = q.Where(goqu.C("TIMESTAMP").Lte(*at))
+	}
+
+	qSQL, qArgs, err := q.ToSQL()
+	if err != nil {
+		return 0, 0, fmt.Errorf("error building query: %w", err)
+	}
+
+	err = db.QueryRow(qSQL, qArgs...).Scan(&level, &ts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, nil
+	}
+	return level, ts, err
+}
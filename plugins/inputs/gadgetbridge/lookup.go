@@ -0,0 +1,149 @@
+package gadgetbridge
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/toml"
+)
+
+// lookupTable maps the values of a tag to the tags that the metrics with them
+// are tagged with, as read from LookupFiles.
+type lookupTable struct {
+	tag    string
+	values map[string]map[string]string
+}
+
+// readLookupFile reads the lookup tables in the file at path, which is told
+// apart as CSV or TOML by its extension.
+//
+// A CSV file holds a single table. Its header names the tag looked up
+// followed by the tags that are added, and each row holds a value of the tag
+// followed by the values of the added tags. Empty values aren't added.
+//
+//	device_id,room
+//	1,bedroom
+//
+// A TOML file holds a table for every tag looked up, mapping its values to
+// the added tags.
+//
+//	[device_id.1]
+//	room = "bedroom"
+func readLookupFile(path string) ([]lookupTable, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		table, err := readLookupCSV(path)
+		if err != nil {
+			return nil, err
+		}
+		return []lookupTable{table}, nil
+	case ".toml":
+		return readLookupTOML(path)
+	default:
+		return nil, fmt.Errorf("unknown extension %q, expected .csv or .toml", ext)
+	}
+}
+
+func readLookupCSV(path string) (lookupTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return lookupTable{}, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return lookupTable{}, err
+	}
+	if len(records) == 0 {
+		return lookupTable{}, errors.New("missing header")
+	}
+
+	header := records[0]
+	if len(header) < 2 {
+		return lookupTable{}, errors.New("header must name the tag looked up and at least one added tag")
+	}
+
+	table := lookupTable{
+		tag:    header[0],
+		values: make(map[string]map[string]string, len(records)-1),
+	}
+	for _, record := range records[1:] {
+		tags := make(map[string]string, len(header)-1)
+		for i, tag := range header[1:] {
+			if v := record[i+1]; v != "" {
+				tags[tag] = v
+			}
+		}
+		table.values[record[0]] = tags
+	}
+
+	return table, nil
+}
+
+func readLookupTOML(path string) ([]lookupTable, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]map[string]map[string]string
+	if err := toml.Unmarshal(b, &values); err != nil {
+		return nil, err
+	}
+
+	// The tables are sorted so that which one wins a tag added by more than
+	// one doesn't change between runs.
+	tags := make([]string, 0, len(values))
+	for tag := range values {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	tables := make([]lookupTable, len(tags))
+	for i, tag := range tags {
+		tables[i] = lookupTable{tag, values[tag]}
+	}
+
+	return tables, nil
+}
+
+// lookupTaggingAccumulator adds the tags that the lookup tables map the tags
+// of every metric added through AddFields, which is all that the plugin uses,
+// to. Later tables override the tags added by earlier ones.
+type lookupTaggingAccumulator struct {
+	telegraf.Accumulator
+	lookups []lookupTable
+}
+
+func (a lookupTaggingAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	var cloned bool
+	for _, lookup := range a.lookups {
+		value, ok := tags[lookup.tag]
+		if !ok {
+			continue
+		}
+		added, ok := lookup.values[value]
+		if !ok {
+			continue
+		}
+
+		// The tags are reused for every row of a table, so they're copied
+		// rather than carrying one row's lookups over to the next.
+		if !cloned {
+			tags = maps.Clone(tags)
+			cloned = true
+		}
+		maps.Copy(tags, added)
+	}
+
+	a.Accumulator.AddFields(measurement, fields, tags, t...)
+}
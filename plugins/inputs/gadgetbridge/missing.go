@@ -0,0 +1,81 @@
+package gadgetbridge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MissingDatabaseBehavior is how a database in DatabasePaths that doesn't
+// exist is handled.
+type MissingDatabaseBehavior string
+
+const (
+	// MissingDatabaseError fails every gather that a database is missing
+	// from.
+	MissingDatabaseError MissingDatabaseBehavior = "error"
+	// MissingDatabaseIgnore skips a missing database, such as one that the
+	// phone hasn't synced yet, and tries again on the next gather. It's only
+	// warned about once until it appears.
+	MissingDatabaseIgnore MissingDatabaseBehavior = "ignore"
+)
+
+func (b MissingDatabaseBehavior) validate() error {
+	switch b {
+	case "", MissingDatabaseError, MissingDatabaseIgnore:
+		return nil
+	default:
+		return fmt.Errorf("unknown behavior %q", b)
+	}
+}
+
+// MissingTableBehavior is how an extra table that doesn't exist in a
+// database is handled. Missing known tables are always skipped quietly, since
+// every device family only records some of them.
+type MissingTableBehavior string
+
+const (
+	// MissingTableIgnore skips a missing table with only a debug message.
+	MissingTableIgnore MissingTableBehavior = "ignore"
+	// MissingTableWarn skips a missing table with a warning and counts it as
+	// a missing_table error on every gather.
+	MissingTableWarn MissingTableBehavior = "warn"
+	// MissingTableError fails the table, like a table that couldn't be
+	// queried, which also keeps the database from being processed.
+	MissingTableError MissingTableBehavior = "error"
+)
+
+func (b MissingTableBehavior) validate() error {
+	switch b {
+	case "", MissingTableIgnore, MissingTableWarn, MissingTableError:
+		return nil
+	default:
+		return fmt.Errorf("unknown behavior %q", b)
+	}
+}
+
+// errMissingTable is returned for a missing table with MissingTableError.
+var errMissingTable = errors.New("table is missing")
+
+// skipMissingDatabase records that the database at path is missing, warning
+// about it unless it was already missing before.
+func (p *Plugin) skipMissingDatabase(path string) {
+	if p.missingDatabases[path] {
+		p.log.Debugf("Skipping database %q that is still missing", path)
+		return
+	}
+
+	if p.missingDatabases == nil {
+		p.missingDatabases = make(map[string]bool)
+	}
+	p.missingDatabases[path] = true
+
+	p.log.Warnf("Skipping missing database %q until it appears", path)
+}
+
+// foundDatabase records that the database at path exists.
+func (p *Plugin) foundDatabase(path string) {
+	if p.missingDatabases[path] {
+		delete(p.missingDatabases, path)
+		p.log.Infof("Database %q appeared", path)
+	}
+}
@@ -0,0 +1,16 @@
+package gadgetbridge
+
+import "libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+
+// NullTagsBehavior is how the NULL tag columns of a table's rows are handled.
+type NullTagsBehavior = gadgetbridgedb.NullTagsBehavior
+
+const (
+	// NullTagsDropRow drops a row with a NULL tag, counting it as a
+	// dropped_rows error.
+	NullTagsDropRow = gadgetbridgedb.NullTagsDropRow
+	// NullTagsDropTag leaves a NULL tag out of the row's tags.
+	NullTagsDropTag = gadgetbridgedb.NullTagsDropTag
+	// NullTagsUnknown sets a NULL tag to "unknown".
+	NullTagsUnknown = gadgetbridgedb.NullTagsUnknown
+)
@@ -0,0 +1,22 @@
+package gadgetbridge
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// truncatingAccumulator truncates the timestamps of the metrics added through
+// AddFields, which is all that the plugin uses, to a multiple of precision.
+// Metrics without a timestamp are left alone.
+type truncatingAccumulator struct {
+	telegraf.Accumulator
+	precision time.Duration
+}
+
+func (a truncatingAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if len(t) > 0 {
+		t = []time.Time{t[0].Truncate(a.precision)}
+	}
+	a.Accumulator.AddFields(measurement, fields, tags, t...)
+}
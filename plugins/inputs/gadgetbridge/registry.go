@@ -0,0 +1,79 @@
+package gadgetbridge
+
+import (
+	"fmt"
+	"time"
+)
+
+// TablePack is a set of tables that are gathered by default once registered
+// with RegisterTables, such as those of a device family that Gadgetbridge
+// supports but the plugin doesn't yet.
+type TablePack struct {
+	// Name names the pack in the errors of RegisterTables, such as "huami".
+	Name string
+	// Tables describes the tables of the pack. They're gathered like the
+	// built-in tables: missing ones are skipped quietly, keep_sentinels
+	// applies to them and they take the place of extra_tables of the same
+	// name.
+	Tables []TableDescription
+}
+
+// ignoredPackTable is a table of a pack that RegisterTables ignored because it
+// was already gathered by default, such as by a later version of the plugin.
+type ignoredPackTable struct {
+	pack, table string
+}
+
+// ignoredPackTables are the tables that RegisterTables ignored, which Init
+// warns about.
+var ignoredPackTables []ignoredPackTable
+
+// RegisterTables adds the tables of the pack to those gathered by default, so
+// that a fork or a companion module can add support for more devices without
+// changing the plugin. It must be called from an init function, before any
+// plugin is created, such as:
+//
+//	func init() {
+//		gadgetbridge.RegisterTables(gadgetbridge.TablePack{
+//			Name:   "huami",
+//			Tables: []gadgetbridge.TableDescription{...},
+//		})
+//	}
+//
+// Like inputs.Add, it panics if the pack is invalid. Tables that are already
+// gathered by default, such as those the plugin came to support after the
+// pack was written, keep their description, and Init warns about them.
+func RegisterTables(pack TablePack) {
+	if err := validateTablePack(pack); err != nil {
+		panic(fmt.Sprintf("gadgetbridge: invalid table pack %q: %v", pack.Name, err))
+	}
+	for _, t := range pack.Tables {
+		if isKnownTable(t.Name) {
+			ignoredPackTables = append(ignoredPackTables, ignoredPackTable{pack.Name, t.Name})
+			continue
+		}
+		knownTables = append(knownTables, t)
+	}
+}
+
+func validateTablePack(pack TablePack) error {
+	for i, t := range pack.Tables {
+		if t.Name == "" {
+			return fmt.Errorf("table #%d is missing its table name", i+1)
+		}
+		for _, other := range pack.Tables[:i] {
+			if other.Name == t.Name {
+				return fmt.Errorf("table %q is listed more than once", t.Name)
+			}
+		}
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("table %q: %w", t.Name, err)
+		}
+		if t.Columns.Timezone != "" {
+			if _, err := time.LoadLocation(t.Columns.Timezone); err != nil {
+				return fmt.Errorf("table %q: %w", t.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package gadgetbridge
+
+// Reload replaces the configuration of the plugin with that of newPlugin,
+// which must already be initialized, while keeping what has been gathered so
+// far. It waits for a gather in progress to finish first.
+//
+// If the databases are watched, the directories of the new DatabasePaths are
+// watched instead. Should that fail, the error is returned and the directories
+// from before are still watched, while the rest of the config is reloaded.
+func (p *Plugin) Reload(newPlugin *Plugin) error {
+	p.reload(newPlugin)
+	return p.rewatch()
+}
+
+func (p *Plugin) reload(newPlugin *Plugin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Every option must be copied here, along with anything Init derives
+	// from them, which TestPlugin_ReloadCopiesEveryOption checks for the
+	// options.
+	p.DatabasePaths = newPlugin.DatabasePaths
+	p.ExtraTables = newPlugin.ExtraTables
+	p.SettingsPaths = newPlugin.SettingsPaths
+	p.SettingsKeys = newPlugin.SettingsKeys
+	p.GatherFITFiles = newPlugin.GatherFITFiles
+	p.GatherGPXTracks = newPlugin.GatherGPXTracks
+	p.GatherUserAttributes = newPlugin.GatherUserAttributes
+	p.GatherActivitySummaries = newPlugin.GatherActivitySummaries
+	p.GatherDeviceAttributes = newPlugin.GatherDeviceAttributes
+	p.GatherSessions = newPlugin.GatherSessions
+	p.SleepSessions = newPlugin.SleepSessions
+	p.GatherTableStats = newPlugin.GatherTableStats
+	p.GatherFreshness = newPlugin.GatherFreshness
+	p.GatherDeviceInventory = newPlugin.GatherDeviceInventory
+	p.GatherUserProfiles = newPlugin.GatherUserProfiles
+	p.GatherHeartbeat = newPlugin.GatherHeartbeat
+	p.DeviceTags = newPlugin.DeviceTags
+	p.UserTags = newPlugin.UserTags
+	p.FirmwareTags = newPlugin.FirmwareTags
+	p.DeviceAliases = newPlugin.DeviceAliases
+	p.DevicesExclude = newPlugin.DevicesExclude
+	p.UsersInclude = newPlugin.UsersInclude
+	p.UsersExclude = newPlugin.UsersExclude
+	p.LookupFiles = newPlugin.LookupFiles
+	p.TimestampPrecision = newPlugin.TimestampPrecision
+	p.KeepSentinels = newPlugin.KeepSentinels
+	p.GatherUnknownColumns = newPlugin.GatherUnknownColumns
+	p.MinTimestamp = newPlugin.MinTimestamp
+	p.MaxFutureSkew = newPlugin.MaxFutureSkew
+	p.DuplicateRows = newPlugin.DuplicateRows
+	p.DatabaseTimezones = newPlugin.DatabaseTimezones
+	p.TrackSearchPaths = newPlugin.TrackSearchPaths
+	p.VerifyChecksums = newPlugin.VerifyChecksums
+	p.ChecksumManifest = newPlugin.ChecksumManifest
+	p.ProcessedAction = newPlugin.ProcessedAction
+	p.ProcessedDirectory = newPlugin.ProcessedDirectory
+	p.MissingDatabaseBehavior = newPlugin.MissingDatabaseBehavior
+	p.MissingTableBehavior = newPlugin.MissingTableBehavior
+	p.Strict = newPlugin.Strict
+	// WatchDatabases only takes effect in Start, so it's left as it was
+	// started with, but the watcher follows DatabasePaths.
+	// InstanceID is left as it was initialized with, so that the instance
+	// keeps its statistics and can still be told apart in the logs.
+
+	p.settingsFilter = newPlugin.settingsFilter
+	p.deviceAliases = newPlugin.deviceAliases
+	p.devicesFilter = newPlugin.devicesFilter
+	p.usersFilter = newPlugin.usersFilter
+	p.minTimestamp = newPlugin.minTimestamp
+	p.lookups = newPlugin.lookups
+	p.databaseLocations = newPlugin.databaseLocations
+	p.tableLocations = newPlugin.tableLocations
+}
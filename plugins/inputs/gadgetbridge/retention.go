@@ -0,0 +1,104 @@
+package gadgetbridge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ProcessedAction is the action taken on a database after it has been
+// gathered successfully.
+type ProcessedAction string
+
+const (
+	// ProcessedKeep leaves the database in place.
+	ProcessedKeep ProcessedAction = "keep"
+	// ProcessedMove moves the database into ProcessedDirectory.
+	ProcessedMove ProcessedAction = "move"
+	// ProcessedDelete deletes the database.
+	ProcessedDelete ProcessedAction = "delete"
+)
+
+func (a ProcessedAction) validate() error {
+	switch a {
+	case "", ProcessedKeep, ProcessedMove, ProcessedDelete:
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", a)
+	}
+}
+
+// gatheredDatabase is a database that was gathered completely, as it was
+// when it was gathered.
+type gatheredDatabase struct {
+	path string
+	info os.FileInfo
+}
+
+// ProcessGathered takes ProcessedAction on the databases that the last Gather
+// gathered completely, which Gather otherwise does on its next call. Neither
+// must happen before the metrics of those databases have been written and
+// the state has been saved, since a database that's moved or deleted can't be
+// gathered again.
+func (p *Plugin) ProcessGathered() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.processGathered()
+}
+
+// processGathered takes ProcessedAction on p.gathered, leaving out the
+// databases that have changed since they were gathered, as they will be
+// gathered again.
+func (p *Plugin) processGathered() error {
+	gathered := p.gathered
+	p.gathered = nil
+
+	if p.ProcessedAction == "" || p.ProcessedAction == ProcessedKeep {
+		return nil
+	}
+
+	var errs []error
+	for _, d := range gathered {
+		info, err := os.Stat(d.path)
+		if err != nil || info.Size() != d.info.Size() || !info.ModTime().Equal(d.info.ModTime()) {
+			p.log.Debugf("Not processing database %q, which changed since it was gathered", d.path)
+			continue
+		}
+		if err := p.processDatabase(d.path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to process database %q: %w", d.path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// processDatabase applies ProcessedAction to the database at path and its
+// checksum sidecar, if any.
+func (p *Plugin) processDatabase(path string) error {
+	paths := []string{path}
+	if _, err := os.Stat(path + checksumSidecarExt); err == nil {
+		paths = append(paths, path+checksumSidecarExt)
+	}
+
+	var errs []error
+
+	switch p.ProcessedAction {
+	case ProcessedMove:
+		for _, path := range paths {
+			dst := filepath.Join(p.ProcessedDirectory, filepath.Base(path))
+			if err := os.Rename(path, dst); err != nil {
+				errs = append(errs, fmt.Errorf("failed to move processed file: %w", err))
+			}
+		}
+	case ProcessedDelete:
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete processed file: %w", err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
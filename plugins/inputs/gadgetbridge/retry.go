@@ -0,0 +1,73 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// isReadError returns whether err is SQLite failing to read the database file
+// itself, as it does when the file is replaced while it's being read, such as
+// by a sync tool, which immutable=1 doesn't guard against.
+func isReadError(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// The extended codes, such as SQLITE_IOERR_SHORT_READ, keep their primary
+	// code in the lowest byte.
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_IOERR, sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_NOTADB:
+		return true
+	default:
+		return false
+	}
+}
+
+// reopenDB opens the database at path again, closing db only once the new
+// connection works so that db is kept if it doesn't.
+func reopenDB(db *sql.DB, path string) (*sql.DB, error) {
+	reopened, err := gadgetbridgedb.Open(path)
+	if err != nil {
+		return db, err
+	}
+	if err := reopened.Ping(); err != nil {
+		reopened.Close()
+		return db, err
+	}
+	db.Close()
+	return reopened, nil
+}
+
+// tableState is the state of a single table, saved so that a table whose
+// read failed partway doesn't keep the rows it got through.
+type tableState struct {
+	lastTime    int64
+	hasLastTime bool
+	lastRows    []string
+	hasLastRows bool
+}
+
+func (p *Plugin) saveTableState(table string) tableState {
+	var s tableState
+	s.lastTime, s.hasLastTime = p.state.LastTableTimes[table]
+	s.lastRows, s.hasLastRows = p.state.LastTableRows[table]
+	return s
+}
+
+func (p *Plugin) restoreTableState(table string, s tableState) {
+	if s.hasLastTime {
+		p.state.LastTableTimes[table] = s.lastTime
+	} else {
+		delete(p.state.LastTableTimes, table)
+	}
+	if s.hasLastRows {
+		p.state.LastTableRows[table] = s.lastRows
+	} else {
+		delete(p.state.LastTableRows, table)
+	}
+}
@@ -0,0 +1,160 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// Validate checks every configured database against the tables that would be
+// gathered from it, reporting missing databases, tables and columns. Built-in
+// tables that are missing aren't reported, as not every device records every
+// one of them. It doesn't gather anything or change the plugin's state.
+func (p *Plugin) Validate() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.validateDatabases(false)
+}
+
+// validateDatabases validates every configured database, strictly if strict
+// is true, in which case the column types are checked as well. The built-in
+// tables are only checked if they exist, as they're skipped otherwise.
+func (p *Plugin) validateDatabases(strict bool) error {
+	var errs []error
+
+	paths, err := expandDatabasePaths(p.DatabasePaths)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(paths) == 0 {
+		errs = append(errs, errors.New("no databases found in database_paths"))
+	}
+
+	for _, path := range paths {
+		for _, err := range p.validateDatabase(path, strict) {
+			errs = append(errs, fmt.Errorf("database %q: %w", path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *Plugin) validateDatabase(path string, strict bool) []error {
+	// SQLite reports missing files rather obscurely, so check for them first.
+	if _, err := os.Stat(path); err != nil {
+		return []error{err}
+	}
+
+	db, err := gadgetbridgedb.Open(path)
+	if err != nil {
+		return []error{err}
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		return []error{fmt.Errorf("failed to open database: %w", err)}
+	}
+
+	var errs []error
+	for _, t := range slices.Concat(knownTables, p.ExtraTables) {
+		if isKnownTable(t.Name) {
+			columns, err := gadgetbridgedb.Columns(db, t.Name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("table %q: %w", t.Name, err))
+				continue
+			}
+			// Not every device records every known table, and known
+			// tables are gathered without the tags and fields they're
+			// missing.
+			if len(columns) == 0 {
+				continue
+			}
+			t = t.WithoutColumns(t.MissingColumns(columns))
+		}
+		for _, err := range validateTable(db, t, strict) {
+			errs = append(errs, fmt.Errorf("table %q: %w", t.Name, err))
+		}
+	}
+
+	return errs
+}
+
+// validateTable validates the table t, along with the types of its columns if
+// strict is true.
+func validateTable(db *sql.DB, t TableDescription, strict bool) []error {
+	columns, err := gadgetbridgedb.Columns(db, t.Name)
+	if err != nil {
+		return []error{err}
+	}
+	if len(columns) == 0 {
+		return []error{errors.New("table does not exist")}
+	}
+
+	var errs []error
+	for _, column := range slices.Concat([]string{t.Columns.Timestamp}, t.Columns.Tags, t.Columns.Fields, t.TableReader().Columns()) {
+		if !gadgetbridgedb.HasColumn(columns, column) {
+			errs = append(errs, fmt.Errorf("missing column %q", column))
+		}
+	}
+	if t.Columns.UTCOffset != "" && !gadgetbridgedb.HasColumn(columns, t.Columns.UTCOffset) {
+		errs = append(errs, fmt.Errorf("missing column %q", t.Columns.UTCOffset))
+	}
+
+	if strict {
+		// Tags are read as text, which any value can be, so only the
+		// columns that are read as numbers are checked.
+		numeric := slices.Concat([]string{t.Columns.Timestamp}, t.Columns.Fields)
+		if t.Columns.UTCOffset != "" {
+			numeric = append(numeric, t.Columns.UTCOffset)
+		}
+		for _, column := range columns {
+			if !slices.Contains(numeric, column.Name) {
+				continue
+			}
+			if a := column.Affinity(); !a.IsNumeric() {
+				errs = append(errs, fmt.Errorf("column %q has type %q with %s affinity, but a number is expected", column.Name, column.Type, a))
+			}
+		}
+	}
+
+	for _, j := range t.Joins {
+		for _, err := range validateJoin(db, columns, j) {
+			errs = append(errs, fmt.Errorf("join %q: %w", j.Name, err))
+		}
+	}
+
+	return errs
+}
+
+// validateJoin validates the join j of a table with the given columns.
+func validateJoin(db *sql.DB, columns []ColumnInfo, j TableJoin) []error {
+	joinedColumns, err := gadgetbridgedb.Columns(db, j.Name)
+	if err != nil {
+		return []error{err}
+	}
+	if len(joinedColumns) == 0 {
+		return []error{errors.New("table does not exist")}
+	}
+
+	var errs []error
+	for column, joinedColumn := range j.On {
+		if !gadgetbridgedb.HasColumn(columns, column) {
+			errs = append(errs, fmt.Errorf("missing column %q", column))
+		}
+		if !gadgetbridgedb.HasColumn(joinedColumns, joinedColumn) {
+			errs = append(errs, fmt.Errorf("missing joined column %q", joinedColumn))
+		}
+	}
+	for _, column := range j.Tags {
+		if !gadgetbridgedb.HasColumn(joinedColumns, column) {
+			errs = append(errs, fmt.Errorf("missing joined column %q", column))
+		}
+	}
+
+	return errs
+}
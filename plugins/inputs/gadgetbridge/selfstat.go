@@ -0,0 +1,59 @@
+package gadgetbridge
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// selfStatMeasurement is the measurement that the plugin's own statistics are
+// registered under. Telegraf's internal input reports them as
+// internal_gadgetbridge.
+const selfStatMeasurement = "gadgetbridge"
+
+// tableStats are the statistics of a single table. Activity files are counted
+// under their state keys, such as "BASE_ACTIVITY_SUMMARY/fit".
+type tableStats struct {
+	rowsRead       selfstat.Stat
+	rowsDropped    selfstat.Stat
+	metricsEmitted selfstat.Stat
+	errors         selfstat.Stat
+}
+
+// newTableStats returns the statistics of the given table, tagged with the
+// plugin's instance ID so that every instance counts its own. Registering the
+// same table again returns the same statistics.
+func (p *Plugin) newTableStats(table string) tableStats {
+	tags := map[string]string{"instance": p.instanceID, "table": table}
+	return tableStats{
+		rowsRead:       selfstat.Register(selfStatMeasurement, "rows_read", tags),
+		rowsDropped:    selfstat.Register(selfStatMeasurement, "rows_dropped", tags),
+		metricsEmitted: selfstat.Register(selfStatMeasurement, "metrics_emitted", tags),
+		errors:         selfstat.Register(selfStatMeasurement, "errors", tags),
+	}
+}
+
+// newGatherTimeStat returns the average time that a gather of the instance
+// with the given ID takes.
+func newGatherTimeStat(instanceID string) selfstat.Stat {
+	tags := map[string]string{"instance": instanceID}
+	return selfstat.RegisterTiming(selfStatMeasurement, "gather_time_ns", tags)
+}
+
+// recordGatherTime records how long the gather that started at start took.
+func (p *Plugin) recordGatherTime(start time.Time) {
+	p.gatherTime.Incr(time.Since(start).Nanoseconds())
+}
+
+// countingAccumulator counts the metrics added through AddFields, which is all
+// that the activity file gatherers use.
+type countingAccumulator struct {
+	telegraf.Accumulator
+	metrics selfstat.Stat
+}
+
+func (a countingAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.metrics.Incr(1)
+	a.Accumulator.AddFields(measurement, fields, tags, t...)
+}
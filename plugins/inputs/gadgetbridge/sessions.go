@@ -0,0 +1,353 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// sessionMeasurement is the measurement that the sessions gathered with
+// GatherSessions are added to.
+const sessionMeasurement = "gadgetbridge_session"
+
+// workoutSessionsStateKey is the key in pluginState.LastTableTimes that tracks
+// the START_TIME of the newest workout gathered as a session. Its key in
+// pluginState.LastTableRows holds the IDs of the sessions gathered at that
+// time.
+const workoutSessionsStateKey = "BASE_ACTIVITY_SUMMARY/sessions"
+
+// sleepSessionsStateKey returns the key in pluginState.LastTableTimes that
+// tracks the time that the sleep sessions of the table are next read from.
+// Its key in pluginState.LastTableRows holds the IDs of the sessions after
+// that time which were already gathered.
+func sleepSessionsStateKey(table string) string {
+	return table + "/sessions"
+}
+
+// SleepSessionTable describes how the sleep sessions are found in the samples
+// of a table.
+type SleepSessionTable struct {
+	// Table is the name of the table, which must be gathered by default or
+	// be one of the extra tables.
+	Table string `toml:"table"`
+	// KindColumn is the field column of the kinds of activity that the
+	// device recognized, such as RAW_KIND.
+	KindColumn string `toml:"kind_column"`
+	// Kinds are the kinds of sleep, such as those of light and deep sleep.
+	Kinds []int64 `toml:"kinds"`
+	// SampleLength is the time that each sample stands for, which is a
+	// minute if zero.
+	SampleLength config.Duration `toml:"sample_length,omitempty"`
+	// MaxGap is the longest time between two samples asleep of the same
+	// session, which is an hour if zero.
+	MaxGap config.Duration `toml:"max_gap,omitempty"`
+}
+
+// validateSleepSessions checks that every table of SleepSessions is gathered
+// and has its kind column as a field.
+func (p *Plugin) validateSleepSessions() error {
+	for i, s := range p.SleepSessions {
+		if s.Table == "" {
+			return fmt.Errorf("sleep session table #%d is missing its table name", i+1)
+		}
+		t, ok := p.findTable(s.Table)
+		if !ok {
+			return fmt.Errorf("sleep session table %q is neither gathered by default nor an extra table", s.Table)
+		}
+		if !slices.Contains(t.Columns.Fields, s.KindColumn) {
+			return fmt.Errorf("kind column %q of sleep session table %q isn't one of its fields", s.KindColumn, s.Table)
+		}
+		if len(s.Kinds) == 0 {
+			return fmt.Errorf("sleep session table %q has no kinds of sleep", s.Table)
+		}
+		if s.SampleLength < 0 || s.MaxGap < 0 {
+			return fmt.Errorf("sample_length and max_gap of sleep session table %q must not be negative", s.Table)
+		}
+	}
+	return nil
+}
+
+// findTable returns the description of the table that's gathered by default
+// or is an extra table with the name.
+func (p *Plugin) findTable(name string) (TableDescription, bool) {
+	tables := slices.Concat(knownTables, p.ExtraTables)
+	i := slices.IndexFunc(tables, func(t TableDescription) bool { return t.Name == name })
+	if i == -1 {
+		return TableDescription{}, false
+	}
+	return tables[i], true
+}
+
+// gatherSessions gathers the workouts recorded since the last gather and the
+// sleep sessions that ended since, each as a metric timestamped with its
+// start and tagged with its session_type and session_id.
+func (p *Plugin) gatherSessions(acc telegraf.Accumulator, db *sql.DB, dbPath string, opts gatherOptions, failed func(key string, err error)) {
+	if opts.includes(activitySummaryTable) {
+		if err := p.gatherWorkoutSessions(acc, db, dbPath, opts); err != nil {
+			failed(workoutSessionsStateKey, fmt.Errorf("error gathering workout sessions: %w", err))
+		}
+	}
+
+	for _, s := range p.SleepSessions {
+		if !opts.includes(s.Table) {
+			continue
+		}
+		t, _ := p.findTable(s.Table)
+		if err := p.gatherSleepSessions(acc, db, dbPath, t, s, opts); err != nil {
+			key := sleepSessionsStateKey(s.Table)
+			failed(key, fmt.Errorf("error gathering sleep sessions of table %q: %w", s.Table, err))
+		}
+	}
+}
+
+// gatherWorkoutSessions gathers the activities of BASE_ACTIVITY_SUMMARY that
+// weren't gathered yet as sessions. Like gatherTable, the activities that
+// start at the same time as the newest one of the last gather are read again,
+// skipping those that were already gathered.
+func (p *Plugin) gatherWorkoutSessions(acc telegraf.Accumulator, db *sql.DB, dbPath string, opts gatherOptions) error {
+	columns, err := gadgetbridgedb.Columns(db, activitySummaryTable)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		p.log.Debugf("Skipping workout sessions missing from %q", dbPath)
+		return nil
+	}
+
+	q := sqliteBuilder.
+		From(activitySummaryTable).
+		Select("_id", "START_TIME", "END_TIME", "ACTIVITY_KIND", "DEVICE_ID", "USER_ID").
+		Order(goqu.C("START_TIME").Asc())
+	lastTime, hasLastTime := p.state.LastTableTimes[workoutSessionsStateKey]
+	lastRows, hasLastRows := p.state.LastTableRows[workoutSessionsStateKey]
+	if opts.backfill {
+		q = opts.where(q, "START_TIME", time.Time.UnixMilli)
+		q = opts.newest(q, "START_TIME")
+	} else if hasLastTime && hasLastRows {
+		q = q.Where(goqu.C("START_TIME").Gte(lastTime))
+	} else if hasLastTime {
+		q = q.Where(goqu.C("START_TIME").Gt(lastTime))
+	}
+
+	qSQL, qArgs, err := q.ToSQL()
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var n, dropped int
+	var dropErr error
+	for r.Next() {
+		var activityID, startTime, endTime, kind int64
+		var deviceID, userID string
+		if err := r.Scan(&activityID, &startTime, &endTime, &kind, &deviceID, &userID); err != nil {
+			if dropped == 0 {
+				dropErr = err
+			}
+			dropped++
+			continue
+		}
+
+		id := "workout-" + strconv.FormatInt(activityID, 10)
+		if !opts.backfill && hasLastRows && startTime == lastTime && slices.Contains(lastRows, id) {
+			continue
+		}
+		n++
+
+		start, end := time.UnixMilli(startTime), time.UnixMilli(endTime)
+		acc.AddFields(sessionMeasurement, sessionFields(start, end), map[string]string{
+			"database_path": dbPath,
+			"device_id":     deviceID,
+			"user_id":       userID,
+			"session_type":  "workout",
+			"session_id":    id,
+			"sport":         lookupActivityKind(kind),
+		}, start)
+
+		if !opts.backfill {
+			if !hasLastTime || startTime != lastTime {
+				lastTime, hasLastTime = startTime, true
+				lastRows = nil
+			}
+			lastRows = append(lastRows, id)
+			p.state.LastTableTimes[workoutSessionsStateKey] = startTime
+			p.state.LastTableRows[workoutSessionsStateKey] = lastRows
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("error reading rows: %w", err)
+	}
+
+	if dropped > 0 {
+		p.log.Warnf("Dropped %d unreadable workout sessions of %q, the first because of: %v", dropped, dbPath, dropErr)
+		addTableError(acc, dbPath, activitySummaryTable, errorDroppedRows, dropped)
+	}
+
+	p.log.Debugf("Gathered %d workout sessions of %q", n, dbPath)
+	return nil
+}
+
+// gatherSleepSessions gathers the sleep sessions of the table t that ended
+// since the last gather. A session that hasn't ended yet, such as one that
+// samples yet to be synced may add to, is left to a later gather, as are
+// those of a backfill that end past its range.
+func (p *Plugin) gatherSleepSessions(acc telegraf.Accumulator, db *sql.DB, dbPath string, t TableDescription, s SleepSessionTable, opts gatherOptions) error {
+	columns, err := gadgetbridgedb.Columns(db, t.Name)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		p.log.Debugf("Skipping sleep sessions of table %q missing from %q", t.Name, dbPath)
+		return nil
+	}
+	if !gadgetbridgedb.HasColumn(columns, s.KindColumn) {
+		return fmt.Errorf("missing column %q", s.KindColumn)
+	}
+	if isKnownTable(t.Name) {
+		t = p.withExistingColumns(t, dbPath, columns)
+	}
+
+	key := sleepSessionsStateKey(t.Name)
+	sessionOpts := gadgetbridgedb.SessionOptions{
+		SleepOptions: gadgetbridgedb.SleepOptions{
+			SummaryOptions: gadgetbridgedb.SummaryOptions{
+				Location: p.tableLocation(t.Name, dbPath),
+			},
+			KindColumn:   s.KindColumn,
+			Kinds:        s.Kinds,
+			SampleLength: time.Duration(s.SampleLength),
+		},
+		MaxGap: time.Duration(s.MaxGap),
+	}
+
+	lastTime, hasLastTime := p.state.LastTableTimes[key]
+	if opts.backfill {
+		sessionOpts.From, sessionOpts.To = opts.from, opts.to
+	} else if hasLastTime {
+		sessionOpts.From = time.Unix(lastTime, 0)
+	}
+
+	sessions, err := gadgetbridgedb.SleepSessions(db, t, sessionOpts)
+	if err != nil {
+		return err
+	}
+
+	// The sessions after the time that the next gather reads from are read
+	// again, so those that were already gathered are remembered.
+	var gathered []string
+	if !opts.backfill {
+		gathered = p.state.LastTableRows[key]
+	}
+
+	ids := make([]string, len(sessions))
+	var n int
+	for i, session := range sessions {
+		ids[i] = sleepSessionID(t, session)
+		if !session.Ended || slices.Contains(gathered, ids[i]) {
+			continue
+		}
+		n++
+
+		tags := map[string]string{
+			"database_path": dbPath,
+			"session_type":  "sleep",
+			"session_id":    ids[i],
+		}
+		for column, v := range session.Tags {
+			tags[t.Columns.KeyName(column)] = v
+		}
+
+		fields := sessionFields(session.Start, session.End)
+		fields["asleep_seconds"] = session.Asleep.Seconds()
+		acc.AddFields(sessionMeasurement, fields, tags, session.Start)
+	}
+
+	if !opts.backfill {
+		if from, ok := nextSleepSessionsTime(sessions); ok {
+			var after []string
+			for i, session := range sessions {
+				if session.Ended && !session.Start.Before(from) {
+					after = append(after, ids[i])
+				}
+			}
+			p.state.LastTableTimes[key] = from.Unix()
+			p.state.LastTableRows[key] = after
+		}
+	}
+
+	p.log.Debugf("Gathered %d sleep sessions from table %q of %q", n, t.Name, dbPath)
+	return nil
+}
+
+// nextSleepSessionsTime returns the time that the next gather reads the
+// samples of the sessions from: the start of the earliest session that hasn't
+// ended, moved back to the start of every other session that it would cut
+// short, or otherwise the end of the latest session. It returns false if there
+// are no sessions.
+func nextSleepSessionsTime(sessions []gadgetbridgedb.SleepSession) (time.Time, bool) {
+	if len(sessions) == 0 {
+		return time.Time{}, false
+	}
+
+	var from time.Time
+	for _, session := range sessions {
+		if !session.Ended && (from.IsZero() || session.Start.Before(from)) {
+			from = session.Start
+		}
+	}
+
+	if from.IsZero() {
+		for _, session := range sessions {
+			if session.End.After(from) {
+				from = session.End
+			}
+		}
+		return from, true
+	}
+
+	for moved := true; moved; {
+		moved = false
+		for _, session := range sessions {
+			if session.Start.Before(from) && session.End.After(from) {
+				from = session.Start
+				moved = true
+			}
+		}
+	}
+	return from, true
+}
+
+// sleepSessionID returns the ID of the sleep session of the table t, which
+// stays the same between gathers.
+func sleepSessionID(t TableDescription, session gadgetbridgedb.SleepSession) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%d", t.Name, session.Start.Unix())
+	for _, tag := range t.Columns.Tags {
+		fmt.Fprintf(h, "\x00%s=%s", tag, session.Tags[tag])
+	}
+	return fmt.Sprintf("sleep-%016x", h.Sum64())
+}
+
+// sessionFields returns the fields that every session has.
+func sessionFields(start, end time.Time) map[string]any {
+	return map[string]any{
+		"start_time":       start.Unix(),
+		"end_time":         end.Unix(),
+		"duration_seconds": end.Sub(start).Seconds(),
+	}
+}
@@ -0,0 +1,138 @@
+package gadgetbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+)
+
+const settingsMeasurement = "gadgetbridge_settings"
+
+// defaultSettingsKeys is the list of preference keys that are gathered when
+// SettingsKeys is empty.
+var defaultSettingsKeys = []string{
+	"fitness_goal",
+	"heartrate_measurement_interval",
+	"alarm*",
+}
+
+// settingKind is the type that a preference is gathered as.
+type settingKind int
+
+const (
+	settingString settingKind = iota
+	settingInteger
+)
+
+// settingKinds are the types of the known preferences. Every other preference
+// is gathered as a string field, since guessing its type from each value would
+// change the type of its field whenever the value happens to look like a
+// number, which outputs then reject.
+var settingKinds = map[string]settingKind{
+	"fitness_goal":                   settingInteger,
+	"heartrate_measurement_interval": settingInteger,
+}
+
+// settingsExport is the JSON document written by Gadgetbridge's data export.
+// Each preference is stored with its type alongside its value.
+type settingsExport struct {
+	Preferences []struct {
+		Key   string          `json:"key"`
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	} `json:"preferences"`
+}
+
+func (p *Plugin) gatherSettings(acc telegraf.Accumulator, path string) error {
+	settings, err := readSettings(path)
+	if err != nil {
+		return err
+	}
+
+	tags := map[string]string{"settings_path": path}
+	fields := make(map[string]interface{})
+
+	for key, value := range settings {
+		if !p.settingsFilter.Match(key) {
+			continue
+		}
+
+		v, err := settingValue(value, settingKinds[key])
+		if err != nil {
+			p.log.Warnf("Skipping preference %q of %q: %v", key, path, err)
+			continue
+		}
+		if v != nil {
+			fields[key] = v
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	acc.AddFields(settingsMeasurement, fields, tags)
+	return nil
+}
+
+// readSettings reads the preferences in the JSON file at path. Both the typed
+// preference list written by Gadgetbridge's export and a plain JSON object of
+// keys to values are accepted.
+func readSettings(path string) (map[string]any, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var export settingsExport
+	if err := json.Unmarshal(b, &export); err == nil && export.Preferences != nil {
+		settings := make(map[string]any, len(export.Preferences))
+		for _, pref := range export.Preferences {
+			var v any
+			if err := json.Unmarshal(pref.Value, &v); err != nil {
+				return nil, fmt.Errorf("invalid value for preference %q: %w", pref.Key, err)
+			}
+			settings[pref.Key] = v
+		}
+		return settings, nil
+	}
+
+	var settings map[string]any
+	if err := json.Unmarshal(b, &settings); err != nil {
+		return nil, fmt.Errorf("invalid settings JSON: %w", err)
+	}
+
+	return settings, nil
+}
+
+// settingValue converts a decoded JSON value into a field value of the given
+// kind. Gadgetbridge stores most numeric preferences as strings, so those are
+// parsed back into numbers. Unset preferences, as well as nested objects and
+// arrays, have nothing to report and are nil.
+func settingValue(v any, kind settingKind) (any, error) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		s = strconv.FormatBool(v)
+	default:
+		return nil, nil
+	}
+
+	switch kind {
+	case settingInteger:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't an integer", s)
+		}
+		return i, nil
+	default:
+		return s, nil
+	}
+}
@@ -0,0 +1,79 @@
+package gadgetbridge
+
+import (
+	"maps"
+	"time"
+)
+
+// Status describes the outcome of the plugin's recent gathers.
+type Status struct {
+	// LastGather is when the last gather finished, or zero if none has.
+	LastGather time.Time `json:"last_gather"`
+	// LastSuccess is when the last gather without any errors finished, or
+	// zero if none has.
+	LastSuccess time.Time `json:"last_success"`
+	// GatheringSince is when the gather in progress started, or zero if
+	// there's none.
+	GatheringSince time.Time `json:"gathering_since"`
+	// ProgressedAt is when the gather in progress last finished a table or
+	// database, or started if it hasn't yet, or zero if there's none.
+	ProgressedAt time.Time `json:"progressed_at"`
+	// Databases maps every database of the last gather to the error it was
+	// gathered with, or to an empty string if there was none.
+	Databases map[string]string `json:"databases"`
+}
+
+// Status returns the outcome of the plugin's recent gathers. It doesn't wait
+// for a gather in progress.
+func (p *Plugin) Status() Status {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+
+	status := p.status
+	status.Databases = maps.Clone(status.Databases)
+	return status
+}
+
+// startStatus records that a gather started now.
+func (p *Plugin) startStatus() {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+
+	p.status.GatheringSince = time.Now()
+	p.status.ProgressedAt = p.status.GatheringSince
+}
+
+// progressStatus records that the gather in progress finished a table or
+// database now.
+func (p *Plugin) progressStatus() {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+
+	if !p.status.GatheringSince.IsZero() {
+		p.status.ProgressedAt = time.Now()
+	}
+}
+
+// updateStatus records the outcome of a gather that finished now.
+func (p *Plugin) updateStatus(dbErrs map[string]error, failed bool) {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+
+	now := time.Now()
+
+	p.status.GatheringSince = time.Time{}
+	p.status.ProgressedAt = time.Time{}
+	p.status.LastGather = now
+	if !failed {
+		p.status.LastSuccess = now
+	}
+
+	p.status.Databases = make(map[string]string, len(dbErrs))
+	for path, err := range dbErrs {
+		if err != nil {
+			p.status.Databases[path] = err.Error()
+		} else {
+			p.status.Databases[path] = ""
+		}
+	}
+}
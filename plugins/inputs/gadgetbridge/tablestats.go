@@ -0,0 +1,78 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/influxdata/telegraf"
+)
+
+// tableStatsMeasurement is the measurement that the table stats gathered with
+// GatherTableStats are added to.
+const tableStatsMeasurement = "gadgetbridge_table"
+
+// gatherTableStats adds a metric for every device of the table with the number
+// of rows that were just read from it and how far behind its newest row is.
+// If the table has no DEVICE_ID column, nor a join tagging its rows with one,
+// a single metric is added for the whole table. deviceRows maps device IDs to
+// the number of rows read, with "" used for the whole table.
+func gatherTableStats(
+	acc telegraf.Accumulator, db *sql.DB, dbPath string,
+	t TableDescription, deviceRows map[string]int,
+) error {
+	device, byDevice := t.DeviceColumn()
+
+	q := sqliteBuilder.From(t.Name)
+	if byDevice {
+		q = q.Select(goqu.L("?", device).As("device"), goqu.MAX(t.Columns.Timestamp)).GroupBy("device")
+	} else {
+		q = q.Select(goqu.L("NULL"), goqu.MAX(t.Columns.Timestamp))
+	}
+
+	qSQL, qArgs, err := q.ToSQL()
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	now := time.Now()
+
+	for r.Next() {
+		var deviceID sql.NullString
+		var newest sql.NullInt64
+		if err := r.Scan(&deviceID, &newest); err != nil {
+			return fmt.Errorf("error scanning row: %w", err)
+		}
+
+		// An empty table has no newest row to be behind of.
+		if !newest.Valid {
+			continue
+		}
+
+		tags := map[string]string{
+			"database_path": dbPath,
+			"table":         t.Name,
+		}
+		if byDevice {
+			tags["device_id"] = deviceID.String
+		}
+
+		acc.AddFields(tableStatsMeasurement, map[string]interface{}{
+			"rows_read":   deviceRows[deviceID.String],
+			"lag_seconds": int64(now.Sub(t.TableReader().Time(newest.Int64)).Seconds()),
+		}, tags, now)
+	}
+
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 45;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,2);
+CREATE TABLE IF NOT EXISTS "USER_ATTRIBUTES" ("_id" INTEGER PRIMARY KEY ,"HEIGHT_CM" INTEGER NOT NULL ,"WEIGHT_KG" INTEGER NOT NULL ,"SLEEP_GOAL_HPD" INTEGER,"STEPS_GOAL_SPD" INTEGER,"VALID_FROM_UTC" INTEGER,"VALID_TO_UTC" INTEGER,"USER_ID" INTEGER NOT NULL );
+INSERT INTO USER_ATTRIBUTES VALUES(1,172,64,7,NULL,1659312000000,NULL,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT);
+INSERT INTO DEVICE VALUES(1,'Bangle.js 2','Espruino','E7:A3:5C:00:00:04',0,'BANGLEJS',NULL,NULL);
+CREATE TABLE IF NOT EXISTS "DEVICE_ATTRIBUTES" ("_id" INTEGER PRIMARY KEY ,"FIRMWARE_VERSION1" TEXT NOT NULL ,"FIRMWARE_VERSION2" TEXT,"VALID_FROM_UTC" INTEGER,"VALID_TO_UTC" INTEGER,"DEVICE_ID" INTEGER NOT NULL ,"VOLATILE_IDENTIFIER" TEXT);
+INSERT INTO DEVICE_ATTRIBUTES VALUES(1,'2v15','0.19',1659312000000,NULL,1,NULL);
+CREATE TABLE IF NOT EXISTS "BANGLE_JSACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO BANGLE_JSACTIVITY_SAMPLE VALUES(1659340800,1,1,-1,0,1,0);
+INSERT INTO BANGLE_JSACTIVITY_SAMPLE VALUES(1659341400,1,1,42,356,1,88);
+INSERT INTO BANGLE_JSACTIVITY_SAMPLE VALUES(1659342000,1,1,17,91,1,79);
+CREATE TABLE IF NOT EXISTS "BATTERY_LEVEL" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"LEVEL" INTEGER NOT NULL ,"BATTERY_INDEX" INTEGER  NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ,"BATTERY_INDEX" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO BATTERY_LEVEL VALUES(1659340800,1,96,0);
+INSERT INTO BATTERY_LEVEL VALUES(1659342000,1,95,0);
+COMMIT;
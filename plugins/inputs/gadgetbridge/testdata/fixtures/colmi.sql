@@ -0,0 +1,24 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'R02_1C00','Colmi','30:31:7D:00:00:0C',0,'COLMI_R02',NULL,NULL,NULL);
+CREATE TABLE IF NOT EXISTS "COLMI_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,"DISTANCE" INTEGER NOT NULL ,"CALORIES" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO COLMI_ACTIVITY_SAMPLE VALUES(1725807600,1,1,0,612,1,0,430,21);
+CREATE TABLE IF NOT EXISTS "COLMI_HEART_RATE_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO COLMI_HEART_RATE_SAMPLE VALUES(1725807600000,1,1,0);
+INSERT INTO COLMI_HEART_RATE_SAMPLE VALUES(1725807900000,1,1,66);
+CREATE TABLE IF NOT EXISTS "COLMI_SPO2_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"SPO2" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO COLMI_SPO2_SAMPLE VALUES(1725807600000,1,1,97);
+CREATE TABLE IF NOT EXISTS "COLMI_STRESS_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"STRESS" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO COLMI_STRESS_SAMPLE VALUES(1725807600000,1,1,34);
+CREATE TABLE IF NOT EXISTS "COLMI_SLEEP_STAGE_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"DURATION" INTEGER NOT NULL ,"STAGE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO COLMI_SLEEP_STAGE_SAMPLE VALUES(1725750000000,1,1,42,2);
+INSERT INTO COLMI_SLEEP_STAGE_SAMPLE VALUES(1725752520000,1,1,18,3);
+CREATE TABLE IF NOT EXISTS "COLMI_SLEEP_SESSION_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"WAKEUP_TIME" INTEGER NOT NULL ,"SLEEP_STAGES" BLOB,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO COLMI_SLEEP_SESSION_SAMPLE VALUES(1725750000000,1,1,1725777000000,X'022A0312');
+COMMIT;
@@ -0,0 +1,13 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'M6','FitPro','C4:7C:8D:00:00:07',0,'FITPRO',NULL,NULL,NULL);
+CREATE TABLE IF NOT EXISTS "FIT_PRO_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,"CALORIES_BURNT" INTEGER,"DISTANCE_METERS" INTEGER,"SPO2_PERCENT" INTEGER,"PRESSURE_LOW_MM_HG" INTEGER,"PRESSURE_HIGH_MM_HG" INTEGER,"ACTIVE_TIME_MINUTES" INTEGER,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO FIT_PRO_ACTIVITY_SAMPLE VALUES(1725807600,1,1,312,1,0,14,220,0,0,0,5);
+INSERT INTO FIT_PRO_ACTIVITY_SAMPLE VALUES(1725808500,1,1,0,1,72,0,0,97,78,121,0);
+COMMIT;
@@ -0,0 +1,36 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'Instinct 2','Garmin','C4:4F:96:00:00:03',0,'GARMIN_INSTINCT_2',NULL,'Watch',NULL);
+CREATE TABLE IF NOT EXISTS "DEVICE_ATTRIBUTES" ("_id" INTEGER PRIMARY KEY ,"FIRMWARE_VERSION1" TEXT NOT NULL ,"FIRMWARE_VERSION2" TEXT,"VALID_FROM_UTC" INTEGER,"VALID_TO_UTC" INTEGER,"DEVICE_ID" INTEGER NOT NULL ,"VOLATILE_IDENTIFIER" TEXT);
+INSERT INTO DEVICE_ATTRIBUTES VALUES(1,'17.16',NULL,1725700000000,NULL,1,NULL);
+CREATE TABLE IF NOT EXISTS "GARMIN_FIT_FILE" ("_id" INTEGER PRIMARY KEY AUTOINCREMENT ,"DOWNLOAD_TIMESTAMP" INTEGER NOT NULL ,"DEVICE_ID" INTEGER NOT NULL ,"USER_ID" INTEGER NOT NULL ,"FILE_NUMBER" INTEGER NOT NULL ,"FILE_DATA_TYPE" INTEGER NOT NULL ,"FILE_SUB_TYPE" INTEGER NOT NULL ,"FILE_TIMESTAMP" INTEGER NOT NULL ,"SPECIFIC_FLAGS" INTEGER NOT NULL ,"FILE_SIZE" INTEGER NOT NULL ,"FILE_DATA" BLOB);
+INSERT INTO GARMIN_FIT_FILE VALUES(1,1725810000000,1,1,1,128,32,1725807600,0,4,X'0E107B08');
+CREATE TABLE IF NOT EXISTS "GARMIN_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,"DISTANCE_CM" INTEGER NOT NULL ,"ACTIVE_CALORIES" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO GARMIN_ACTIVITY_SAMPLE VALUES(1725807600,1,1,2,88,1,97,6400,5);
+INSERT INTO GARMIN_ACTIVITY_SAMPLE VALUES(1725807660,1,1,3,121,1,112,8900,7);
+INSERT INTO GARMIN_ACTIVITY_SAMPLE VALUES(1725807720,1,1,0,0,8,-1,0,0);
+CREATE TABLE IF NOT EXISTS "GARMIN_STRESS_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"STRESS" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO GARMIN_STRESS_SAMPLE VALUES(1725807600000,1,1,27);
+INSERT INTO GARMIN_STRESS_SAMPLE VALUES(1725807780000,1,1,-1);
+CREATE TABLE IF NOT EXISTS "GARMIN_SPO2_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"SPO2" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO GARMIN_SPO2_SAMPLE VALUES(1725764400000,1,1,95);
+CREATE TABLE IF NOT EXISTS "GARMIN_BODY_ENERGY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"ENERGY" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO GARMIN_BODY_ENERGY_SAMPLE VALUES(1725807600000,1,1,63);
+INSERT INTO GARMIN_BODY_ENERGY_SAMPLE VALUES(1725811200000,1,1,58);
+CREATE TABLE IF NOT EXISTS "GARMIN_SLEEP_STAGE_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"STAGE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO GARMIN_SLEEP_STAGE_SAMPLE VALUES(1725753600000,1,1,1);
+INSERT INTO GARMIN_SLEEP_STAGE_SAMPLE VALUES(1725757200000,1,1,2);
+CREATE TABLE IF NOT EXISTS "GARMIN_HRV_VALUE_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"VALUE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO GARMIN_HRV_VALUE_SAMPLE VALUES(1725753600000,1,1,48);
+INSERT INTO GARMIN_HRV_VALUE_SAMPLE VALUES(1725753900000,1,1,52);
+CREATE TABLE IF NOT EXISTS "GARMIN_HRV_SUMMARY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"WEEKLY_AVERAGE" INTEGER,"LAST_NIGHT_AVERAGE" INTEGER,"LAST_NIGHT_5_MIN_HIGH" INTEGER,"BASELINE_LOW_UPPER" INTEGER,"BASELINE_BALANCED_LOWER" INTEGER,"BASELINE_BALANCED_UPPER" INTEGER,"STATUS_NUM" INTEGER,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO GARMIN_HRV_SUMMARY_SAMPLE VALUES(1725780000000,1,1,51,50,71,42,46,60,2);
+CREATE TABLE IF NOT EXISTS "BATTERY_LEVEL" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"LEVEL" INTEGER NOT NULL ,"BATTERY_INDEX" INTEGER  NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ,"BATTERY_INDEX" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO BATTERY_LEVEL VALUES(1725807600,1,88,0);
+COMMIT;
@@ -0,0 +1,36 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,0);
+CREATE TABLE IF NOT EXISTS "USER_ATTRIBUTES" ("_id" INTEGER PRIMARY KEY ,"HEIGHT_CM" INTEGER NOT NULL ,"WEIGHT_KG" INTEGER NOT NULL ,"SLEEP_GOAL_HPD" INTEGER,"STEPS_GOAL_SPD" INTEGER,"VALID_FROM_UTC" INTEGER,"VALID_TO_UTC" INTEGER,"USER_ID" INTEGER NOT NULL );
+INSERT INTO USER_ATTRIBUTES VALUES(1,165,58,8,8000,1725700000000,NULL,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'Amazfit GTS 2 Mini','Huami','F4:2B:8C:00:00:02',0,'AMAZFITGTS2_MINI','0',NULL,NULL);
+CREATE TABLE IF NOT EXISTS "DEVICE_ATTRIBUTES" ("_id" INTEGER PRIMARY KEY ,"FIRMWARE_VERSION1" TEXT NOT NULL ,"FIRMWARE_VERSION2" TEXT,"VALID_FROM_UTC" INTEGER,"VALID_TO_UTC" INTEGER,"DEVICE_ID" INTEGER NOT NULL ,"VOLATILE_IDENTIFIER" TEXT);
+INSERT INTO DEVICE_ATTRIBUTES VALUES(1,'1.0.1.95','0.1.1.41',1725700000000,NULL,1,NULL);
+CREATE TABLE IF NOT EXISTS "HUAMI_EXTENDED_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,"UNKNOWN1" INTEGER,"SLEEP" INTEGER,"DEEP_SLEEP" INTEGER,"REM_SLEEP" INTEGER,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO HUAMI_EXTENDED_ACTIVITY_SAMPLE VALUES(1725785460,1,1,30,0,112,58,0,68,0,0);
+INSERT INTO HUAMI_EXTENDED_ACTIVITY_SAMPLE VALUES(1725785520,1,1,34,0,121,57,0,70,31,0);
+INSERT INTO HUAMI_EXTENDED_ACTIVITY_SAMPLE VALUES(1725807600,1,1,84,96,1,104,0,0,0,0);
+INSERT INTO HUAMI_EXTENDED_ACTIVITY_SAMPLE VALUES(1725807660,1,1,92,112,1,111,0,0,0,0);
+CREATE TABLE IF NOT EXISTS "HUAMI_STRESS_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"TYPE_NUM" INTEGER NOT NULL ,"STRESS" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO HUAMI_STRESS_SAMPLE VALUES(1725807600000,1,1,0,34);
+INSERT INTO HUAMI_STRESS_SAMPLE VALUES(1725807900000,1,1,0,41);
+CREATE TABLE IF NOT EXISTS "HUAMI_SPO2_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"TYPE_NUM" INTEGER NOT NULL ,"SPO2" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO HUAMI_SPO2_SAMPLE VALUES(1725785700000,1,1,1,96);
+CREATE TABLE IF NOT EXISTS "HUAMI_HEART_RATE_RESTING_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"UTC_OFFSET" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO HUAMI_HEART_RATE_RESTING_SAMPLE VALUES(1725753600000,1,1,7200000,54);
+CREATE TABLE IF NOT EXISTS "HUAMI_HEART_RATE_MANUAL_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"UTC_OFFSET" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO HUAMI_HEART_RATE_MANUAL_SAMPLE VALUES(1725790000000,1,1,7200000,72);
+INSERT INTO HUAMI_HEART_RATE_MANUAL_SAMPLE VALUES(1725790060000,1,1,7200000,0);
+CREATE TABLE IF NOT EXISTS "HUAMI_HEART_RATE_MAX_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"UTC_OFFSET" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO HUAMI_HEART_RATE_MAX_SAMPLE VALUES(1725753600000,1,1,7200000,164);
+CREATE TABLE IF NOT EXISTS "BASE_ACTIVITY_SUMMARY" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT,"START_TIME" INTEGER NOT NULL ,"END_TIME" INTEGER NOT NULL ,"ACTIVITY_KIND" INTEGER NOT NULL ,"BASE_LONGITUDE" INTEGER,"BASE_LATITUDE" INTEGER,"BASE_ALTITUDE" INTEGER,"GPX_TRACK" TEXT,"RAW_DETAILS_PATH" TEXT,"DEVICE_ID" INTEGER NOT NULL ,"USER_ID" INTEGER NOT NULL ,"SUMMARY_DATA" TEXT,"RAW_SUMMARY_DATA" BLOB);
+INSERT INTO BASE_ACTIVITY_SUMMARY VALUES(1,'Outdoor Running',1725807600000,1725809400000,16,NULL,NULL,NULL,NULL,NULL,1,1,'{"steps":{"value":4812,"unit":"steps"}}',NULL);
+CREATE TABLE IF NOT EXISTS "BATTERY_LEVEL" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"LEVEL" INTEGER NOT NULL ,"BATTERY_INDEX" INTEGER  NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ,"BATTERY_INDEX" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO BATTERY_LEVEL VALUES(1725785460,1,71,0);
+INSERT INTO BATTERY_LEVEL VALUES(1725807600,1,68,0);
+COMMIT;
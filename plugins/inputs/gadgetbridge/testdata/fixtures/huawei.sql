@@ -0,0 +1,18 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'HUAWEI Band 7','Huawei','E0:9D:13:00:00:0A',0,'HUAWEIBAND7',NULL,NULL,NULL);
+CREATE TABLE IF NOT EXISTS "HUAWEI_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"OTHER_TIMESTAMP" INTEGER  NOT NULL ,"SOURCE" INTEGER  NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"CALORIES" INTEGER NOT NULL ,"DISTANCE" INTEGER NOT NULL ,"SPO" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ,"OTHER_TIMESTAMP" ,"SOURCE" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO HUAWEI_ACTIVITY_SAMPLE VALUES(1725807600,1,1,1725807659,13,1,12,84,5,61,-1,-1);
+INSERT INTO HUAWEI_ACTIVITY_SAMPLE VALUES(1725807600,1,1,1725807600,10,-1,-1,-1,-1,-1,97,74);
+CREATE TABLE IF NOT EXISTS "HUAWEI_WORKOUT_SUMMARY_SAMPLE" ("WORKOUT_ID" INTEGER PRIMARY KEY AUTOINCREMENT ,"DEVICE_ID" INTEGER NOT NULL ,"USER_ID" INTEGER NOT NULL ,"WORKOUT_NUMBER" INTEGER NOT NULL ,"STATUS" INTEGER NOT NULL ,"START_TIMESTAMP" INTEGER NOT NULL ,"END_TIMESTAMP" INTEGER NOT NULL ,"CALORIES" INTEGER NOT NULL ,"DISTANCE" INTEGER NOT NULL ,"STEP_COUNT" INTEGER NOT NULL ,"TOTAL_TIME" INTEGER NOT NULL ,"DURATION" INTEGER NOT NULL ,"TYPE" INTEGER NOT NULL ,"STROKES" INTEGER NOT NULL ,"AVG_STROKE_RATE" INTEGER NOT NULL ,"POOL_LENGTH" INTEGER NOT NULL ,"LAPS" INTEGER NOT NULL ,"AVG_SWOLF" INTEGER NOT NULL ,"RAW_DATA" BLOB);
+INSERT INTO HUAWEI_WORKOUT_SUMMARY_SAMPLE VALUES(1,1,1,3,2,1725811200,1725813000,312000,5210,6120,1800,1800,1,0,0,0,0,0,NULL);
+CREATE TABLE IF NOT EXISTS "HUAWEI_WORKOUT_DATA_SAMPLE" ("WORKOUT_ID" INTEGER  NOT NULL ,"TIMESTAMP" INTEGER  NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,"SPEED" INTEGER NOT NULL ,"STEP_RATE" INTEGER NOT NULL ,"CADENCE" INTEGER NOT NULL ,"STEP_LENGTH" INTEGER NOT NULL ,"GROUND_CONTACT_TIME" INTEGER NOT NULL ,"IMPACT" INTEGER NOT NULL ,"SWING_ANGLE" INTEGER NOT NULL ,"FORE_FOOT_LANDING" INTEGER NOT NULL ,"MID_FOOT_LANDING" INTEGER NOT NULL ,"BACK_FOOT_LANDING" INTEGER NOT NULL ,"EVERSION_ANGLE" INTEGER NOT NULL ,"SWOLF" INTEGER NOT NULL ,"STROKE_RATE" INTEGER NOT NULL ,"DATA_ERROR_HEX" BLOB,"CALORIES" INTEGER NOT NULL ,"CYCLING_POWER" INTEGER NOT NULL ,"FREQUENCY" INTEGER NOT NULL ,"ALTITUDE" INTEGER,PRIMARY KEY ("WORKOUT_ID" ,"TIMESTAMP" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO HUAWEI_WORKOUT_DATA_SAMPLE VALUES(1,1725811205,142,29,168,84,102,-1,-1,-1,-1,-1,-1,-1,-1,-1,NULL,-1,-1,-1,NULL);
+INSERT INTO HUAWEI_WORKOUT_DATA_SAMPLE VALUES(1,1725811210,-1,31,172,86,104,-1,-1,-1,-1,-1,-1,-1,-1,-1,NULL,-1,-1,-1,12);
+COMMIT;
@@ -0,0 +1,19 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'Lefun','Lefun','E4:24:6C:00:00:09',0,'LEFUN',NULL,NULL,NULL);
+CREATE TABLE IF NOT EXISTS "LEFUN_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"DISTANCE" INTEGER NOT NULL ,"CALORIES" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO LEFUN_ACTIVITY_SAMPLE VALUES(1725807600,1,1,1,420,290,18,0);
+CREATE TABLE IF NOT EXISTS "LEFUN_BIOMETRIC_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"TYPE" INTEGER NOT NULL ,"VALUE1" INTEGER NOT NULL ,"VALUE2" INTEGER,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO LEFUN_BIOMETRIC_SAMPLE VALUES(1725807900000,1,1,0,71,NULL);
+INSERT INTO LEFUN_BIOMETRIC_SAMPLE VALUES(1725808200000,1,1,1,118,76);
+INSERT INTO LEFUN_BIOMETRIC_SAMPLE VALUES(1725808500000,1,1,2,98,NULL);
+CREATE TABLE IF NOT EXISTS "LEFUN_SLEEP_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"TYPE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO LEFUN_SLEEP_SAMPLE VALUES(1725750000000,1,1,2);
+INSERT INTO LEFUN_SLEEP_SAMPLE VALUES(1725756000000,1,1,3);
+COMMIT;
@@ -0,0 +1,24 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 17;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,1);
+CREATE TABLE IF NOT EXISTS "USER_ATTRIBUTES" ("_id" INTEGER PRIMARY KEY ,"HEIGHT_CM" INTEGER NOT NULL ,"WEIGHT_KG" INTEGER NOT NULL ,"SLEEP_GOAL_HPD" INTEGER,"STEPS_GOAL_SPD" INTEGER,"VALID_FROM_UTC" INTEGER,"VALID_TO_UTC" INTEGER,"USER_ID" INTEGER NOT NULL );
+INSERT INTO USER_ATTRIBUTES VALUES(1,180,75,8,10000,1478476800000,NULL,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"MODEL" TEXT);
+INSERT INTO DEVICE VALUES(1,'MI1S','Xiaomi','C8:0F:10:00:00:01',11,'5.15.7.14');
+CREATE TABLE IF NOT EXISTS "DEVICE_ATTRIBUTES" ("_id" INTEGER PRIMARY KEY ,"FIRMWARE_VERSION1" TEXT NOT NULL ,"FIRMWARE_VERSION2" TEXT,"VALID_FROM_UTC" INTEGER,"VALID_TO_UTC" INTEGER,"DEVICE_ID" INTEGER NOT NULL );
+INSERT INTO DEVICE_ATTRIBUTES VALUES(1,'1.0.15.0','1.0.9.0',1478476800000,NULL,1);
+CREATE TABLE IF NOT EXISTS "TAG" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"DESCRIPTION" TEXT,"USER_ID" INTEGER NOT NULL );
+CREATE TABLE IF NOT EXISTS "ACTIVITY_DESCRIPTION" ("_id" INTEGER PRIMARY KEY ,"TIMESTAMP_FROM" INTEGER NOT NULL ,"TIMESTAMP_TO" INTEGER NOT NULL ,"DETAILS" TEXT,"USER_ID" INTEGER NOT NULL );
+CREATE TABLE IF NOT EXISTS "ACTIVITY_DESC_TAG_LINK" ("_id" INTEGER PRIMARY KEY ,"ACTIVITY_DESCRIPTION_ID" INTEGER NOT NULL ,"TAG_ID" INTEGER NOT NULL );
+CREATE TABLE IF NOT EXISTS "MI_BAND_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO MI_BAND_ACTIVITY_SAMPLE VALUES(1478502000,1,1,12,0,4,255);
+INSERT INTO MI_BAND_ACTIVITY_SAMPLE VALUES(1478502060,1,1,9,0,4,255);
+INSERT INTO MI_BAND_ACTIVITY_SAMPLE VALUES(1478502120,1,1,64,38,1,92);
+INSERT INTO MI_BAND_ACTIVITY_SAMPLE VALUES(1478502180,1,1,88,104,1,101);
+INSERT INTO MI_BAND_ACTIVITY_SAMPLE VALUES(1478502240,1,1,20,7,1,84);
+CREATE TABLE IF NOT EXISTS "PEBBLE_HEALTH_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+COMMIT;
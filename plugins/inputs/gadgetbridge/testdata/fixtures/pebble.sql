@@ -0,0 +1,23 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'Pebble Time 2C4F','Pebble','B0:B4:48:00:00:06',0,'PEBBLE',NULL,NULL,NULL);
+CREATE TABLE IF NOT EXISTS "PEBBLE_HEALTH_ACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_PEBBLE_HEALTH_DATA" BLOB,"RAW_INTENSITY" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO PEBBLE_HEALTH_ACTIVITY_SAMPLE VALUES(1725807600,1,1,X'0100',1820,94,0);
+INSERT INTO PEBBLE_HEALTH_ACTIVITY_SAMPLE VALUES(1725807660,1,1,X'0100',2214,117,124);
+CREATE TABLE IF NOT EXISTS "PEBBLE_HEALTH_ACTIVITY_OVERLAY" ("TIMESTAMP_FROM" INTEGER  NOT NULL ,"TIMESTAMP_TO" INTEGER  NOT NULL ,"RAW_KIND" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_PEBBLE_HEALTH_DATA" BLOB,PRIMARY KEY ("TIMESTAMP_FROM" ,"TIMESTAMP_TO" ,"RAW_KIND" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO PEBBLE_HEALTH_ACTIVITY_OVERLAY VALUES(1725750000,1725777000,1,1,1,NULL);
+INSERT INTO PEBBLE_HEALTH_ACTIVITY_OVERLAY VALUES(1725756000,1725762000,2,1,1,NULL);
+INSERT INTO PEBBLE_HEALTH_ACTIVITY_OVERLAY VALUES(1725807600,1725809400,6,1,1,NULL);
+CREATE TABLE IF NOT EXISTS "PEBBLE_MISFIT_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_PEBBLE_MISFIT_SAMPLE" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO PEBBLE_MISFIT_SAMPLE VALUES(1725811140,1,1,9217);
+INSERT INTO PEBBLE_MISFIT_SAMPLE VALUES(1725811200,1,1,18);
+CREATE TABLE IF NOT EXISTS "PEBBLE_MORPHEUZ_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO PEBBLE_MORPHEUZ_SAMPLE VALUES(1725751200,1,1,312);
+INSERT INTO PEBBLE_MORPHEUZ_SAMPLE VALUES(1725751800,1,1,47);
+COMMIT;
@@ -0,0 +1,13 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,1);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'Steel HR','Withings','00:24:E4:00:00:08',0,'WITHINGS_STEEL_HR',NULL,NULL,NULL);
+CREATE TABLE IF NOT EXISTS "WITHINGS_STEEL_HRACTIVITY_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"DURATION" INTEGER NOT NULL ,"RAW_KIND" INTEGER NOT NULL ,"STEPS" INTEGER NOT NULL ,"DISTANCE" INTEGER NOT NULL ,"CALORIES" INTEGER NOT NULL ,"HEART_RATE" INTEGER NOT NULL ,"RAW_INTENSITY" INTEGER NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO WITHINGS_STEEL_HRACTIVITY_SAMPLE VALUES(1725807600,1,1,60,1,88,64,4,0,40);
+INSERT INTO WITHINGS_STEEL_HRACTIVITY_SAMPLE VALUES(1725807660,1,1,60,1,0,0,1,68,0);
+COMMIT;
@@ -0,0 +1,19 @@
+PRAGMA foreign_keys=OFF;
+PRAGMA user_version = 93;
+BEGIN TRANSACTION;
+CREATE TABLE android_metadata (locale TEXT);
+INSERT INTO android_metadata VALUES('en_US');
+CREATE TABLE IF NOT EXISTS "USER" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"BIRTHDAY" INTEGER NOT NULL ,"GENDER" INTEGER NOT NULL );
+INSERT INTO USER VALUES(1,'gadgetbridge-user',631152000000,0);
+CREATE TABLE IF NOT EXISTS "DEVICE" ("_id" INTEGER PRIMARY KEY ,"NAME" TEXT NOT NULL ,"MANUFACTURER" TEXT NOT NULL ,"IDENTIFIER" TEXT NOT NULL UNIQUE ,"TYPE" INTEGER NOT NULL ,"TYPE_NAME" TEXT NOT NULL ,"MODEL" TEXT,"ALIAS" TEXT,"PARENT_FOLDER" TEXT);
+INSERT INTO DEVICE VALUES(1,'Xiaomi Smart Band 8','Xiaomi','D0:62:2C:00:00:05',0,'MIBAND8',NULL,NULL,NULL);
+CREATE TABLE IF NOT EXISTS "XIAOMI_SLEEP_TIME_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"WAKEUP_TIME" INTEGER,"IS_AWAKE" INTEGER,"TOTAL_DURATION" INTEGER,"DEEP_SLEEP_DURATION" INTEGER,"LIGHT_SLEEP_DURATION" INTEGER,"REM_SLEEP_DURATION" INTEGER,"AWAKE_DURATION" INTEGER,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO XIAOMI_SLEEP_TIME_SAMPLE VALUES(1725753600000,1,1,1725780600000,1,450,95,260,80,15);
+CREATE TABLE IF NOT EXISTS "XIAOMI_SLEEP_STAGE_SAMPLE" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"USER_ID" INTEGER NOT NULL ,"STAGE" INTEGER,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO XIAOMI_SLEEP_STAGE_SAMPLE VALUES(1725753600000,1,1,3);
+INSERT INTO XIAOMI_SLEEP_STAGE_SAMPLE VALUES(1725756300000,1,1,2);
+INSERT INTO XIAOMI_SLEEP_STAGE_SAMPLE VALUES(1725762000000,1,1,4);
+INSERT INTO XIAOMI_SLEEP_STAGE_SAMPLE VALUES(1725780600000,1,1,5);
+CREATE TABLE IF NOT EXISTS "BATTERY_LEVEL" ("TIMESTAMP" INTEGER  NOT NULL ,"DEVICE_ID" INTEGER  NOT NULL ,"LEVEL" INTEGER NOT NULL ,"BATTERY_INDEX" INTEGER  NOT NULL ,PRIMARY KEY ("TIMESTAMP" ,"DEVICE_ID" ,"BATTERY_INDEX" ) ON CONFLICT REPLACE) WITHOUT ROWID;
+INSERT INTO BATTERY_LEVEL VALUES(1725780600,1,76,0);
+COMMIT;
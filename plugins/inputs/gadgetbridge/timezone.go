@@ -0,0 +1,115 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// databaseLocation is a location that the databases matching pattern are in.
+type databaseLocation struct {
+	pattern string
+	loc     *time.Location
+}
+
+// loadDatabaseLocations loads the locations of DatabaseTimezones, sorted by
+// their patterns so that which one matches a database doesn't change between
+// runs.
+func loadDatabaseLocations(timezones map[string]string) ([]databaseLocation, error) {
+	locations := make([]databaseLocation, 0, len(timezones))
+	for pattern, name := range timezones {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("database %q: %w", pattern, err)
+		}
+		locations = append(locations, databaseLocation{pattern, loc})
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].pattern < locations[j].pattern
+	})
+
+	return locations, nil
+}
+
+// databaseLocation returns the location that the database at path is in,
+// which is UTC unless DatabaseTimezones says otherwise. A pattern that is
+// the path itself wins over the others.
+func (p *Plugin) databaseLocation(path string) *time.Location {
+	for _, l := range p.databaseLocations {
+		if l.pattern == path {
+			return l.loc
+		}
+	}
+	for _, l := range p.databaseLocations {
+		if ok, _ := filepath.Match(l.pattern, path); ok {
+			return l.loc
+		}
+	}
+	return time.UTC
+}
+
+// tableLocation returns the location that the local timestamps of the table
+// in the database at path are in, which is the table's own Timezone if it has
+// one.
+func (p *Plugin) tableLocation(table, path string) *time.Location {
+	if loc, ok := p.tableLocations[table]; ok {
+		return loc
+	}
+	return p.databaseLocation(path)
+}
+
+// localClock converts the timestamps of a table recorded in the device's
+// local time, which are Unix seconds as if its wall clock were UTC, to and
+// from actual times.
+type localClock struct {
+	loc *time.Location
+}
+
+// time returns the time of the local timestamp ts. offset, if valid, is the
+// device's offset from UTC in seconds when ts was recorded, which is used
+// instead of the location.
+//
+// Around a DST transition, a local time can be ambiguous or not exist at all.
+// A local time that happens twice, when the clocks fall back, is taken to be
+// the earlier one. A local time that's skipped, when the clocks spring
+// forward, is shifted forward by the length of the gap, as if the clocks
+// hadn't changed yet.
+func (c localClock) time(ts int64, offset sql.NullInt64) time.Time {
+	if offset.Valid {
+		return time.Unix(ts-offset.Int64, 0)
+	}
+
+	// The offsets a day either side of the local time cover any transition
+	// around it, as no zone changes its offset twice in a day.
+	_, before := time.Unix(ts-secondsPerDay, 0).In(c.loc).Zone()
+	_, after := time.Unix(ts+secondsPerDay, 0).In(c.loc).Zone()
+
+	// The candidates are tried from the largest offset, which is the
+	// earliest time.
+	offsets := []int{max(before, after), min(before, after)}
+	for _, offset := range offsets {
+		t := time.Unix(ts-int64(offset), 0)
+		if _, actual := t.In(c.loc).Zone(); actual == offset {
+			return t
+		}
+	}
+
+	// The local time is in a gap, so it's read with the offset from before
+	// the gap.
+	return time.Unix(ts-int64(before), 0)
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// unix returns the local timestamp of t, as the inverse of time without an
+// offset.
+func (c localClock) unix(t time.Time) int64 {
+	_, offset := t.In(c.loc).Zone()
+	return t.Unix() + int64(offset)
+}
@@ -0,0 +1,69 @@
+package gadgetbridge
+
+import (
+	"slices"
+	"strings"
+)
+
+// withUnknownColumns returns the known table t, given all of its columns in
+// the database at dbPath, with the numeric columns that it doesn't gather
+// added to its fields if GatherUnknownColumns is enabled. Those columns, such
+// as ones added by a newer Gadgetbridge, are logged once per database, so
+// that what's not gathered can be found out.
+func (p *Plugin) withUnknownColumns(t TableDescription, dbPath string, columns []ColumnInfo) TableDescription {
+	unknown := t.UnknownColumns(columns)
+	if len(unknown) == 0 {
+		return t
+	}
+
+	key := dbPath + "\x00" + t.Name
+	if !p.reportedColumns[key] {
+		if p.reportedColumns == nil {
+			p.reportedColumns = make(map[string]bool)
+		}
+		p.reportedColumns[key] = true
+
+		names := make([]string, len(unknown))
+		for i, column := range unknown {
+			names[i] = column.Name
+		}
+		p.log.Infof("Table %q of %q has columns that aren't gathered: %s", t.Name, dbPath, strings.Join(names, ", "))
+	}
+
+	if !p.GatherUnknownColumns {
+		return t
+	}
+
+	fields := slices.Clone(t.Columns.Fields)
+	for _, column := range unknown {
+		if column.Affinity().IsNumeric() {
+			fields = append(fields, column.Name)
+		}
+	}
+	t.Columns.Fields = fields
+	return t
+}
+
+// withExistingColumns returns the known table t, given all of its columns in
+// the database at dbPath, without the tags and fields that the table doesn't
+// have, such as in databases of an older or newer Gadgetbridge, rather than
+// failing the whole table over them. Those columns are logged once per
+// database.
+func (p *Plugin) withExistingColumns(t TableDescription, dbPath string, columns []ColumnInfo) TableDescription {
+	missing := t.MissingColumns(columns)
+	if len(missing) == 0 {
+		return t
+	}
+
+	key := dbPath + "\x00" + t.Name + "\x00missing"
+	if !p.reportedColumns[key] {
+		if p.reportedColumns == nil {
+			p.reportedColumns = make(map[string]bool)
+		}
+		p.reportedColumns[key] = true
+
+		p.log.Infof("Table %q of %q is missing columns that are left out: %s", t.Name, dbPath, strings.Join(missing, ", "))
+	}
+
+	return t.WithoutColumns(missing)
+}
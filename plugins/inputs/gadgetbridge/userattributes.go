@@ -0,0 +1,66 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/influxdata/telegraf"
+)
+
+// userAttributesMeasurement is the measurement that the user attributes
+// gathered with GatherUserAttributes are added to.
+const userAttributesMeasurement = "gadgetbridge_user_attributes"
+
+// userAttributesTable is the table that user attributes are gathered from. It
+// doubles as their key in pluginState.LastTableTimes, which tracks the
+// VALID_FROM_UTC of the newest attributes gathered.
+const userAttributesTable = "USER_ATTRIBUTES"
+
+// gatherUserAttributes gathers the body stats and goals of every user that
+// were recorded since the last gather, each timestamped with the time it
+// became valid. Attributes without such a time can't be placed in history, so
+// they're left out.
+func (p *Plugin) gatherUserAttributes(acc telegraf.Accumulator, db *sql.DB, dbPath string, opts gatherOptions) error {
+	return p.gatherHistory(acc, db, dbPath, opts, historyTable{
+		name:        userAttributesTable,
+		what:        "user attributes",
+		measurement: userAttributesMeasurement,
+		timeColumn:  "VALID_FROM_UTC",
+		columns:     []any{"USER_ID", "VALID_FROM_UTC", "VALID_TO_UTC", "HEIGHT_CM", "WEIGHT_KG", "SLEEP_GOAL_HPD", "STEPS_GOAL_SPD"},
+		where:       goqu.C("VALID_FROM_UTC").IsNotNull(),
+		scan:        scanUserAttributes,
+	})
+}
+
+func scanUserAttributes(r *sql.Rows) (historyRow, error) {
+	var userID string
+	var validFrom int64
+	var validTo, sleepGoal, stepsGoal sql.NullInt64
+	var height, weight int64
+	if err := r.Scan(&userID, &validFrom, &validTo, &height, &weight, &sleepGoal, &stepsGoal); err != nil {
+		return historyRow{}, err
+	}
+
+	fields := map[string]interface{}{
+		"height_cm": height,
+		"weight_kg": weight,
+	}
+	if sleepGoal.Valid {
+		fields["sleep_goal_hours"] = sleepGoal.Int64
+	}
+	if stepsGoal.Valid {
+		fields["steps_goal"] = stepsGoal.Int64
+	}
+	// The attributes are only gathered once, so an end that's recorded
+	// after they were gathered isn't seen.
+	if validTo.Valid {
+		fields["valid_to"] = time.UnixMilli(validTo.Int64).Unix()
+	}
+
+	return historyRow{
+		time:   validFrom,
+		tags:   map[string]string{"user_id": userID},
+		fields: fields,
+	}, nil
+}
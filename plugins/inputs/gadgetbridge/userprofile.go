@@ -0,0 +1,109 @@
+package gadgetbridge
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/gadgetbridgedb"
+)
+
+// userProfileMeasurement is the measurement that the user profiles gathered
+// with GatherUserProfiles are added to.
+const userProfileMeasurement = "gadgetbridge_user"
+
+// genders maps the codes of USER.GENDER to their names, as in Gadgetbridge's
+// ActivityUser.
+var genders = map[int64]string{
+	0: "female",
+	1: "male",
+	2: "other",
+}
+
+// gatherUserProfiles adds a metric for every user of the database at dbPath,
+// which db is opened on, with their age and the maximum heart rate estimated
+// from it, for heart rate zones and calorie estimates downstream.
+func (p *Plugin) gatherUserProfiles(acc telegraf.Accumulator, db *sql.DB, dbPath string) error {
+	columns, err := gadgetbridgedb.Columns(db, "USER")
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	qSQL, qArgs, err := sqliteBuilder.
+		From("USER").
+		Select("_id", "NAME", "BIRTHDAY", "GENDER").
+		ToSQL()
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	r, err := db.Query(qSQL, qArgs...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	now := time.Now()
+	var dropped int
+	var dropErr error
+	for r.Next() {
+		var userID, name string
+		var birthday, gender int64
+		if err := r.Scan(&userID, &name, &birthday, &gender); err != nil {
+			if dropped == 0 {
+				dropErr = err
+			}
+			dropped++
+			continue
+		}
+
+		age := ageAt(time.UnixMilli(birthday), now)
+
+		tags := map[string]string{
+			"database_path": dbPath,
+			"user_id":       userID,
+			"user":          name,
+		}
+		if name, ok := genders[gender]; ok {
+			tags["gender"] = name
+		}
+
+		acc.AddFields(userProfileMeasurement, map[string]interface{}{
+			"birthday":                 time.UnixMilli(birthday).Unix(),
+			"age_years":                age,
+			"estimated_max_heart_rate": estimateMaxHeartRate(age),
+		}, tags, now)
+	}
+
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("error reading rows: %w", err)
+	}
+
+	if dropped > 0 {
+		p.log.Warnf("Dropped %d unreadable users of %q, the first because of: %v", dropped, dbPath, dropErr)
+		addTableError(acc, dbPath, "USER", errorDroppedRows, dropped)
+	}
+
+	return nil
+}
+
+// ageAt returns how many whole years old someone born at birthday is at t.
+func ageAt(birthday, t time.Time) int64 {
+	birthday = birthday.In(t.Location())
+	age := int64(t.Year() - birthday.Year())
+	if t.Month() < birthday.Month() || t.Month() == birthday.Month() && t.Day() < birthday.Day() {
+		age--
+	}
+	return age
+}
+
+// estimateMaxHeartRate estimates the maximum heart rate at the age in years
+// the way Gadgetbridge does for its heart rate zones.
+func estimateMaxHeartRate(age int64) int64 {
+	return 220 - age
+}
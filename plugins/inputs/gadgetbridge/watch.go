@@ -0,0 +1,164 @@
+package gadgetbridge
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/influxdata/telegraf"
+)
+
+// watchSettleDelay is how long a database has to stay untouched after a
+// change before it's gathered. Exports are synced in many writes, and
+// gathering one halfway through would only yield part of it. It's only
+// changed by tests.
+var watchSettleDelay = 5 * time.Second
+
+var _ telegraf.ServiceInput = (*Plugin)(nil)
+
+// Start starts watching the directories of DatabasePaths if WatchDatabases is
+// set, gathering into acc as soon as a matching database has been created or
+// written to and has settled. Otherwise, it does nothing and the plugin only
+// gathers when Gather is called.
+func (p *Plugin) Start(acc telegraf.Accumulator) error {
+	if !p.WatchDatabases {
+		return nil
+	}
+
+	p.watchMu.Lock()
+	defer p.watchMu.Unlock()
+
+	return p.watchDatabases(acc)
+}
+
+// Stop stops watching the databases, waiting for a gather started by a change
+// to finish.
+func (p *Plugin) Stop() {
+	p.watchMu.Lock()
+	defer p.watchMu.Unlock()
+
+	p.stopWatching()
+}
+
+// rewatch watches the directories of DatabasePaths again if they're being
+// watched, such as once they've been reloaded. If that fails, the directories
+// from before are still watched.
+func (p *Plugin) rewatch() error {
+	p.watchMu.Lock()
+	defer p.watchMu.Unlock()
+
+	if p.watcher == nil {
+		return nil
+	}
+	return p.watchDatabases(p.watchAcc)
+}
+
+// watchDatabases watches the directories of DatabasePaths in place of those
+// watched before, if any, which are kept if it fails. p.watchMu must be held.
+func (p *Plugin) watchDatabases(acc telegraf.Accumulator) error {
+	p.mu.Lock()
+	patterns := make([]string, len(p.DatabasePaths))
+	for i, path := range p.DatabasePaths {
+		patterns[i] = filepath.Clean(path)
+	}
+	p.mu.Unlock()
+
+	dirs, err := watchDirs(patterns)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %q: %w", dir, err)
+		}
+	}
+
+	p.stopWatching()
+
+	done := make(chan struct{})
+	p.watcher = watcher
+	p.watchDone = done
+	p.watchAcc = acc
+
+	go func() {
+		defer close(done)
+		p.watch(acc, watcher, patterns)
+	}()
+
+	return nil
+}
+
+// stopWatching stops the watcher, if any. p.watchMu must be held.
+func (p *Plugin) stopWatching() {
+	if p.watcher == nil {
+		return
+	}
+
+	p.watcher.Close()
+	<-p.watchDone
+	p.watcher = nil
+}
+
+func (p *Plugin) watch(acc telegraf.Accumulator, watcher *fsnotify.Watcher, patterns []string) {
+	settle := time.NewTimer(watchSettleDelay)
+	settle.Stop()
+	defer settle.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			if matchesAny(patterns, filepath.Clean(event.Name)) {
+				p.log.Debugf("Database %q changed: %s", event.Name, event.Op)
+				settle.Reset(watchSettleDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			p.log.Warnf("Failed to watch databases: %v", err)
+		case <-settle.C:
+			if err := p.Gather(acc); err != nil {
+				acc.AddError(err)
+			}
+		}
+	}
+}
+
+// watchDirs returns the directories of the database path patterns to watch.
+// Only the directories are watched, since exports are usually replaced rather
+// than written in place. A directory can't be a glob pattern, since those
+// that match it later couldn't be watched.
+func watchDirs(patterns []string) ([]string, error) {
+	dirs := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		dirs[i] = filepath.Dir(filepath.Clean(pattern))
+		if strings.ContainsAny(dirs[i], "*?[") {
+			return nil, fmt.Errorf("can't watch database path %q, whose directory is a glob pattern", pattern)
+		}
+	}
+	return dirs, nil
+}
+
+// matchesAny returns whether path matches any of the glob patterns.
+func matchesAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	_ "net/http/pprof"
+)
+
+// servePprof serves net/http/pprof's profiles at addr in the background.
+func servePprof(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		// net/http/pprof only registers its handlers on the default mux.
+		Handler:           http.DefaultServeMux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("failed to serve pprof", "addr", addr, "err", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/plugins/inputs/gadgetbridge"
+
+	"github.com/influxdata/telegraf/plugins/common/shim"
+)
+
+// watchReload reloads the config of the shim's input from configFile whenever
+// the process receives SIGHUP. A config that fails to load is logged and
+// leaves the running one in place.
+func watchReload(s *shim.Shim, configFile string) {
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+
+	go func() {
+		for range hupCh {
+			if err := reloadConfig(s, configFile); err != nil {
+				slog.Error("failed to reload config", "err", err)
+				continue
+			}
+			slog.Info("reloaded config", "config", configFile)
+		}
+	}()
+}
+
+func reloadConfig(s *shim.Shim, configFile string) error {
+	plugin, ok := s.Input.(*gadgetbridge.Plugin)
+	if !ok {
+		return fmt.Errorf("unexpected input type %T", s.Input)
+	}
+
+	// Loading the config into a fresh shim parses and initializes a new
+	// plugin without touching the running one.
+	newShim, err := loadConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	newPlugin, ok := newShim.Input.(*gadgetbridge.Plugin)
+	if !ok {
+		return fmt.Errorf("unexpected input type %T", newShim.Input)
+	}
+
+	return plugin.Reload(newPlugin)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/plugins/inputs/gadgetbridge"
+
+	"github.com/influxdata/telegraf/plugins/common/shim"
+)
+
+// runCommand gathers metrics every poll interval until the process is
+// stopped, which is what Telegraf's execd input runs.
+func runCommand(args []string) error {
+	fs, global := newFlagSet("run", "")
+	configFile := configFlag(fs)
+	stateFile := stateFileFlag(fs)
+
+	var outputs outputFlags
+	outputs.register(fs)
+
+	pollInterval := fs.Duration("poll_interval", 5*time.Minute, "how often to send metrics")
+	pollIntervalDisabled := fs.Bool(
+		"poll_interval_disabled",
+		false,
+		"set to true to disable polling. You want to use this when you are sending metrics on your own schedule",
+	)
+	prometheusListen := fs.String(
+		"prometheus_listen",
+		"",
+		"serve the latest gathered values on /metrics in Prometheus format at this address, such as :9273",
+	)
+	watch := fs.Bool(
+		"watch",
+		false,
+		"also gather as soon as a database matching database_paths is created or written to, independent of -poll_interval. Same as watch_databases in the config",
+	)
+	healthListen := fs.String(
+		"health_listen",
+		"",
+		"serve /healthz and /readyz at this address, such as :8080, reflecting the last successful gather and the status of each database",
+	)
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return err
+	}
+
+	if *pollIntervalDisabled {
+		*pollInterval = shim.PollIntervalDisabled
+	}
+
+	// create the shim. This is what will run your plugins.
+	shimLayer, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	if err := restoreState(shimLayer, *stateFile); err != nil {
+		return err
+	}
+
+	outs, err := outputs.outputs()
+	if err != nil {
+		return err
+	}
+	if *prometheusListen != "" {
+		outs = append(outs, newPrometheusOutput(*prometheusListen))
+	}
+
+	if *healthListen != "" {
+		// A gather is considered overdue once a few polls have passed without
+		// one succeeding.
+		var maxAge time.Duration
+		if *pollInterval != shim.PollIntervalDisabled {
+			maxAge = 3 * *pollInterval
+		}
+		if err := serveHealth(shimLayer, *healthListen, maxAge); err != nil {
+			return fmt.Errorf("failed to serve health: %w", err)
+		}
+	}
+
+	plugin, ok := shimLayer.Input.(*gadgetbridge.Plugin)
+	if !ok {
+		return fmt.Errorf("unexpected input type %T", shimLayer.Input)
+	}
+	if *watch {
+		plugin.WatchDatabases = true
+	}
+
+	// Outputs are only written to after a gather, which nothing would ever
+	// prompt.
+	if len(outs) > 0 && *pollInterval == shim.PollIntervalDisabled && !plugin.WatchDatabases {
+		return errors.New("polling or watching must be enabled to write to an output")
+	}
+
+	startWatchdog(shimLayer)
+	if *configFile != "" {
+		watchReload(shimLayer, *configFile)
+	}
+	if err := sdNotify("READY=1"); err != nil {
+		slog.Warn("failed to notify systemd of readiness", "err", err)
+	}
+
+	// Both stop gathering on SIGINT or SIGTERM once the gather in progress has
+	// finished and its metrics have been written.
+	if len(outs) > 0 {
+		err = runOutputs(shimLayer, outs, *pollInterval)
+	} else {
+		err = shimLayer.Run(*pollInterval)
+	}
+	sdNotify("STOPPING=1")
+	persistState(shimLayer, *stateFile)
+	return err
+}
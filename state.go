@@ -0,0 +1,166 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/plugins/inputs/gadgetbridge"
+
+	"github.com/influxdata/telegraf"
+)
+
+// stateCommand prints when each table was last gathered according to a state
+// file, or resets the tables given with -reset so that they're gathered from
+// the start again.
+func stateCommand(args []string) error {
+	fs, global := newFlagSet("state", "")
+	stateFile := stateFileFlag(fs)
+	reset := fs.String("reset", "",
+		"comma-separated glob patterns of the tables to forget, such as '*' for every table")
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return err
+	}
+
+	if *stateFile == "" {
+		return errors.New("-state_file is required")
+	}
+
+	plugin := &gadgetbridge.Plugin{}
+	plugin.SetState(nil)
+
+	if err := loadState(plugin, *stateFile); err != nil {
+		return err
+	}
+
+	if *reset != "" {
+		tables, err := plugin.ResetState(strings.Split(*reset, ","))
+		if err != nil {
+			return err
+		}
+
+		if err := saveState(plugin, *stateFile); err != nil {
+			return err
+		}
+
+		for _, table := range tables {
+			fmt.Println(table)
+		}
+		return nil
+	}
+
+	return printState(os.Stdout, plugin.LastGathered())
+}
+
+// printState prints when each table was last gathered, the least recent
+// first.
+func printState(w io.Writer, lastGathered map[string]time.Time) error {
+	tables := make([]string, 0, len(lastGathered))
+	for table := range lastGathered {
+		tables = append(tables, table)
+	}
+	slices.SortFunc(tables, func(a, b string) int {
+		return cmp.Or(lastGathered[a].Compare(lastGathered[b]), strings.Compare(a, b))
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, table := range tables {
+		fmt.Fprintf(tw, "%s\t%s\n", table, lastGathered[table].Format(time.RFC3339))
+	}
+
+	return tw.Flush()
+}
+
+// loadState restores the state of the plugin from the file at path. A missing
+// file leaves the state as it is.
+func loadState(plugin telegraf.StatefulPlugin, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+
+	// The state's type is unexported, so the current state is used as a
+	// blueprint to unmarshal into, just like Telegraf does.
+	state := reflect.New(reflect.TypeOf(plugin.GetState()))
+	if err := json.Unmarshal(b, state.Interface()); err != nil {
+		return fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	return plugin.SetState(state.Elem().Interface())
+}
+
+// saveState writes the state of the plugin to the file at path. The file is
+// replaced atomically, so it's never left half-written.
+func saveState(plugin telegraf.StatefulPlugin, path string) error {
+	b, err := json.Marshal(plugin.GetState())
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create state: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace state: %w", err)
+	}
+
+	return nil
+}
+
+// holdSignals keeps SIGINT and SIGTERM from stopping the process until the
+// returned function is called, so that a gather in progress can finish and
+// its metrics and state can be written. A second signal exits right away.
+func holdSignals() (release func()) {
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-sigCh:
+			slog.Warn("finishing gather before exiting, signal again to exit now", "signal", sig)
+		case <-done:
+			return
+		}
+
+		select {
+		case <-sigCh:
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
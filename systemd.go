@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/plugins/inputs/gadgetbridge"
+
+	"github.com/influxdata/telegraf/plugins/common/shim"
+)
+
+// sdNotify sends state to systemd's notification socket. It does nothing if
+// the process wasn't started by systemd with Type=notify.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// Abstract sockets are given with a leading @.
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to systemd: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("failed to notify systemd: %w", err)
+	}
+
+	return nil
+}
+
+// watchdogInterval returns the interval that systemd expects its watchdog to
+// be pinged in, or zero if the watchdog isn't enabled for this process.
+func watchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
+
+// startWatchdog pings systemd's watchdog in the background, if it's enabled,
+// on its own ticker rather than between gathers, so that a long gather doesn't
+// miss pings. The pings only stop once a gather is stuck, having made no
+// progress for longer than the watchdog's interval, and systemd then restarts
+// the process.
+func startWatchdog(s *shim.Shim) {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	plugin, ok := s.Input.(*gadgetbridge.Plugin)
+	if !ok {
+		slog.Warn("watchdog disabled for unexpected input", "input", fmt.Sprintf("%T", s.Input))
+		return
+	}
+
+	go func() {
+		// Ping twice per interval so that a late ping isn't mistaken for a
+		// hang.
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if status := plugin.Status(); gatherStuck(status, interval) {
+				slog.Warn("gather is stuck, no longer pinging watchdog",
+					"gathering_since", status.GatheringSince,
+					"progressed_at", status.ProgressedAt)
+				continue
+			}
+
+			if err := sdNotify("WATCHDOG=1"); err != nil {
+				slog.Warn("failed to ping watchdog", "err", err)
+			}
+		}
+	}()
+}
+
+// gatherStuck returns whether the gather in progress, if any, hasn't made any
+// progress for longer than interval.
+func gatherStuck(status gadgetbridge.Status, interval time.Duration) bool {
+	return !status.GatheringSince.IsZero() && time.Since(status.ProgressedAt) > interval
+}
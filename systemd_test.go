@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"libdb.so/telegraf-plugin-gadgetbridge/plugins/inputs/gadgetbridge"
+)
+
+func TestGatherStuck(t *testing.T) {
+	now := time.Now()
+	interval := time.Minute
+
+	tests := []struct {
+		name   string
+		status gadgetbridge.Status
+		want   bool
+	}{
+		{"idle", gadgetbridge.Status{LastGather: now.Add(-time.Hour)}, false},
+		{"progressing", gadgetbridge.Status{GatheringSince: now.Add(-time.Hour), ProgressedAt: now}, false},
+		{"stuck", gadgetbridge.Status{GatheringSince: now.Add(-time.Hour), ProgressedAt: now.Add(-2 * interval)}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, gatherStuck(test.status, interval))
+		})
+	}
+}
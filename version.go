@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// features lists the file formats of recorded activities that this binary
+// can decode, which gather_fit_files and gather_gpx_tracks need. Every build has
+// the same ones, as none of them depend on build tags.
+var features = []string{"fit", "gpx"}
+
+// printVersion prints the version of the binary as recorded in its build info
+// along with its features.
+func printVersion(w io.Writer) error {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return errors.New("no build info embedded in binary")
+	}
+
+	settings := make(map[string]string, len(info.Settings))
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+
+	commit := "unknown"
+	if revision := settings["vcs.revision"]; revision != "" {
+		commit = revision
+		if t := settings["vcs.time"]; t != "" {
+			commit += " (" + t + ")"
+		}
+		if settings["vcs.modified"] == "true" {
+			commit += " (modified)"
+		}
+	}
+
+	fmt.Fprintf(w, "%s %s\n", info.Main.Path, info.Main.Version)
+	fmt.Fprintf(w, "commit: %s\n", commit)
+	fmt.Fprintf(w, "go: %s\n", info.GoVersion)
+	fmt.Fprintf(w, "features: %s\n", strings.Join(features, " "))
+
+	return nil
+}
+
+// versionCommand prints the version of the binary.
+func versionCommand(args []string) error {
+	fs, global := newFlagSet("version", "")
+	fs.Parse(args)
+
+	if err := global.setup(); err != nil {
+		return err
+	}
+
+	return printVersion(os.Stdout)
+}